@@ -1,12 +1,18 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/xumoe-c/maiecho/server/internal/collector"
 	"github.com/xumoe-c/maiecho/server/internal/config"
 	"github.com/xumoe-c/maiecho/server/internal/llm"
 	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/progress"
 	"github.com/xumoe-c/maiecho/server/internal/provider/divingfish"
 	"github.com/xumoe-c/maiecho/server/internal/provider/yuzuchan"
 	"github.com/xumoe-c/maiecho/server/internal/router"
@@ -42,36 +48,80 @@ func main() {
 	}
 	defer logger.Sync()
 
+	// 维护命令 (执行后退出，不启动服务器)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "dedupe-comments":
+			runDedupeComments(cfg.DatabaseURL)
+		case "migrate":
+			runMigrate(cfg.DatabaseURL, os.Args[2:])
+		default:
+			logger.Fatal("未知命令", "module", "main", "command", os.Args[1])
+		}
+		return
+	}
+
 	logger.Info("启动 MaiEcho 服务器",
 		"module", "main",
 		"version", "1.0",
 		"port", cfg.ServerPort,
 	)
 
-	// 初始化存储
-	// 目前使用 sqlite，文件将创建在当前目录
-	db, err := storage.NewDatabase(".\\server\\sqlite_db\\maiecho.db")
+	// 初始化存储 (根据 database_url 选择 SQLite 或 PostgreSQL)
+	db, err := storage.NewDatabase(cfg.DatabaseURL)
 	if err != nil {
 		logger.Fatal("连接数据库失败", "module", "main", "error", err)
 	}
+	logger.Info("数据库连接成功", "module", "main", "driver", db.Driver())
 
-	// 初始化 LLM 客户端
-	llmClient := llm.NewClient(cfg.LLM)
+	// 初始化 LLM 提供方与各角色的路由
+	llmRouter, err := llm.NewRouter(cfg.LLM)
+	if err != nil {
+		logger.Fatal("初始化 LLM 路由失败", "module", "main", "error", err)
+	}
+	logger.Info("LLM 路由已加载", "module", "main", "routes", llmRouter.Describe())
+	status.RegisterLLM(llmRouter.InFlight)
+
+	// 记录每次 LLM 调用的 token 用量，供用量统计与每日预算使用
+	usageService := service.NewUsageService(db, cfg.LLM)
+	llmRouter.SetUsageRecorder(usageService)
+
+	// 匹配确认、相关性判断和清洗的响应缓存，prompts.yaml 修改后旧缓存失效
+	if cfg.LLM.CacheTTL > 0 {
+		llmCache := service.NewLLMCache(db, cfg.LLM.CacheTTL, prompts.Version)
+		if removed, err := llmCache.Purge(); err != nil {
+			logger.Error("清理LLM缓存失败", "module", "main", "error", err)
+		} else if removed > 0 {
+			logger.Info("已清理过期的LLM缓存", "module", "main", "removed", removed)
+		}
+		llmRouter.SetCache(llmCache)
+	}
 
 	// 初始化服务
 	dfClient := divingfish.NewClient()
 	yzClient := yuzuchan.NewClient()
 	songService := service.NewSongService(db, dfClient, yzClient)
-	collectorService := service.NewCollectorService(db, songService, cfg, llmClient, prompts)
+	registry, err := collector.NewRegistry(db, cfg)
+	if err != nil {
+		logger.Fatal("初始化采集器失败", "module", "main", "error", err)
+	}
+	collectorService := service.NewCollectorService(db, songService, registry, llmRouter, prompts)
 
-	analysisService := service.NewAnalysisService(db, llmClient, prompts)
+	// 作业进度事件中心 (供 SSE 推送)；顾问摘要的流式输出使用单独的事件中心
+	hub := progress.NewHub()
+	summaryHub := progress.NewHub()
+	analysisService := service.NewAnalysisService(db, llmRouter, prompts, hub, summaryHub, usageService)
+	analysisService.AbortInterruptedJobs()
+	jobService := service.NewJobService(db, hub, summaryHub)
+	commentService := service.NewCommentService(db)
+	chartService := service.NewChartService(db)
 
 	// 启动调度器
 	collectorService.StartScheduler()
 	defer collectorService.StopScheduler()
 
 	// 初始化路由
-	r := router.NewRouter(songService, collectorService, analysisService)
+	r := router.NewRouter(songService, collectorService, analysisService, jobService, commentService, chartService, usageService)
 
 	// 启动 API 服务器
 	addr := cfg.ServerPort
@@ -83,3 +133,73 @@ func main() {
 		logger.Fatal("启动服务器失败", "module", "main", "error", err)
 	}
 }
+
+// runDedupeComments 合并历史数据中 (source, external_id) 重复的评论，然后执行迁移创建唯一索引
+func runDedupeComments(databaseURL string) {
+	db, err := storage.OpenDatabase(databaseURL)
+	if err != nil {
+		logger.Fatal("连接数据库失败", "module", "main", "error", err)
+	}
+
+	removed, err := db.DedupeComments()
+	if err != nil {
+		logger.Fatal("合并重复评论失败", "module", "main", "removed", removed, "error", err)
+	}
+	logger.Info("重复评论合并完成", "module", "main", "removed", removed)
+
+	if _, err := db.MigrateUp(); err != nil {
+		logger.Fatal("数据库迁移失败", "module", "main", "error", err)
+	}
+	logger.Info("评论唯一索引已创建", "module", "main")
+}
+
+// runMigrate 执行 `maiecho migrate up|down [steps]|status`
+func runMigrate(databaseURL string, args []string) {
+	db, err := storage.OpenDatabase(databaseURL)
+	if err != nil {
+		logger.Fatal("连接数据库失败", "module", "main", "error", err)
+	}
+
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		applied, err := db.MigrateUp()
+		if err != nil {
+			logger.Fatal("数据库迁移失败", "module", "main", "applied", len(applied), "error", err)
+		}
+		logger.Info("数据库迁移完成", "module", "main", "applied", len(applied), "version", storage.LatestSchemaVersion())
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				logger.Fatal("回滚步数无效", "module", "main", "steps", args[1])
+			}
+		}
+		reverted, err := db.MigrateDown(steps)
+		if err != nil {
+			logger.Fatal("数据库回滚失败", "module", "main", "reverted", len(reverted), "error", err)
+		}
+		logger.Info("数据库回滚完成", "module", "main", "reverted", len(reverted))
+	case "status":
+		states, err := db.MigrationStatus()
+		if err != nil {
+			logger.Fatal("读取迁移状态失败", "module", "main", "error", err)
+		}
+		for _, st := range states {
+			mark := "pending"
+			if st.Unknown {
+				mark = "unknown (数据库比程序新)"
+			} else if st.Applied {
+				mark = "applied " + st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-28s %s\n", st.Version, st.Name, mark)
+		}
+	default:
+		logger.Fatal("未知的迁移操作，可用: up | down [steps] | status", "module", "main", "action", action)
+	}
+}
@@ -15,9 +15,76 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/collectors": {
+            "get": {
+                "description": "列出所有采集器及其封禁熔断状态 (closed/open/half_open)、连续封禁次数与冷却结束时间",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "获取采集器状态",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_controller.CollectorListResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/collectors/{name}/reset": {
+            "post": {
+                "description": "手动解除指定采集器的封禁冷却，立即恢复采集",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "重置采集器封禁状态",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "采集器名称 (例如 bilibili)",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_status.CollectorStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/analysis/batch": {
             "post": {
-                "description": "触发针对多个歌曲ID(GameID)的LLM分析流程",
+                "description": "触发针对多个歌曲ID(GameID)的LLM分析流程，返回可用于查询进度的作业ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -44,9 +111,7 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "400": {
@@ -72,7 +137,7 @@ const docTemplate = `{
         },
         "/analysis/songs/{id}": {
             "get": {
-                "description": "获取指定歌曲(GameID)的最新分析结果",
+                "description": "获取指定歌曲(GameID)的最新分析结果，包含歌曲总览和各谱面详情",
                 "consumes": [
                     "application/json"
                 ],
@@ -96,7 +161,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.AnalysisResult"
+                            "$ref": "#/definitions/internal_controller.AnalysisResponse"
                         }
                     },
                     "404": {
@@ -111,7 +176,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "触发针对指定歌曲ID(GameID)的LLM分析流程",
+                "description": "异步触发针对指定歌曲ID(GameID)的LLM分析流程，立即返回作业ID。可通过 /jobs/{id} 轮询或 /jobs/{id}/events 订阅进度",
                 "consumes": [
                     "application/json"
                 ],
@@ -134,6 +199,13 @@ const docTemplate = `{
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -141,8 +213,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -162,38 +234,47 @@ const docTemplate = `{
                 }
             }
         },
-        "/collect": {
-            "post": {
-                "description": "针对特定关键词或GameID启动数据收集任务",
-                "consumes": [
-                    "application/json"
-                ],
+        "/charts/changes": {
+            "get": {
+                "description": "按检测时间倒序列出同步时发现的谱面定数 (DS) 或等级变更",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "collector"
+                    "charts"
                 ],
-                "summary": "触发数据收集任务",
+                "summary": "获取定数变更列表",
                 "parameters": [
                     {
-                        "description": "Collection Request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_controller.CollectRequest"
-                        }
+                        "type": "string",
+                        "description": "只返回该时间之后的变更 (RFC3339 或 2006-01-02)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "歌曲ID (数据库ID)",
+                        "name": "song_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页数量",
+                        "name": "page_size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.ChartChangeListResponse"
                         }
                     },
                     "400": {
@@ -208,68 +289,27 @@ const docTemplate = `{
                 }
             }
         },
-        "/collect/backfill": {
-            "post": {
-                "description": "用于初始化数据源，为无数据的歌曲收集评论数据",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "collector"
-                ],
-                "summary": "触发回填数据收集",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    }
-                }
-            }
-        },
-        "/songs": {
+        "/charts/search": {
             "get": {
-                "description": "支持分页和多种筛选条件",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "按谱面数据与每个谱面最新一次的分析结果 (没有谱面结果时使用所属歌曲的总览结果) 搜索谱面，按定数倒序排列。pattern 与 tag 按子串匹配，可重复传入，需同时满足",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "songs"
+                    "charts"
                 ],
-                "summary": "获取歌曲列表",
+                "summary": "按分析结果搜索谱面",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "版本",
-                        "name": "version",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "类型 (DX/Standard)",
-                        "name": "type",
+                        "description": "难度 (Basic, Advanced, Expert, Master, Re:Master)",
+                        "name": "difficulty",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "流派",
-                        "name": "genre",
+                        "description": "等级 (如 13+)",
+                        "name": "level",
                         "in": "query"
                     },
                     {
@@ -285,15 +325,47 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
-                        "type": "boolean",
-                        "description": "是否新歌",
-                        "name": "is_new",
+                        "type": "string",
+                        "description": "谱面类型 (DX, SD)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "谱面配置标签 (如 转圈)，可重复",
+                        "name": "pattern",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "难度描述符标签 (如 逆诈称)，可重复",
+                        "name": "tag",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "搜索关键词",
-                        "name": "keyword",
+                        "description": "情感倾向 (Positive, Neutral, Negative)",
+                        "name": "sentiment",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "拟合定数与定数之差 (fit_diff - ds) 的下限",
+                        "name": "min_delta",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "拟合定数与定数之差 (fit_diff - ds) 的上限",
+                        "name": "max_delta",
                         "in": "query"
                     },
                     {
@@ -313,7 +385,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.SongListResponse"
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.ChartSearchResponse"
                         }
                     },
                     "400": {
@@ -326,35 +398,32 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "向数据库添加新歌曲",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/charts/{id}/timeline": {
+            "get": {
+                "description": "获取单个谱面的定数变更事件与每次同步记录的数据快照 (定数、拟合难度、平均达成率等)，均按时间正序排列",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "songs"
+                    "charts"
                 ],
-                "summary": "创建歌曲",
+                "summary": "获取谱面历史",
                 "parameters": [
                     {
-                        "description": "Song JSON",
-                        "name": "song",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.Song"
-                        }
+                        "type": "integer",
+                        "description": "Chart ID (数据库ID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.Song"
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.ChartTimeline"
                         }
                     },
                     "400": {
@@ -365,13 +434,22 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/songs/aliases/refresh": {
+        "/collect": {
             "post": {
-                "description": "从YuzuChan API获取最新的歌曲别名",
+                "description": "针对特定关键词或GameID启动数据收集任务，返回可用于查询进度的作业ID。可通过 source 指定只使用某个采集器",
                 "consumes": [
                     "application/json"
                 ],
@@ -379,21 +457,30 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "songs"
+                    "collector"
+                ],
+                "summary": "触发数据收集任务",
+                "parameters": [
+                    {
+                        "description": "Collection Request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_controller.CollectRequest"
+                        }
+                    }
                 ],
-                "summary": "刷新歌曲别名",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -404,24 +491,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/songs/sync": {
+        "/collect/backfill": {
             "post": {
-                "description": "从Diving-Fish API获取并更新歌曲数据",
+                "description": "用于初始化数据源，为无数据的歌曲收集评论数据",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "songs"
+                    "collector"
                 ],
-                "summary": "同步歌曲数据",
+                "summary": "触发回填数据收集",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "500": {
@@ -436,33 +521,147 @@ const docTemplate = `{
                 }
             }
         },
-        "/songs/{id}": {
+        "/comments/search": {
             "get": {
-                "description": "获取歌曲及其谱面详情 (通过 GameID)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "在评论内容和来源标题中搜索关键词，多个词以空格分隔且需同时匹配。启用全文索引时按相关度 (score) 排序",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "songs"
+                    "comments"
                 ],
-                "summary": "获取歌曲详情",
+                "summary": "搜索评论",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Game ID",
-                        "name": "id",
-                        "in": "path",
+                        "type": "string",
+                        "description": "搜索词",
+                        "name": "q",
+                        "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "歌曲ID (数据库ID)",
+                        "name": "song_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "来源 (Bilibili/Tieba)",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页数量 (最大 100)",
+                        "name": "page_size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.Song"
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.CommentSearchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs": {
+            "get": {
+                "description": "按创建时间倒序列出采集与分析作业，支持按类型和状态筛选",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "获取作业列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "作业类型 (collect/backfill/analysis)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "作业状态 (pending/running/done/failed)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页数量",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.JobListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "获取作业的状态、进度计数、各子项的错误信息与耗时",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "获取作业详情",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.Job"
                         }
                     },
                     "400": {
@@ -482,33 +681,1002 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
         },
-        "/system/status": {
+        "/jobs/{id}/events": {
             "get": {
-                "description": "获取服务器运行状态、资源使用情况等",
+                "description": "以 Server-Sent Events 推送作业的阶段事件 (started, comments_loaded, bucketed, chart_analyzed, advisor_done, saved, song_failed, completed, failed)。连接建立后先回放已发生的事件，作业结束后服务端关闭连接",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "订阅作业进度事件 (SSE)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_progress.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/summary": {
+            "get": {
+                "description": "以 Server-Sent Events 推送分析作业中顾问报告摘要的生成过程：advisor_delta (增量文本 delta 与目前为止的完整文本 text)、advisor_summary (该目标的最终摘要，以此为准)。每首歌曲的每个谱面及歌曲总览各生成一次摘要，以 data.target_type / data.target_id 区分。连接建立后先回放已发生的事件，作业结束时推送 completed 或 failed 并关闭连接",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "订阅顾问摘要的流式输出 (SSE)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_progress.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/llm/usage": {
+            "get": {
+                "description": "按本地日期和 Agent 角色汇总 LLM 调用次数、token 数与估算费用，并返回今日的预算状态。默认统计最近 7 天",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "llm"
+                ],
+                "summary": "获取LLM用量统计",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "起始日期 YYYY-MM-DD (含)，默认为 to 之前 6 天",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "截止日期 YYYY-MM-DD (含)，默认为今天",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Agent 角色 (cleaner, analyst, advisor, mapper, relevance)",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "歌曲ID (数据库ID)",
+                        "name": "song_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "作业ID",
+                        "name": "job_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "获取系统状态",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_status.SystemStatus"
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.LLMUsageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "github_com_xumoe-c_maiecho_server_internal_model.AnalysisResult": {
+        },
+        "/songs": {
+            "get": {
+                "description": "支持分页和多种筛选条件",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "songs"
+                ],
+                "summary": "获取歌曲列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "版本",
+                        "name": "version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "类型 (DX/Standard)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "流派",
+                        "name": "genre",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "最小定数",
+                        "name": "min_ds",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "最大定数",
+                        "name": "max_ds",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "是否新歌",
+                        "name": "is_new",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "搜索关键词",
+                        "name": "keyword",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页数量",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.SongListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "向数据库添加新歌曲",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "songs"
+                ],
+                "summary": "创建歌曲",
+                "parameters": [
+                    {
+                        "description": "Song JSON",
+                        "name": "song",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.Song"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.Song"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/aliases/refresh": {
+            "post": {
+                "description": "从YuzuChan API获取最新的歌曲别名",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "songs"
+                ],
+                "summary": "刷新歌曲别名",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/sync": {
+            "post": {
+                "description": "从Diving-Fish API获取并更新歌曲数据",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "songs"
+                ],
+                "summary": "同步歌曲数据",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/{id}": {
+            "get": {
+                "description": "获取歌曲及其谱面详情 (通过 GameID)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "songs"
+                ],
+                "summary": "获取歌曲详情",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Game ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.Song"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/system/status": {
+            "get": {
+                "description": "获取服务器运行状态、资源使用情况等",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "获取系统状态",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_status.SystemStatus"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_xumoe-c_maiecho_server_internal_logger.ErrorEntry": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "level": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "module": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.AnalysisResult": {
+            "type": "object",
+            "properties": {
+                "chart_report": {
+                    "description": "谱面顾问的结构化报告，仅谱面结果包含",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.ChartReport"
+                        }
+                    ]
+                },
+                "cons": {
+                    "description": "缺点",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "difficulty_analysis": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "pros": {
+                    "description": "优点",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rating_advice": {
+                    "type": "string"
+                },
+                "reasoning_log": {
+                    "description": "存储 LLM 的推理过程",
+                    "type": "string"
+                },
+                "sentiment": {
+                    "description": "分析师从评论中提取的结构化事实",
+                    "type": "string"
+                },
+                "summary": {
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "难度描述符与谱面配置，单独成表以便筛选",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.AnalysisTag"
+                    }
+                },
+                "target_id": {
+                    "type": "integer"
+                },
+                "target_type": {
+                    "description": "Song or Chart",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.AnalysisTag": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.Chart": {
+            "type": "object",
+            "properties": {
+                "avg_achievement": {
+                    "type": "number"
+                },
+                "avg_dx": {
+                    "type": "number"
+                },
+                "charter": {
+                    "description": "NotesDesigner",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "difficulty": {
+                    "description": "Basic, Advanced, Expert, Master, Re:Master",
+                    "type": "string"
+                },
+                "ds": {
+                    "description": "Internal decimal level",
+                    "type": "number"
+                },
+                "fit_diff": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "level": {
+                    "description": "13, 13+, 14, etc.",
+                    "type": "string"
+                },
+                "notes": {
+                    "description": "JSON array: [tap, hold, slide, touch, break]",
+                    "type": "string"
+                },
+                "sample_count": {
+                    "type": "integer"
+                },
+                "song_id": {
+                    "type": "integer"
+                },
+                "std_dev": {
+                    "type": "number"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.ChartChange": {
+            "type": "object",
+            "properties": {
+                "chart_id": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "difficulty": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "new_ds": {
+                    "type": "number"
+                },
+                "new_level": {
+                    "type": "string"
+                },
+                "old_ds": {
+                    "type": "number"
+                },
+                "old_level": {
+                    "type": "string"
+                },
+                "song_id": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.ChartChangeItem": {
+            "type": "object",
+            "properties": {
+                "chart_id": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "difficulty": {
+                    "type": "string"
+                },
+                "game_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "new_ds": {
+                    "type": "number"
+                },
+                "new_level": {
+                    "type": "string"
+                },
+                "old_ds": {
+                    "type": "number"
+                },
+                "old_level": {
+                    "type": "string"
+                },
+                "song_id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.ChartChangeListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.ChartChangeItem"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.ChartReport": {
+            "type": "object",
+            "properties": {
+                "key_sections": {
+                    "description": "关键段落 (难点、杀点)",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.ChartSection"
+                    }
+                },
+                "practice_tips": {
+                    "description": "练习建议",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "recommended_level": {
+                    "description": "推荐挑战的玩家水平",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.LevelRange"
+                        }
+                    ]
+                },
+                "tricks": {
+                    "description": "配置技巧 (如拆法、手法)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.ChartSearchItem": {
+            "type": "object",
+            "properties": {
+                "avg_achievement": {
+                    "type": "number"
+                },
+                "avg_dx": {
+                    "type": "number"
+                },
+                "charter": {
+                    "description": "NotesDesigner",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "difficulty": {
+                    "description": "Basic, Advanced, Expert, Master, Re:Master",
+                    "type": "string"
+                },
+                "ds": {
+                    "description": "Internal decimal level",
+                    "type": "number"
+                },
+                "fit_diff": {
+                    "type": "number"
+                },
+                "fit_diff_delta": {
+                    "description": "fit_diff - ds，正数表示实际难度高于定数",
+                    "type": "number"
+                },
+                "game_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "level": {
+                    "description": "13, 13+, 14, etc.",
+                    "type": "string"
+                },
+                "notes": {
+                    "description": "JSON array: [tap, hold, slide, touch, break]",
+                    "type": "string"
+                },
+                "result_id": {
+                    "type": "integer"
+                },
+                "result_type": {
+                    "description": "chart: 谱面自身的分析结果; song: 谱面没有单独结果时使用的歌曲总览",
+                    "type": "string"
+                },
+                "sample_count": {
+                    "type": "integer"
+                },
+                "sentiment": {
+                    "type": "string"
+                },
+                "song_id": {
+                    "type": "integer"
+                },
+                "std_dev": {
+                    "type": "number"
+                },
+                "summary": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.AnalysisTag"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.ChartSearchResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.ChartSearchItem"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.ChartSection": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "description": "段落的配置与难点",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "段落名称，如 \"尾杀\"、\"中段交互\"",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.ChartSnapshot": {
+            "type": "object",
+            "properties": {
+                "avg_achievement": {
+                    "type": "number"
+                },
+                "avg_dx": {
+                    "type": "number"
+                },
+                "chart_id": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "ds": {
+                    "type": "number"
+                },
+                "fit_diff": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "level": {
+                    "type": "string"
+                },
+                "sample_count": {
+                    "type": "integer"
+                },
+                "std_dev": {
+                    "type": "number"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.ChartTimeline": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.ChartChange"
+                    }
+                },
+                "chart": {
+                    "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.Chart"
+                },
+                "snapshots": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.ChartSnapshot"
+                    }
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.CommentSearchHit": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "chart_id": {
+                    "type": "integer"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "external_id": {
+                    "description": "同一来源内唯一",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "likes": {
+                    "description": "点赞数，重复采集时更新",
+                    "type": "integer"
+                },
+                "parent_external_id": {
+                    "description": "楼中楼回复所属的顶层评论ID",
+                    "type": "string"
+                },
+                "post_date": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "search_tag": {
+                    "description": "The keyword used to find this comment",
+                    "type": "string"
+                },
+                "sentiment": {
+                    "description": "-1.0 to 1.0",
+                    "type": "number"
+                },
+                "song_id": {
+                    "type": "integer"
+                },
+                "source": {
+                    "description": "Bilibili, Tieba, etc.",
+                    "type": "string"
+                },
+                "source_title": {
+                    "description": "Title of the source (e.g. Video Title)",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.CommentSearchResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.CommentSearchHit"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.Job": {
             "type": "object",
             "properties": {
                 "createdAt": {
@@ -517,23 +1685,41 @@ const docTemplate = `{
                 "deletedAt": {
                     "$ref": "#/definitions/gorm.DeletedAt"
                 },
-                "difficulty_analysis": {
+                "failed": {
+                    "type": "integer"
+                },
+                "finished_at": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "rating_advice": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.JobItem"
+                    }
+                },
+                "resume_at": {
+                    "description": "暂停中的作业预计恢复的时间",
                     "type": "string"
                 },
-                "summary": {
+                "started_at": {
                     "type": "string"
                 },
-                "target_id": {
+                "status": {
+                    "description": "pending, running, paused, done, failed",
+                    "type": "string"
+                },
+                "succeeded": {
                     "type": "integer"
                 },
-                "target_type": {
-                    "description": "Song or Chart",
+                "total": {
+                    "description": "子项总数",
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "collect, backfill, analysis",
                     "type": "string"
                 },
                 "updatedAt": {
@@ -541,57 +1727,191 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_xumoe-c_maiecho_server_internal_model.Chart": {
+        "github_com_xumoe-c_maiecho_server_internal_model.JobItem": {
             "type": "object",
             "properties": {
-                "avg_achievement": {
-                    "type": "number"
+                "createdAt": {
+                    "type": "string"
                 },
-                "avg_dx": {
-                    "type": "number"
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
                 },
-                "charter": {
-                    "description": "NotesDesigner",
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
                     "type": "string"
                 },
-                "createdAt": {
+                "finished_at": {
                     "type": "string"
                 },
-                "deletedAt": {
-                    "$ref": "#/definitions/gorm.DeletedAt"
+                "game_id": {
+                    "type": "integer"
                 },
-                "difficulty": {
-                    "description": "Basic, Advanced, Expert, Master, Re:Master",
+                "id": {
+                    "type": "integer"
+                },
+                "job_id": {
+                    "type": "integer"
+                },
+                "keyword": {
                     "type": "string"
                 },
-                "ds": {
-                    "description": "Internal decimal level",
+                "song_id": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "done, failed",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.JobListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.Job"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.LLMBudgetStatus": {
+            "type": "object",
+            "properties": {
+                "cost": {
                     "type": "number"
                 },
-                "fit_diff": {
+                "daily_budget": {
                     "type": "number"
                 },
-                "id": {
+                "daily_token_budget": {
                     "type": "integer"
                 },
-                "level": {
-                    "description": "13, 13+, 14, etc.",
+                "day": {
                     "type": "string"
                 },
-                "notes": {
-                    "description": "JSON array: [tap, hold, slide, touch, break]",
+                "exceeded": {
+                    "description": "超出预算时分析作业暂停到次日",
+                    "type": "boolean"
+                },
+                "total_tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.LLMUsageDay": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "type": "integer"
+                },
+                "completion_tokens": {
+                    "type": "integer"
+                },
+                "cost": {
+                    "type": "number"
+                },
+                "day": {
                     "type": "string"
                 },
-                "sample_count": {
+                "prompt_tokens": {
                     "type": "integer"
                 },
-                "song_id": {
+                "total_tokens": {
                     "type": "integer"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.LLMUsageResponse": {
+            "type": "object",
+            "properties": {
+                "budget": {
+                    "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.LLMBudgetStatus"
                 },
-                "std_dev": {
+                "by_day": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.LLMUsageDay"
+                    }
+                },
+                "by_role": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.LLMUsageRole"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.LLMUsageTotals"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.LLMUsageRole": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "type": "integer"
+                },
+                "completion_tokens": {
+                    "type": "integer"
+                },
+                "cost": {
                     "type": "number"
                 },
-                "updatedAt": {
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "total_tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.LLMUsageTotals": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "type": "integer"
+                },
+                "completion_tokens": {
+                    "type": "integer"
+                },
+                "cost": {
+                    "type": "number"
+                },
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "total_tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_model.LevelRange": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "string"
+                },
+                "min": {
                     "type": "string"
                 }
             }
@@ -636,6 +1956,10 @@ const docTemplate = `{
                 "is_new": {
                     "type": "boolean"
                 },
+                "last_scraped_at": {
+                    "description": "上次成功采集的时间",
+                    "type": "string"
+                },
                 "release_date": {
                     "type": "string"
                 },
@@ -670,6 +1994,10 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
+                "is_suitable": {
+                    "description": "nil: unchecked, true: suitable, false: unsuitable",
+                    "type": "boolean"
+                },
                 "song_id": {
                     "type": "integer"
                 },
@@ -692,21 +2020,125 @@ const docTemplate = `{
                 }
             }
         },
+        "github_com_xumoe-c_maiecho_server_internal_progress.Event": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "job_id": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "song_id": {
+                    "type": "integer"
+                },
+                "stage": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_status.CollectorStatus": {
+            "type": "object",
+            "properties": {
+                "ban_reason": {
+                    "description": "最近一次触发封禁的原因",
+                    "type": "string"
+                },
+                "ban_state": {
+                    "description": "closed, open, half_open",
+                    "type": "string"
+                },
+                "ban_trips": {
+                    "description": "连续触发封禁的次数",
+                    "type": "integer"
+                },
+                "banned": {
+                    "type": "boolean"
+                },
+                "banned_until": {
+                    "description": "冷却结束时间",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_status.DiscoveryStatus": {
+            "type": "object",
+            "properties": {
+                "last_run_at": {
+                    "type": "string"
+                },
+                "running": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_xumoe-c_maiecho_server_internal_status.SchedulerStatus": {
+            "type": "object",
+            "properties": {
+                "busy_workers": {
+                    "type": "integer"
+                },
+                "failed_tasks": {
+                    "type": "integer"
+                },
+                "queue_length": {
+                    "description": "待执行任务数",
+                    "type": "integer"
+                },
+                "running_tasks": {
+                    "type": "integer"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
         "github_com_xumoe-c_maiecho_server_internal_status.SystemStatus": {
             "type": "object",
             "properties": {
                 "active_tasks": {
                     "type": "integer"
                 },
+                "collectors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_status.CollectorStatus"
+                    }
+                },
+                "discovery": {
+                    "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_status.DiscoveryStatus"
+                },
                 "goroutines": {
                     "type": "integer"
                 },
                 "last_log_entry": {
                     "type": "string"
                 },
+                "llm_in_flight": {
+                    "type": "integer"
+                },
                 "memory_usage_mb": {
                     "type": "integer"
                 },
+                "recent_errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_logger.ErrorEntry"
+                    }
+                },
+                "scheduler": {
+                    "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_status.SchedulerStatus"
+                },
                 "uptime": {
                     "$ref": "#/definitions/time.Duration"
                 }
@@ -724,6 +2156,20 @@ const docTemplate = `{
                 }
             }
         },
+        "internal_controller.AnalysisResponse": {
+            "type": "object",
+            "properties": {
+                "chart_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.AnalysisResult"
+                    }
+                },
+                "song_result": {
+                    "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_model.AnalysisResult"
+                }
+            }
+        },
         "internal_controller.BatchAnalysisRequest": {
             "type": "object",
             "required": [
@@ -746,6 +2192,21 @@ const docTemplate = `{
                 },
                 "keyword": {
                     "type": "string"
+                },
+                "source": {
+                    "description": "可选：只使用指定名称的采集器",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_controller.CollectorListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_xumoe-c_maiecho_server_internal_status.CollectorStatus"
+                    }
                 }
             }
         },
@@ -10,24 +10,33 @@ import (
 	"github.com/xumoe-c/maiecho/server/internal/llm"
 	"github.com/xumoe-c/maiecho/server/internal/logger"
 	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/progress"
 	"github.com/xumoe-c/maiecho/server/internal/storage"
 )
 
 type Analyzer struct {
 	storage storage.Storage
-	llm     *llm.Client
+	analyst llm.ChatClient
+	advisor llm.ChatClient
 	cleaner *Cleaner
 	mapper  *Mapper
 	kb      *KnowledgeBase
 	prompts *config.PromptConfig
 }
 
-func NewAnalyzer(s storage.Storage, llm *llm.Client, prompts *config.PromptConfig) *Analyzer {
+// NewAnalyzer 创建分析器，各 Agent 角色使用 router 中对应的 LLM 路由
+func NewAnalyzer(s storage.Storage, router *llm.Router, prompts *config.PromptConfig) *Analyzer {
+	return newAnalyzer(s, func(role string) llm.ChatClient { return router.For(role) }, prompts)
+}
+
+// newAnalyzer 创建分析器，clientFor 返回各 Agent 角色使用的 LLM 客户端 (测试中替换为 llmtest.Fake)
+func newAnalyzer(s storage.Storage, clientFor func(role string) llm.ChatClient, prompts *config.PromptConfig) *Analyzer {
 	return &Analyzer{
 		storage: s,
-		llm:     llm,
-		cleaner: NewCleaner(llm, prompts),
-		mapper:  NewMapper(s, llm, prompts),
+		analyst: clientFor(llm.RoleAnalyst),
+		advisor: clientFor(llm.RoleAdvisor),
+		cleaner: NewCleaner(clientFor(llm.RoleCleaner), prompts),
+		mapper:  NewMapper(s, clientFor(llm.RoleMapper), prompts),
 		kb:      NewKnowledgeBase(prompts),
 		prompts: prompts,
 	}
@@ -81,12 +90,17 @@ func (a *Analyzer) RunMapping(ctx context.Context) error {
 
 // AnalyzeSong 对一首歌曲执行完整的分析流程
 func (a *Analyzer) AnalyzeSong(ctx context.Context, songID uint) error {
+	ctx = llm.WithSongID(ctx, songID)
+
 	// 1. 获取歌曲信息
 	song, err := a.storage.GetSong(songID)
 	if err != nil {
 		return fmt.Errorf("获取歌曲失败: %w", err)
 	}
 
+	// 定数变更历史，用于在谱面信息中注明 "由 13.7 调整为 13.9"
+	chartChanges := a.loadChartChanges(song.ID)
+
 	// 2. 获取评论
 	comments, err := a.storage.GetCommentsBySongID(song.ID)
 	if err != nil {
@@ -119,6 +133,10 @@ func (a *Analyzer) AnalyzeSong(ctx context.Context, songID uint) error {
 		}
 	}
 
+	progress.Report(ctx, progress.StageCommentsLoaded, "评论加载完成", map[string]interface{}{
+		"count": len(comments),
+	})
+
 	if len(comments) == 0 {
 		logger.Info("未找到歌曲的评论", "module", "agent.analyzer", "songTitle", song.Title)
 		return nil
@@ -187,6 +205,15 @@ func (a *Analyzer) AnalyzeSong(ctx context.Context, songID uint) error {
 	// 3.1 LLM 深度清洗 (Semantic Cleaning) - 对每个桶分别清洗太耗时，这里简化为只对总数过多的桶清洗
 	// 或者跳过 LLM 清洗，直接进入分析阶段，依靠 Analyst 的能力过滤噪声
 
+	bucketSizes := make(map[string]interface{}, len(commentBuckets))
+	for chartID, bucket := range commentBuckets {
+		bucketSizes[fmt.Sprintf("%d", chartID)] = len(bucket)
+	}
+	progress.Report(ctx, progress.StageBucketed, "评论分桶完成", map[string]interface{}{
+		"valid_comments": len(seenComments),
+		"buckets":        bucketSizes,
+	})
+
 	if len(commentBuckets) == 0 {
 		return nil
 	}
@@ -198,10 +225,16 @@ func (a *Analyzer) AnalyzeSong(ctx context.Context, songID uint) error {
 	// }
 
 	// 4.2 分析具体谱面桶
+	chartTotal := len(commentBuckets)
+	if _, ok := commentBuckets[0]; ok {
+		chartTotal--
+	}
+	chartIndex := 0
 	for chartID, bucketComments := range commentBuckets {
 		if chartID == 0 {
 			continue // 稍后处理通用桶
 		}
+		chartIndex++
 
 		// 获取对应的 Chart 对象
 		var targetChart model.Chart
@@ -212,9 +245,17 @@ func (a *Analyzer) AnalyzeSong(ctx context.Context, songID uint) error {
 			}
 		}
 
-		if err := a.analyzeChartBucket(ctx, song, &targetChart, bucketComments); err != nil {
+		chartData := map[string]interface{}{
+			"chart_id":   chartID,
+			"difficulty": targetChart.Difficulty,
+			"index":      chartIndex,
+			"total":      chartTotal,
+		}
+		if err := a.analyzeChartBucket(ctx, song, &targetChart, chartChanges[chartID], bucketComments); err != nil {
 			logger.Error("分析谱面失败", "module", "agent.analyzer", "chartID", chartID, "error", err)
+			chartData["error"] = err.Error()
 		}
+		progress.Report(ctx, progress.StageChartAnalyzed, fmt.Sprintf("谱面 %d/%d 分析完成", chartIndex, chartTotal), chartData)
 	}
 
 	// 4.3 (可选) 如果没有具体谱面的评论，或者为了生成总览，可以分析通用桶
@@ -258,7 +299,7 @@ func (a *Analyzer) AnalyzeSong(ctx context.Context, songID uint) error {
 		aliasStr := strings.Join(aliases, ", ")
 
 		// 准备谱面信息字符串 (只包含 Expert, Master, Re:Master)
-		chartInfoStr := a.formatChartInfo(song.Charts)
+		chartInfoStr := a.formatChartInfo(song.Charts, chartChanges)
 
 		// 对块运行分析师
 		out, reasoning, err := a.runAnalyst(ctx, chunkStr, termGuide, aliasStr, chartInfoStr)
@@ -284,6 +325,7 @@ func (a *Analyzer) AnalyzeSong(ctx context.Context, songID uint) error {
 	if err != nil {
 		return fmt.Errorf("顾问运行失败: %w", err)
 	}
+	progress.Report(ctx, progress.StageAdvisorDone, "顾问报告生成完成", nil)
 
 	// 7. 保存结果
 	result := &model.AnalysisResult{
@@ -294,10 +336,14 @@ func (a *Analyzer) AnalyzeSong(ctx context.Context, songID uint) error {
 		DifficultyAnalysis: advisorOutput.DifficultyAnalysis,
 		ReasoningLog:       strings.Join(reasoningLogs, "\n\n"),
 	}
+	mergedAnalystOutput.apply(result)
 
 	if err := a.storage.CreateAnalysisResult(result); err != nil {
 		return fmt.Errorf("保存分析结果失败: %w", err)
 	}
+	progress.Report(ctx, progress.StageSaved, "分析结果已保存", map[string]interface{}{
+		"result_id": result.ID,
+	})
 
 	logger.Info("已保存歌曲的分析结果", "module", "agent.analyzer", "songTitle", song.Title)
 	return nil
@@ -328,24 +374,14 @@ func (a *Analyzer) runAnalyst(ctx context.Context, comments, termGuide, aliases,
 		return nil, "", fmt.Errorf("failed to execute user prompt template: %w", err)
 	}
 
-	content, reasoning, err := a.llm.ChatWithReasoning(ctx, systemPrompt, userPrompt)
-	if err != nil {
-		return nil, "", err
-	}
-
-	// 清理响应（移除 markdown 代码块）
-	content = strings.TrimPrefix(content, "```json")
-	content = strings.TrimPrefix(content, "```")
-	content = strings.TrimSuffix(content, "```")
-	content = strings.TrimSpace(content)
-
-	var output AnalystOutput
-	if err := json.Unmarshal([]byte(content), &output); err != nil {
-		return nil, reasoning, fmt.Errorf("解析分析师输出失败: %w. 响应: %s", err, content)
+	var output analystResponse
+	if err := a.analyst.ChatJSON(ctx, systemPrompt, userPrompt, &output); err != nil {
+		return nil, "", fmt.Errorf("获取分析师输出失败: %w", err)
 	}
-	return &output, reasoning, nil
+	return &output.AnalystOutput, output.Reasoning, nil
 }
 
+// runAdvisor 生成歌曲级的总览建议
 func (a *Analyzer) runAdvisor(ctx context.Context, song *model.Song, analystData *AnalystOutput) (*AdvisorOutput, error) {
 	analystJson, _ := json.Marshal(analystData)
 
@@ -380,25 +416,104 @@ func (a *Analyzer) runAdvisor(ctx context.Context, song *model.Song, analystData
 		return nil, fmt.Errorf("failed to execute user prompt template: %w", err)
 	}
 
-	response, err := a.llm.Chat(ctx, systemPrompt, userPrompt)
-	if err != nil {
+	var output AdvisorOutput
+	if err := a.chatAdvisor(ctx, systemPrompt, userPrompt, &output, &output.Summary, "song", song.ID); err != nil {
 		return nil, err
 	}
+	return &output, nil
+}
 
-	// 清理响应
-	response = strings.TrimPrefix(response, "```json")
-	response = strings.TrimPrefix(response, "```")
-	response = strings.TrimSuffix(response, "```")
+// runChartAdvisor 使用谱面顾问提示词生成针对单个谱面的建议，提示词中包含该谱面的难度、定数、物量和谱师
+func (a *Analyzer) runChartAdvisor(ctx context.Context, song *model.Song, chart *model.Chart, changes []model.ChartChange, analystData *AnalystOutput) (*ChartAdvisorOutput, error) {
+	analystJson, _ := json.Marshal(analystData)
 
-	var output AdvisorOutput
-	if err := json.Unmarshal([]byte(response), &output); err != nil {
-		return nil, fmt.Errorf("解析顾问输出失败: %w. 响应: %s", err, response)
+	var aliases []string
+	for _, alias := range song.Aliases {
+		aliases = append(aliases, alias.Alias)
+	}
+
+	systemData := struct {
+		Title        string
+		Artist       string
+		Aliases      string
+		Difficulty   string
+		Level        string
+		DS           string
+		FitDiff      string
+		FitDiffDelta string
+		Notes        string
+		Charter      string
+		Changes      string
+	}{
+		Title:        song.Title,
+		Artist:       song.Artist,
+		Aliases:      strings.Join(aliases, ", "),
+		Difficulty:   chart.Difficulty,
+		Level:        chart.Level,
+		DS:           fmt.Sprintf("%.1f", chart.DS),
+		FitDiff:      fmt.Sprintf("%.2f", chart.FitDiff),
+		FitDiffDelta: fmt.Sprintf("%+.2f", chart.FitDiff-chart.DS),
+		Notes:        formatNotes(chart.Notes),
+		Charter:      chart.Charter,
+		Changes:      formatChartChanges(changes),
+	}
+	if systemData.Charter == "" || systemData.Charter == "-" {
+		systemData.Charter = "未知"
+	}
+	systemPrompt, err := ExecuteTemplate(a.prompts.Agent.ChartAdvisor.System, systemData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute system prompt template: %w", err)
+	}
+
+	userData := struct {
+		AnalysisData string
+	}{
+		AnalysisData: string(analystJson),
+	}
+	userPrompt, err := ExecuteTemplate(a.prompts.Agent.ChartAdvisor.User, userData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute user prompt template: %w", err)
+	}
+
+	var output ChartAdvisorOutput
+	if err := a.chatAdvisor(ctx, systemPrompt, userPrompt, &output, &output.Summary, "chart", chart.ID); err != nil {
+		return nil, err
 	}
 	return &output, nil
 }
 
+// chatAdvisor 请求顾问的结构化输出，summary 指向 out 中的摘要字段
+// context 中注入了流式输出的汇报函数时以流式方式请求，摘要边生成边推送 (advisor_delta)
+func (a *Analyzer) chatAdvisor(ctx context.Context, systemPrompt, userPrompt string, out any, summary *string, targetType string, targetID uint) error {
+	var err error
+	if progress.Streaming(ctx) {
+		streamer := newFieldStreamer("summary", func(delta, text string) {
+			progress.ReportStream(ctx, progress.StageAdvisorDelta, "", map[string]interface{}{
+				"target_type": targetType,
+				"target_id":   targetID,
+				"delta":       delta,
+				"text":        text,
+			})
+		})
+		err = a.advisor.ChatJSONStream(ctx, systemPrompt, userPrompt, out, streamer.Write)
+	} else {
+		err = a.advisor.ChatJSON(ctx, systemPrompt, userPrompt, out)
+	}
+	if err != nil {
+		return fmt.Errorf("获取顾问输出失败: %w", err)
+	}
+
+	// 输出经过修复时流式推送的摘要可能与最终结果不同，以该事件为准
+	progress.ReportStream(ctx, progress.StageAdvisorSummary, "顾问报告摘要生成完成", map[string]interface{}{
+		"target_type": targetType,
+		"target_id":   targetID,
+		"summary":     *summary,
+	})
+	return nil
+}
+
 // analyzeChartBucket 对单个谱面的评论桶进行分析
-func (a *Analyzer) analyzeChartBucket(ctx context.Context, song *model.Song, chart *model.Chart, comments []string) error {
+func (a *Analyzer) analyzeChartBucket(ctx context.Context, song *model.Song, chart *model.Chart, changes []model.ChartChange, comments []string) error {
 	if len(comments) == 0 {
 		return nil
 	}
@@ -420,13 +535,7 @@ func (a *Analyzer) analyzeChartBucket(ctx context.Context, song *model.Song, cha
 	aliasStr := strings.Join(aliases, ", ")
 
 	// 谱面数据 (仅针对当前 Chart)
-	diff := chart.FitDiff - chart.DS
-	sign := "+"
-	if diff < 0 {
-		sign = ""
-	}
-	chartInfoStr := fmt.Sprintf("[%s] DS: %.1f, Fit: %.2f (Diff: %s%.2f)",
-		chart.Difficulty, chart.DS, chart.FitDiff, sign, diff)
+	chartInfoStr := formatChartLine(chart, changes)
 
 	// 2. 运行分析师 (Analyst)
 	// 注意：这里复用了 runAnalyst，它会使用通用的 Analyst Prompt。
@@ -436,11 +545,8 @@ func (a *Analyzer) analyzeChartBucket(ctx context.Context, song *model.Song, cha
 		return fmt.Errorf("分析师运行失败: %w", err)
 	}
 
-	// 3. 运行顾问 (Advisor) - 生成针对该谱面的建议
-	// 同样复用 runAdvisor，但我们需要让 Advisor 知道这是针对特定谱面的
-	// 我们可以临时修改 song.Title 或者在 analystData 中注入信息，但最稳妥的是修改 runAdvisor 接口或 Prompt。这里我们简单地将 Chart 信息附加到 AnalystOutput 的 Summary 中，或者依赖 Advisor 自己从 AnalysisData 中读取
-	// 为了简单起见，我们直接使用 runAdvisor，它会生成一份报告。
-	advisorOut, err := a.runAdvisor(ctx, song, analystOut)
+	// 3. 运行谱面顾问 (Chart Advisor) - 提示词中包含该谱面的难度、定数、物量和谱师
+	advisorOut, err := a.runChartAdvisor(ctx, song, chart, changes, analystOut)
 	if err != nil {
 		return fmt.Errorf("顾问运行失败: %w", err)
 	}
@@ -453,7 +559,9 @@ func (a *Analyzer) analyzeChartBucket(ctx context.Context, song *model.Song, cha
 		RatingAdvice:       advisorOut.RatingAdvice,
 		DifficultyAnalysis: advisorOut.DifficultyAnalysis,
 		ReasoningLog:       reasoning,
+		ChartReport:        advisorOut.report(),
 	}
+	analystOut.apply(result)
 
 	if err := a.storage.CreateAnalysisResult(result); err != nil {
 		return fmt.Errorf("保存谱面分析结果失败: %w", err)
@@ -462,28 +570,19 @@ func (a *Analyzer) analyzeChartBucket(ctx context.Context, song *model.Song, cha
 	return nil
 }
 
-func (a *Analyzer) formatChartInfo(charts []model.Chart) string {
+func (a *Analyzer) formatChartInfo(charts []model.Chart, changes map[uint][]model.ChartChange) string {
 	var infos []string
-	for _, c := range charts {
+	for i := range charts {
+		c := &charts[i]
 		// 只关注 Expert, Master, Re:Master
 		if c.Difficulty != "Expert" && c.Difficulty != "Master" && c.Difficulty != "Re:Master" {
 			continue
 		}
 
-		diff := c.FitDiff - c.DS
-		sign := "+"
-		if diff < 0 {
-			sign = ""
-		}
-
-		// 格式: [DX Master] DS: 13.5, Fit: 13.8 (Diff: +0.3)
 		// 注意: Chart 模型中没有直接存储 DX/Std 标记，通常需要从 Song.Type 或 Chart 属性推断，但这里我们假设 Chart 列表已经包含了所有版本。如果 Chart 模型本身没有区分 DX/Std 的字段，
 		// 我们可能只能显示难度。根据 model.Song 定义，Type 是在 Song 上的。如果一首歌同时有 DX 和 Std 谱面，通常在 Diving-Fish API 中是作为两个不同的 Song 对象存在的。
 		// 所以这里直接用 c.Difficulty 即可。
-
-		info := fmt.Sprintf("[%s] DS: %.1f, Fit: %.2f (Diff: %s%.2f)",
-			c.Difficulty, c.DS, c.FitDiff, sign, diff)
-		infos = append(infos, info)
+		infos = append(infos, formatChartLine(c, changes[c.ID]))
 	}
 	if len(infos) == 0 {
 		return "暂无高难度谱面数据"
@@ -491,6 +590,88 @@ func (a *Analyzer) formatChartInfo(charts []model.Chart) string {
 	return strings.Join(infos, "; ")
 }
 
+// maxChartChangesInPrompt 谱面信息中最多列出的定数变更次数
+const maxChartChangesInPrompt = 3
+
+// formatChartLine 格式化单个谱面的数据
+// 格式: [Master] DS: 13.9, Fit: 14.10 (Diff: +0.20), 定数变更: 13.7→13.9 (2025-01-01)
+// changes 为按时间倒序排列的定数变更，只列出最近几次
+func formatChartLine(c *model.Chart, changes []model.ChartChange) string {
+	diff := c.FitDiff - c.DS
+	sign := "+"
+	if diff < 0 {
+		sign = ""
+	}
+	info := fmt.Sprintf("[%s] DS: %.1f, Fit: %.2f (Diff: %s%.2f)",
+		c.Difficulty, c.DS, c.FitDiff, sign, diff)
+
+	if len(changes) > 0 {
+		info += ", 定数变更: " + formatChartChanges(changes)
+	}
+	return info
+}
+
+// formatChartChanges 按时间顺序列出最近几次定数变更，如 "13.7→13.9 (2025-01-01)"
+// changes 为按时间倒序排列的定数变更
+func formatChartChanges(changes []model.ChartChange) string {
+	if len(changes) == 0 {
+		return "无"
+	}
+	if len(changes) > maxChartChangesInPrompt {
+		changes = changes[:maxChartChangesInPrompt]
+	}
+	var notes []string
+	for i := len(changes) - 1; i >= 0; i-- {
+		ch := changes[i]
+		notes = append(notes, fmt.Sprintf("%.1f→%.1f (%s)", ch.OldDS, ch.NewDS, ch.CreatedAt.Format("2006-01-02")))
+	}
+	return strings.Join(notes, ", ")
+}
+
+// noteTypes 物量数组中各音符的名称，标准谱面没有 Touch
+var (
+	noteTypesDX  = []string{"Tap", "Hold", "Slide", "Touch", "Break"}
+	noteTypesStd = []string{"Tap", "Hold", "Slide", "Break"}
+)
+
+// formatNotes 格式化谱面物量 (JSON 数组)，如 "Tap 512 / Hold 60 / Slide 88 / Touch 25 / Break 40 (总计 725)"
+func formatNotes(notes string) string {
+	var counts []int
+	if err := json.Unmarshal([]byte(notes), &counts); err != nil {
+		return "未知"
+	}
+	var names []string
+	switch len(counts) {
+	case len(noteTypesDX):
+		names = noteTypesDX
+	case len(noteTypesStd):
+		names = noteTypesStd
+	default:
+		return "未知"
+	}
+	total := 0
+	parts := make([]string, len(counts))
+	for i, n := range counts {
+		parts[i] = fmt.Sprintf("%s %d", names[i], n)
+		total += n
+	}
+	return fmt.Sprintf("%s (总计 %d)", strings.Join(parts, " / "), total)
+}
+
+// loadChartChanges 加载歌曲各谱面的定数变更 (ChartID -> 按时间倒序的变更)，失败时只记录日志
+func (a *Analyzer) loadChartChanges(songID uint) map[uint][]model.ChartChange {
+	items, _, err := a.storage.GetChartChanges(model.ChartChangeFilter{SongID: songID})
+	if err != nil {
+		logger.Error("获取定数变更失败", "module", "agent.analyzer", "songID", songID, "error", err)
+		return nil
+	}
+	changes := make(map[uint][]model.ChartChange)
+	for _, item := range items {
+		changes[item.ChartID] = append(changes[item.ChartID], item.ChartChange)
+	}
+	return changes
+}
+
 func (a *Analyzer) mergeAnalystOutputs(outputs []*AnalystOutput) *AnalystOutput {
 	merged := &AnalystOutput{
 		DifficultyTags: []string{},
@@ -530,9 +711,32 @@ func (a *Analyzer) mergeAnalystOutputs(outputs []*AnalystOutput) *AnalystOutput
 				seenCons[con] = true
 			}
 		}
-		if out.Sentiment != "Neutral" {
-			merged.Sentiment = out.Sentiment
-		}
 	}
+	merged.Sentiment = majoritySentiment(outputs)
 	return merged
 }
+
+// majoritySentiment 按多数票合并各批次的情感倾向
+// 得票最多的倾向胜出；最高票并列时 (例如正负各半) 视为 Neutral，没有有效输出时同样为 Neutral
+func majoritySentiment(outputs []*AnalystOutput) string {
+	votes := make(map[string]int)
+	for _, out := range outputs {
+		if out.Sentiment != "" {
+			votes[out.Sentiment]++
+		}
+	}
+
+	best, bestVotes, tied := "Neutral", 0, false
+	for _, sentiment := range []string{"Positive", "Neutral", "Negative"} {
+		switch n := votes[sentiment]; {
+		case n > bestVotes:
+			best, bestVotes, tied = sentiment, n, false
+		case n == bestVotes && n > 0:
+			tied = true
+		}
+	}
+	if tied {
+		return "Neutral"
+	}
+	return best
+}
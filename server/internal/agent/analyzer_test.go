@@ -0,0 +1,340 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+	"github.com/xumoe-c/maiecho/server/internal/llm"
+	"github.com/xumoe-c/maiecho/server/internal/llm/llmtest"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/progress"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+)
+
+const (
+	songAnalystReply = `{"reasoning":"整体评价正面，曲子受欢迎","difficulty_tags":["14+"],"key_patterns":["交互"],` +
+		`"pros":["曲子好听"],"cons":[],"sentiment":"Positive","version_analysis":""}`
+	// 谱面分析的回复带有代码块，校验前应被去掉
+	chartAnalystReply = "```json\n" + `{"reasoning":"多条评论提到尾杀","difficulty_tags":["体力谱"],"key_patterns":["尾杀"," 尾杀 ","纵连"],` +
+		`"pros":[],"cons":["尾杀太难"],"sentiment":"Negative","version_analysis":"Master 诈称"}` + "\n```"
+	songAdvisorReply  = `{"summary":"好听的高难曲","rating_advice":"先练交互","difficulty_analysis":"整体偏难"}`
+	chartAdvisorReply = `{"summary":"尾杀决定成绩","rating_advice":"背熟尾杀","difficulty_analysis":"Master 实际难度高于定数",` +
+		`"key_sections":[{"name":"尾杀","description":"高密度星星"}],"recommended_level":{"min":"14","max":"14+"},` +
+		`"practice_tips":["先用低速练尾杀"],"tricks":[]}`
+)
+
+// analyzerFixture 基于内存 SQLite 与 LLM 替身的分析器
+type analyzerFixture struct {
+	analyzer *Analyzer
+	store    *storage.Database
+	fakes    map[string]*llmtest.Fake
+	song     *model.Song
+}
+
+func newAnalyzerFixture(t *testing.T) *analyzerFixture {
+	t.Helper()
+
+	// LoadPrompts 从当前目录读取 prompts.yaml，测试使用仓库中的正式提示词
+	t.Chdir("../../prompts")
+	prompts, err := config.LoadPrompts()
+	if err != nil {
+		t.Fatalf("LoadPrompts() error = %v", err)
+	}
+
+	store, err := storage.NewDatabase("file:" + strings.ReplaceAll(t.Name(), "/", "_") + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if db, err := store.DB.DB(); err == nil {
+			db.Close()
+		}
+	})
+
+	song := &model.Song{
+		GameID: 11311, Title: "PANDORA PARADOXXX", Type: "DX", Artist: "削除",
+		Charts: []model.Chart{
+			{Difficulty: "Expert", Level: "13", DS: 13.2, FitDiff: 13.35},
+			{Difficulty: "Master", Level: "14+", DS: 14.8, FitDiff: 15.02, Notes: "[812,64,120,40,52]", Charter: "ぴちネコ"},
+		},
+		Aliases: []model.SongAlias{{Alias: "潘多拉"}},
+	}
+	if err := store.CreateSong(song); err != nil {
+		t.Fatalf("CreateSong() error = %v", err)
+	}
+	comments := []model.Comment{
+		{SourceTitle: "【舞萌DX】潘多拉 紫谱 AP 手元", Content: "尾杀太难了，体力完全跟不上"},
+		{SourceTitle: "【舞萌DX】潘多拉 紫谱 AP 手元", Content: "<p>尾杀   星星很多</p>"},
+		{SourceTitle: "【舞萌DX】潘多拉 紫谱 AP 手元", Content: "前排围观"}, // 噪音
+		{SourceTitle: "舞萌 潘多拉 宴谱 自制", Content: "这个改谱太有意思了"},  // 非官方谱面
+		{SourceTitle: "舞萌 听歌 推荐", Content: "这首曲子真的非常好听"},     // 通用桶
+		{SourceTitle: "舞萌 听歌 推荐", Content: "这首曲子真的非常好听"},     // 重复
+	}
+	for i := range comments {
+		c := &comments[i]
+		c.Source = "bilibili"
+		c.ExternalID = string(rune('a' + i))
+		c.SongID = &song.ID
+		if err := store.CreateComment(c); err != nil {
+			t.Fatalf("CreateComment() error = %v", err)
+		}
+	}
+
+	fakes := map[string]*llmtest.Fake{}
+	for _, role := range llm.Roles {
+		fakes[role] = llmtest.NewFake()
+	}
+	// 歌曲总览的谱面数据包含全部高难度谱面，谱面分析只包含当前谱面，先匹配总览
+	fakes[llm.RoleAnalyst].
+		ReplyContaining("[Expert] DS: 13.2", songAnalystReply).
+		ReplyContaining("[Master] DS: 14.8", chartAnalystReply)
+	fakes[llm.RoleAdvisor].
+		ReplyContaining("曲子好听", songAdvisorReply).
+		ReplyContaining("尾杀太难", chartAdvisorReply)
+
+	return &analyzerFixture{
+		analyzer: newAnalyzer(store, func(role string) llm.ChatClient { return fakes[role] }, prompts),
+		store:    store,
+		fakes:    fakes,
+		song:     song,
+	}
+}
+
+func (f *analyzerFixture) chart(difficulty string) model.Chart {
+	for _, c := range f.song.Charts {
+		if c.Difficulty == difficulty {
+			return c
+		}
+	}
+	return model.Chart{}
+}
+
+func TestAnalyzeSong(t *testing.T) {
+	f := newAnalyzerFixture(t)
+
+	if err := f.analyzer.AnalyzeSong(context.Background(), f.song.ID); err != nil {
+		t.Fatalf("AnalyzeSong() error = %v", err)
+	}
+
+	analystCalls := f.fakes[llm.RoleAnalyst].Calls()
+	if len(analystCalls) != 2 {
+		t.Fatalf("analyst calls = %d, want 2 (Master 谱面 + 歌曲总览)", len(analystCalls))
+	}
+	chartCall := f.fakes[llm.RoleAnalyst].CallsContaining("[Master] DS: 14.8, Fit: 15.02 (Diff: +0.22)")
+	if len(chartCall) != 2 {
+		t.Errorf("calls with Master chart info = %d, want 2", len(chartCall))
+	}
+	for _, call := range analystCalls {
+		if strings.Contains(call.User, "前排围观") || strings.Contains(call.User, "改谱") {
+			t.Errorf("analyst prompt contains filtered comment: %q", call.User)
+		}
+		if strings.Count(call.User, "这首曲子真的非常好听") > 1 {
+			t.Errorf("analyst prompt contains duplicate comment: %q", call.User)
+		}
+		if !strings.Contains(call.System, "潘多拉") {
+			t.Errorf("analyst system prompt missing aliases: %q", call.System)
+		}
+	}
+	if !strings.Contains(analystCalls[0].User, "[【舞萌DX】潘多拉 紫谱 AP 手元] 尾杀 星星很多") {
+		t.Errorf("chart analyst prompt = %q, want cleaned comment with source title", analystCalls[0].User)
+	}
+	if n := len(f.fakes[llm.RoleAdvisor].Calls()); n != 2 {
+		t.Errorf("advisor calls = %d, want 2", n)
+	}
+	// 谱面顾问的提示词包含该谱面的数据
+	chartAdvisorCalls := f.fakes[llm.RoleAdvisor].CallsContaining("尾杀太难")
+	if len(chartAdvisorCalls) != 1 {
+		t.Fatalf("chart advisor calls = %d, want 1", len(chartAdvisorCalls))
+	}
+	for _, want := range []string{"难度: Master 14+", "定数 (DS): 14.8", "拟合定数: 15.02 (与定数相差 +0.22",
+		"物量: Tap 812 / Hold 64 / Slide 120 / Touch 40 / Break 52 (总计 1088)", "谱师: ぴちネコ", "定数变更: 无"} {
+		if !strings.Contains(chartAdvisorCalls[0].System, want) {
+			t.Errorf("chart advisor system prompt missing %q:\n%s", want, chartAdvisorCalls[0].System)
+		}
+	}
+	for _, role := range []string{llm.RoleCleaner, llm.RoleMapper} {
+		if n := len(f.fakes[role].Calls()); n != 0 {
+			t.Errorf("%s calls = %d, want 0", role, n)
+		}
+	}
+
+	songResult, err := f.store.GetAnalysisResultsByTarget("song", f.song.ID)
+	if err != nil {
+		t.Fatalf("歌曲分析结果不存在: %v", err)
+	}
+	if songResult.Summary != "好听的高难曲" || songResult.RatingAdvice != "先练交互" {
+		t.Errorf("song result = %+v", songResult)
+	}
+	if !strings.Contains(songResult.ReasoningLog, "整体评价正面") {
+		t.Errorf("song reasoning log = %q", songResult.ReasoningLog)
+	}
+
+	master := f.chart("Master")
+	chartResult, err := f.store.GetAnalysisResultsByTarget("chart", master.ID)
+	if err != nil {
+		t.Fatalf("谱面分析结果不存在: %v", err)
+	}
+	if chartResult.Summary != "尾杀决定成绩" || chartResult.ReasoningLog != "多条评论提到尾杀" {
+		t.Errorf("chart result = %+v", chartResult)
+	}
+	wantReport := &model.ChartReport{
+		KeySections:      []model.ChartSection{{Name: "尾杀", Description: "高密度星星"}},
+		RecommendedLevel: model.LevelRange{Min: "14", Max: "14+"},
+		PracticeTips:     []string{"先用低速练尾杀"},
+		Tricks:           []string{},
+	}
+	if !reflect.DeepEqual(chartResult.ChartReport, wantReport) {
+		t.Errorf("chart report = %+v, want %+v", chartResult.ChartReport, wantReport)
+	}
+	if songResult.ChartReport != nil {
+		t.Errorf("song result chart report = %+v, want nil", songResult.ChartReport)
+	}
+
+	// 分析师输出的结构化事实随结果保存，标签去重并去除首尾空白
+	if songResult.Sentiment != "Positive" || !reflect.DeepEqual(songResult.Pros, []string{"曲子好听"}) {
+		t.Errorf("song result sentiment = %q, pros = %v", songResult.Sentiment, songResult.Pros)
+	}
+	if got := tagValues(songResult, model.AnalysisTagPattern); !reflect.DeepEqual(got, []string{"交互"}) {
+		t.Errorf("song result patterns = %v, want [交互]", got)
+	}
+	if chartResult.Sentiment != "Negative" || !reflect.DeepEqual(chartResult.Cons, []string{"尾杀太难"}) {
+		t.Errorf("chart result sentiment = %q, cons = %v", chartResult.Sentiment, chartResult.Cons)
+	}
+	if got := tagValues(chartResult, model.AnalysisTagDifficulty); !reflect.DeepEqual(got, []string{"体力谱"}) {
+		t.Errorf("chart result difficulty tags = %v, want [体力谱]", got)
+	}
+	if got := tagValues(chartResult, model.AnalysisTagPattern); !reflect.DeepEqual(got, []string{"尾杀", "纵连"}) {
+		t.Errorf("chart result patterns = %v, want [尾杀 纵连]", got)
+	}
+	if _, err := f.store.GetAnalysisResultsByTarget("chart", f.chart("Expert").ID); err == nil {
+		t.Error("Expert 谱面没有评论，不应保存分析结果")
+	}
+}
+
+func TestAnalyzeSongStreamsSummary(t *testing.T) {
+	f := newAnalyzerFixture(t)
+
+	var events []map[string]interface{}
+	var stages []string
+	ctx := progress.WithStreamReporter(context.Background(), func(stage, message string, data map[string]interface{}) {
+		stages = append(stages, stage)
+		events = append(events, data)
+	})
+	if err := f.analyzer.AnalyzeSong(ctx, f.song.ID); err != nil {
+		t.Fatalf("AnalyzeSong() error = %v", err)
+	}
+
+	for _, call := range f.fakes[llm.RoleAdvisor].Calls() {
+		if !call.Streamed {
+			t.Errorf("advisor call not streamed: %q", call.User)
+		}
+	}
+
+	// 按目标拼接增量文本，应与最终摘要一致
+	streamed := map[string]string{}
+	summaries := map[string]string{}
+	for i, data := range events {
+		key := data["target_type"].(string)
+		switch stages[i] {
+		case progress.StageAdvisorDelta:
+			streamed[key] += data["delta"].(string)
+			if data["text"] != streamed[key] {
+				t.Errorf("text = %q, want accumulated %q", data["text"], streamed[key])
+			}
+		case progress.StageAdvisorSummary:
+			summaries[key] = data["summary"].(string)
+		}
+	}
+	want := map[string]string{"song": "好听的高难曲", "chart": "尾杀决定成绩"}
+	for target, summary := range want {
+		if streamed[target] != summary || summaries[target] != summary {
+			t.Errorf("%s: streamed = %q, summary = %q, want %q", target, streamed[target], summaries[target], summary)
+		}
+	}
+	if stages[len(stages)-1] != progress.StageAdvisorSummary {
+		t.Errorf("last stage = %s, want %s", stages[len(stages)-1], progress.StageAdvisorSummary)
+	}
+}
+
+func TestAnalyzeSongChartFailure(t *testing.T) {
+	f := newAnalyzerFixture(t)
+	// 谱面分析失败不影响歌曲总览
+	f.fakes[llm.RoleAnalyst] = llmtest.NewFake().
+		ReplyContaining("[Expert] DS: 13.2", songAnalystReply).
+		FailContaining("[Master] DS: 14.8", errors.New("provider unavailable"))
+	f.analyzer.analyst = f.fakes[llm.RoleAnalyst]
+
+	if err := f.analyzer.AnalyzeSong(context.Background(), f.song.ID); err != nil {
+		t.Fatalf("AnalyzeSong() error = %v", err)
+	}
+	if _, err := f.store.GetAnalysisResultsByTarget("song", f.song.ID); err != nil {
+		t.Errorf("歌曲分析结果不存在: %v", err)
+	}
+	if _, err := f.store.GetAnalysisResultsByTarget("chart", f.chart("Master").ID); err == nil {
+		t.Error("谱面分析失败时不应保存结果")
+	}
+}
+
+func TestAnalyzeSongInvalidAnalystOutput(t *testing.T) {
+	f := newAnalyzerFixture(t)
+	// 分析师输出不符合结构 (sentiment 不在枚举中) 时，所有分析块失败
+	f.analyzer.analyst = llmtest.NewFake().
+		ReplyContaining("谱面数据", `{"reasoning":"","difficulty_tags":[],"key_patterns":[],"pros":[],"cons":[],"sentiment":"Mixed","version_analysis":""}`)
+
+	err := f.analyzer.AnalyzeSong(context.Background(), f.song.ID)
+	if err == nil || !strings.Contains(err.Error(), "所有分析块均失败") {
+		t.Fatalf("AnalyzeSong() error = %v, want all chunks failed", err)
+	}
+	if n := len(f.fakes[llm.RoleAdvisor].Calls()); n != 0 {
+		t.Errorf("advisor calls = %d, want 0", n)
+	}
+}
+
+// tagValues 按保存顺序返回分析结果中指定类别的标签值
+func tagValues(result *model.AnalysisResult, kind string) []string {
+	var values []string
+	for _, tag := range result.Tags {
+		if tag.Kind == kind {
+			values = append(values, tag.Value)
+		}
+	}
+	return values
+}
+
+func TestMergeAnalystOutputsSentiment(t *testing.T) {
+	tests := []struct {
+		name       string
+		sentiments []string
+		want       string
+	}{
+		{name: "no outputs", want: "Neutral"},
+		{name: "single batch", sentiments: []string{"Negative"}, want: "Negative"},
+		{name: "majority wins over later batch", sentiments: []string{"Positive", "Positive", "Negative"}, want: "Positive"},
+		{name: "neutral majority", sentiments: []string{"Neutral", "Positive", "Neutral"}, want: "Neutral"},
+		{name: "positive negative tie", sentiments: []string{"Positive", "Negative"}, want: "Neutral"},
+		{name: "tie with neutral", sentiments: []string{"Negative", "Neutral", "Neutral", "Negative"}, want: "Neutral"},
+		{name: "plurality wins", sentiments: []string{"Negative", "Positive", "Negative", "Neutral"}, want: "Negative"},
+		{name: "empty sentiment ignored", sentiments: []string{"", "Positive"}, want: "Positive"},
+	}
+
+	a := &Analyzer{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputs := make([]*AnalystOutput, len(tt.sentiments))
+			for i, s := range tt.sentiments {
+				outputs[i] = &AnalystOutput{Sentiment: s, Pros: []string{"曲子好听"}}
+			}
+			merged := a.mergeAnalystOutputs(outputs)
+			if merged.Sentiment != tt.want {
+				t.Errorf("Sentiment = %q, want %q", merged.Sentiment, tt.want)
+			}
+			if len(outputs) > 0 && !reflect.DeepEqual(merged.Pros, []string{"曲子好听"}) {
+				t.Errorf("Pros = %v, want deduplicated", merged.Pros)
+			}
+		})
+	}
+}
@@ -3,7 +3,7 @@ package agent
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -19,11 +19,11 @@ type Cleaner struct {
 	htmlTagRegex    *regexp.Regexp
 	noiseKeywords   []string
 	validShortTerms []string
-	llm             *llm.Client
+	llm             llm.ChatClient
 	prompts         *config.PromptConfig
 }
 
-func NewCleaner(llmClient *llm.Client, prompts *config.PromptConfig) *Cleaner {
+func NewCleaner(llmClient llm.ChatClient, prompts *config.PromptConfig) *Cleaner {
 	return &Cleaner{
 		htmlTagRegex: regexp.MustCompile(`<[^>]*>`),
 		// 噪音关键词：与谱面分析无关的内容
@@ -112,26 +112,16 @@ func (c *Cleaner) CleanWithLLM(ctx context.Context, comments []string) ([]string
 	}
 
 	// 调用 LLM
-	// 注意：Cleaner 不需要推理过程，只需要结果
-	response, err := c.llm.Chat(ctx, c.prompts.Agent.Cleaner.System, promptBody.String())
-	if err != nil {
+	// 注意：Cleaner 不需要推理过程，只需要结果；同一批评论重复分析时复用缓存的清洗结果
+	var result CleanerOutput
+	if err := c.llm.ChatJSONCached(ctx, c.prompts.Agent.Cleaner.System, promptBody.String(), &result); err != nil {
+		if errors.Is(err, llm.ErrInvalidJSON) {
+			// 降级：输出修复后仍无法解析时不做语义清洗，返回原始列表
+			logger.Error("LLM 清洗结果解析失败，降级处理", "module", "agent.cleaner", "error", err, "count", len(comments))
+			return comments, nil
+		}
 		return nil, fmt.Errorf("LLM 清洗请求失败: %w", err)
 	}
 
-	// 解析 JSON
-	var validComments []string
-	// 尝试清理 Markdown 代码块标记 (```json ... ```)
-	response = strings.TrimPrefix(response, "```json")
-	response = strings.TrimPrefix(response, "```")
-	response = strings.TrimSuffix(response, "```")
-	response = strings.TrimSpace(response)
-
-	if err := json.Unmarshal([]byte(response), &validComments); err != nil {
-		logger.Error("LLM 清洗结果解析失败，降级处理", "module", "agent.cleaner", "error", err, "responseLength", len(response))
-		// 降级：如果解析失败，返回原始列表的前 50% (假设前排评论质量较高) 或者全部返回
-		// 这里为了安全起见，返回空列表让上层处理，或者返回原始列表
-		return comments, nil // 降级为不清洗
-	}
-
-	return validComments, nil
+	return result.Comments, nil
 }
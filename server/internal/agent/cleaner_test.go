@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+	"github.com/xumoe-c/maiecho/server/internal/llm/llmtest"
+)
+
+func newTestCleaner(t *testing.T, fake *llmtest.Fake) *Cleaner {
+	t.Helper()
+	t.Chdir("../../prompts")
+	prompts, err := config.LoadPrompts()
+	if err != nil {
+		t.Fatalf("LoadPrompts() error = %v", err)
+	}
+	return NewCleaner(fake, prompts)
+}
+
+func TestCleanWithLLM(t *testing.T) {
+	comments := []string{"尾杀太难了，体力完全跟不上", "前排", "中段交互要背谱"}
+	errUnavailable := errors.New("provider unavailable")
+
+	tests := []struct {
+		name    string
+		reply   string
+		err     error
+		want    []string
+		wantErr error
+	}{
+		{
+			name:  "keeps selected comments",
+			reply: `{"comments":["尾杀太难了，体力完全跟不上","中段交互要背谱"]}`,
+			want:  []string{"尾杀太难了，体力完全跟不上", "中段交互要背谱"},
+		},
+		{
+			name:  "code fence handled by the client",
+			reply: "```json\n" + `{"comments":["中段交互要背谱"]}` + "\n```",
+			want:  []string{"中段交互要背谱"},
+		},
+		{
+			name:  "no valid comments",
+			reply: `{"comments":[]}`,
+			want:  []string{},
+		},
+		{
+			name:  "invalid output falls back to all comments",
+			reply: `["尾杀太难了，体力完全跟不上"]`,
+			want:  comments,
+		},
+		{
+			name:    "request failure is returned",
+			err:     errUnavailable,
+			wantErr: errUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := llmtest.NewFake()
+			if tt.err != nil {
+				fake.FailContaining("待筛选评论列表", tt.err)
+			} else {
+				fake.ReplyContaining("待筛选评论列表", tt.reply)
+			}
+			c := newTestCleaner(t, fake)
+
+			got, err := c.CleanWithLLM(context.Background(), comments)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CleanWithLLM() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CleanWithLLM() = %q, want %q", got, tt.want)
+			}
+
+			calls := fake.Calls()
+			if len(calls) != 1 || !calls[0].Cached {
+				t.Fatalf("calls = %+v, want one cached call", calls)
+			}
+			if !strings.Contains(calls[0].User, "1. 尾杀太难了") || !strings.Contains(calls[0].User, "3. 中段交互要背谱") {
+				t.Errorf("user prompt = %q, want numbered comments", calls[0].User)
+			}
+			if forgot := fake.Forgotten(); len(forgot) != 0 {
+				t.Errorf("Forget() called for %q", forgot)
+			}
+		})
+	}
+}
+
+func TestCleanWithLLMEmpty(t *testing.T) {
+	fake := llmtest.NewFake()
+	c := newTestCleaner(t, fake)
+	got, err := c.CleanWithLLM(context.Background(), nil)
+	if err != nil || len(got) != 0 {
+		t.Errorf("CleanWithLLM(nil) = %q, %v, want empty", got, err)
+	}
+	if calls := fake.Calls(); len(calls) != 0 {
+		t.Errorf("calls = %d, want none", len(calls))
+	}
+}
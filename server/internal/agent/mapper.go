@@ -15,11 +15,11 @@ import (
 
 type Mapper struct {
 	storage storage.Storage
-	llm     *llm.Client
+	llm     llm.ChatClient
 	prompts *config.PromptConfig
 }
 
-func NewMapper(s storage.Storage, l *llm.Client, prompts *config.PromptConfig) *Mapper {
+func NewMapper(s storage.Storage, l llm.ChatClient, prompts *config.PromptConfig) *Mapper {
 	return &Mapper{
 		storage: s,
 		llm:     l,
@@ -132,7 +132,8 @@ func (m *Mapper) verifyMatchWithLLM(ctx context.Context, keyword string, comment
 		return false, fmt.Errorf("执行用户提示模板失败: %w", err)
 	}
 
-	resp, err := m.llm.Chat(ctx, systemPrompt, userPrompt)
+	// 相同的评论与关键词多次映射时复用缓存的判断结果
+	resp, err := m.llm.ChatCached(ctx, systemPrompt, userPrompt)
 	if err != nil {
 		return false, err
 	}
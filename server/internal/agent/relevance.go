@@ -2,7 +2,6 @@ package agent
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -12,11 +11,11 @@ import (
 )
 
 type RelevanceAnalyzer struct {
-	llm     *llm.Client
+	llm     llm.ChatClient
 	prompts *config.PromptConfig
 }
 
-func NewRelevanceAnalyzer(llm *llm.Client, prompts *config.PromptConfig) *RelevanceAnalyzer {
+func NewRelevanceAnalyzer(llm llm.ChatClient, prompts *config.PromptConfig) *RelevanceAnalyzer {
 	return &RelevanceAnalyzer{
 		llm:     llm,
 		prompts: prompts,
@@ -24,8 +23,8 @@ func NewRelevanceAnalyzer(llm *llm.Client, prompts *config.PromptConfig) *Releva
 }
 
 type AliasCheckResult struct {
-	IsSuitable bool   `json:"is_suitable"`
-	Reason     string `json:"reason"`
+	IsSuitable bool   `json:"is_suitable" desc:"是否适合作为搜索关键词"`
+	Reason     string `json:"reason" desc:"简短理由"`
 }
 
 func (a *RelevanceAnalyzer) CheckAliasSuitability(ctx context.Context, title, artist, alias string) (bool, error) {
@@ -39,19 +38,9 @@ func (a *RelevanceAnalyzer) CheckAliasSuitability(ctx context.Context, title, ar
 	userPrompt = strings.ReplaceAll(userPrompt, "{{.Artist}}", artist)
 	userPrompt = strings.ReplaceAll(userPrompt, "{{.Alias}}", alias)
 
-	resp, err := a.llm.Chat(ctx, prompt.System, userPrompt)
-	if err != nil {
-		return false, err
-	}
-
-	// 清理可能的 markdown 标记
-	resp = strings.TrimPrefix(resp, "```json")
-	resp = strings.TrimPrefix(resp, "```")
-	resp = strings.TrimSpace(resp)
-
 	var result AliasCheckResult
-	if err := json.Unmarshal([]byte(resp), &result); err != nil {
-		logger.Error("解析别名检查结果失败", "module", "agent.relevance", "response", resp, "error", err)
+	if err := a.llm.ChatJSONCached(ctx, prompt.System, userPrompt, &result); err != nil {
+		logger.Error("获取别名检查结果失败", "module", "agent.relevance", "alias", alias, "error", err)
 		return false, err
 	}
 
@@ -60,8 +49,8 @@ func (a *RelevanceAnalyzer) CheckAliasSuitability(ctx context.Context, title, ar
 }
 
 type TitleCheckResult struct {
-	IsRelevant bool    `json:"is_relevant"`
-	Confidence float64 `json:"confidence"`
+	IsRelevant bool    `json:"is_relevant" desc:"视频是否与该歌曲相关"`
+	Confidence float64 `json:"confidence" desc:"置信度，0.0-1.0"`
 }
 
 func (a *RelevanceAnalyzer) CheckTitleRelevance(ctx context.Context, title, artist string, aliases []string, videoTitle string) (bool, error) {
@@ -74,18 +63,9 @@ func (a *RelevanceAnalyzer) CheckTitleRelevance(ctx context.Context, title, arti
 	userPrompt = strings.ReplaceAll(userPrompt, "{{.Aliases}}", aliasesStr)
 	userPrompt = strings.ReplaceAll(userPrompt, "{{.VideoTitle}}", videoTitle)
 
-	resp, err := a.llm.Chat(ctx, prompt.System, userPrompt)
-	if err != nil {
-		return false, err
-	}
-
-	resp = strings.TrimPrefix(resp, "```json")
-	resp = strings.TrimPrefix(resp, "```")
-	resp = strings.TrimSpace(resp)
-
 	var result TitleCheckResult
-	if err := json.Unmarshal([]byte(resp), &result); err != nil {
-		logger.Error("解析标题相关性检查结果失败", "module", "agent.relevance", "response", resp, "error", err)
+	if err := a.llm.ChatJSONCached(ctx, prompt.System, userPrompt, &result); err != nil {
+		logger.Error("获取标题相关性检查结果失败", "module", "agent.relevance", "videoTitle", videoTitle, "error", err)
 		return false, err
 	}
 
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// fieldStreamer 从流式输出的 JSON 文本中增量提取一个字符串字段的值
+// 每解码出新的文本时调用 emit(delta, text)，text 为目前为止的完整值
+type fieldStreamer struct {
+	key  *regexp.Regexp
+	raw  strings.Builder
+	pos  int // 字段值中下一个待解码的位置，-1 表示尚未找到字段
+	done bool
+	text strings.Builder
+	emit func(delta, text string)
+}
+
+func newFieldStreamer(field string, emit func(delta, text string)) *fieldStreamer {
+	return &fieldStreamer{
+		key:  regexp.MustCompile(`"` + regexp.QuoteMeta(field) + `"\s*:\s*"`),
+		pos:  -1,
+		emit: emit,
+	}
+}
+
+// Write 追加模型输出的一个片段
+func (s *fieldStreamer) Write(chunk string) {
+	if s.done {
+		return
+	}
+	s.raw.WriteString(chunk)
+	raw := s.raw.String()
+	if s.pos < 0 {
+		loc := s.key.FindStringIndex(raw)
+		if loc == nil {
+			return
+		}
+		s.pos = loc[1]
+	}
+
+	var delta strings.Builder
+	for s.pos < len(raw) {
+		c := raw[s.pos]
+		if c == '"' {
+			s.done = true
+			break
+		}
+		if c != '\\' {
+			delta.WriteByte(c)
+			s.pos++
+			continue
+		}
+		r, n := decodeEscape(raw[s.pos:])
+		if n == 0 {
+			break // 转义序列不完整，等待下一个片段
+		}
+		delta.WriteRune(r)
+		s.pos += n
+	}
+	if delta.Len() > 0 {
+		s.text.WriteString(delta.String())
+		s.emit(delta.String(), s.text.String())
+	}
+}
+
+// decodeEscape 解码 s 开头的 JSON 转义序列，返回字符与消耗的字节数；序列不完整时返回 0
+func decodeEscape(s string) (rune, int) {
+	if len(s) < 2 {
+		return 0, 0
+	}
+	switch s[1] {
+	case 'n':
+		return '\n', 2
+	case 't':
+		return '\t', 2
+	case 'r':
+		return '\r', 2
+	case 'b':
+		return '\b', 2
+	case 'f':
+		return '\f', 2
+	case 'u':
+		r, ok := parseHex4(s)
+		if !ok {
+			return 0, 0
+		}
+		if !utf16.IsSurrogate(r) {
+			return r, 6
+		}
+		// 代理对由两个 \uXXXX 组成
+		if len(s) < 12 {
+			return 0, 0
+		}
+		low, ok := parseHex4(s[6:])
+		if !ok {
+			return utf16.DecodeRune(r, 0), 6
+		}
+		return utf16.DecodeRune(r, low), 12
+	default: // \" \\ \/
+		return rune(s[1]), 2
+	}
+}
+
+// parseHex4 解析 s 开头的 \uXXXX
+func parseHex4(s string) (rune, bool) {
+	if len(s) < 6 || s[0] != '\\' || s[1] != 'u' {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s[2:6], 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	return rune(n), true
+}
@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFieldStreamer(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string
+	}{
+		{"plain", []string{`{"summary":"好听的`, `高难曲","rating_advice":"x"}`}, "好听的高难曲"},
+		{"key split", []string{`{"summ`, `ary" : `, `"abc"}`}, "abc"},
+		{"escapes split", []string{`{"summary":"a\`, `"b\\c\n\u4e`, `2d\ud83d`, `\ude00"}`}, "a\"b\\c\n中😀"},
+		{"code fence", []string{"```json\n", `{"summary":"ok"}`, "\n```"}, "ok"},
+		{"stops at end", []string{`{"summary":"a","difficulty_analysis":"b"}`}, "a"},
+		{"missing", []string{`{"rating_advice":"x"}`}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deltas strings.Builder
+			var last string
+			s := newFieldStreamer("summary", func(delta, text string) {
+				deltas.WriteString(delta)
+				last = text
+			})
+			for _, c := range tt.chunks {
+				s.Write(c)
+			}
+			if deltas.String() != tt.want || last != tt.want {
+				t.Errorf("deltas = %q, text = %q, want %q", deltas.String(), last, tt.want)
+			}
+		})
+	}
+}
@@ -1,18 +1,75 @@
 package agent
 
+import "github.com/xumoe-c/maiecho/server/internal/model"
+
+// CleanerOutput 代表语义清洗后保留的评论
+type CleanerOutput struct {
+	Comments []string `json:"comments" desc:"筛选后保留的有效评论原文列表，没有有效评论时为空列表"`
+}
+
 // AnalystOutput 代表从评论中提取的客观事实
 type AnalystOutput struct {
-	DifficultyTags  []string `json:"difficulty_tags"` // 例如 "13+", "体力谱"
-	KeyPatterns     []string `json:"key_patterns"`    // 例如 "纵连", "流星雨"
-	Pros            []string `json:"pros"`
-	Cons            []string `json:"cons"`
-	Sentiment       string   `json:"sentiment"`        // "Positive", "Neutral", "Negative"
-	VersionAnalysis string   `json:"version_analysis"` // 针对不同版本/难度的特定分析
+	DifficultyTags  []string `json:"difficulty_tags" desc:"难度描述符列表"`     // 例如 "13+", "体力谱"
+	KeyPatterns     []string `json:"key_patterns" desc:"评论中提到的具体谱面配置列表"` // 例如 "纵连", "流星雨"
+	Pros            []string `json:"pros" desc:"提到的优点列表"`
+	Cons            []string `json:"cons" desc:"提到的缺点列表"`
+	Sentiment       string   `json:"sentiment" desc:"整体情感倾向" enum:"Positive,Neutral,Negative"`
+	VersionAnalysis string   `json:"version_analysis" desc:"针对不同版本/难度的特定分析，没有时为空字符串"` // 针对不同版本/难度的特定分析
+}
+
+// apply 将分析师提取的结构化事实写入分析结果
+func (o *AnalystOutput) apply(result *model.AnalysisResult) {
+	result.Sentiment = o.Sentiment
+	result.Tags = append(model.NewAnalysisTags(model.AnalysisTagDifficulty, o.DifficultyTags),
+		model.NewAnalysisTags(model.AnalysisTagPattern, o.KeyPatterns)...)
+	result.Pros = o.Pros
+	result.Cons = o.Cons
+}
+
+// analystResponse 分析师的结构化输出，先输出推理过程再输出结论
+type analystResponse struct {
+	Reasoning string `json:"reasoning" desc:"输出结论之前的深度思考过程"`
+	AnalystOutput
 }
 
 // AdvisorOutput 代表最终建议
 type AdvisorOutput struct {
-	Summary            string `json:"summary"`
-	RatingAdvice       string `json:"rating_advice"`
-	DifficultyAnalysis string `json:"difficulty_analysis"`
+	Summary            string `json:"summary" desc:"总体评价的简明摘要 (1-2句话)"`
+	RatingAdvice       string `json:"rating_advice" desc:"针对想要达成 SSS 或 AP 的玩家的具体建议"`
+	DifficultyAnalysis string `json:"difficulty_analysis" desc:"对谱面难度和配置的详细分析"`
+}
+
+// ChartAdvisorOutput 代表针对单个谱面的建议
+type ChartAdvisorOutput struct {
+	AdvisorOutput
+	KeySections      []ChartSectionOutput `json:"key_sections" desc:"谱面中的关键段落 (难点、杀点)，按出现顺序排列"`
+	RecommendedLevel LevelRangeOutput     `json:"recommended_level" desc:"推荐挑战该谱面的玩家水平，以玩家能稳定 SSS 的谱面等级表示"`
+	PracticeTips     []string             `json:"practice_tips" desc:"练习建议列表"`
+	Tricks           []string             `json:"tricks" desc:"拆法、手法等技巧列表，评论中没有提到时为空列表"`
+}
+
+// ChartSectionOutput 谱面中的一个关键段落
+type ChartSectionOutput struct {
+	Name        string `json:"name" desc:"段落名称，如 尾杀、中段交互"`
+	Description string `json:"description" desc:"该段落的配置与难点"`
+}
+
+// LevelRangeOutput 玩家水平区间
+type LevelRangeOutput struct {
+	Min string `json:"min" desc:"等级下限，如 13+"`
+	Max string `json:"max" desc:"等级上限，如 14"`
+}
+
+// report 转换为随谱面分析结果保存的结构化报告
+func (o *ChartAdvisorOutput) report() *model.ChartReport {
+	r := &model.ChartReport{
+		KeySections:      make([]model.ChartSection, 0, len(o.KeySections)),
+		RecommendedLevel: model.LevelRange{Min: o.RecommendedLevel.Min, Max: o.RecommendedLevel.Max},
+		PracticeTips:     o.PracticeTips,
+		Tricks:           o.Tricks,
+	}
+	for _, s := range o.KeySections {
+		r.KeySections = append(r.KeySections, model.ChartSection{Name: s.Name, Description: s.Description})
+	}
+	return r
 }
@@ -2,10 +2,12 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -13,19 +15,65 @@ import (
 	"github.com/gocolly/colly/v2"
 	"github.com/gocolly/colly/v2/extensions"
 	"github.com/tidwall/gjson"
+	"github.com/xumoe-c/maiecho/server/internal/config"
 	"github.com/xumoe-c/maiecho/server/internal/logger"
 	"github.com/xumoe-c/maiecho/server/internal/model"
 	"github.com/xumoe-c/maiecho/server/internal/storage"
 )
 
+// ErrBanned 采集器处于封禁冷却中
+var ErrBanned = errors.New("collector is currently banned/rate-limited")
+
+// defaultSearchPages 每个关键词默认采集的搜索结果页数
+const defaultSearchPages = 3
+
+// 评论分页的默认值
+const (
+	defaultReplyPages          = 5
+	defaultSubReplyPages       = 2
+	defaultReplyPageSize       = 20
+	defaultMaxCommentsPerVideo = 300
+)
+
 type BilibiliCollector struct {
-	storage  storage.Storage
-	c        *colly.Collector
-	isBanned int32
-	cookie   string
+	storage storage.Storage
+	c       *colly.Collector
+	breaker *Breaker
+	cookie  string
+
+	searchPages         int // 每个关键词采集的搜索结果页数
+	replyPages          int // 每个视频最多采集的顶层评论页数
+	subReplyPages       int // 每条顶层评论最多采集的楼中楼页数，0 表示不采集楼中楼
+	replyPageSize       int
+	maxCommentsPerVideo int // 每个视频最多保存的评论数 (含楼中楼)
+
+	sleep func(time.Duration) // 搜索请求之间的随机延迟，测试中替换为不等待的实现
+}
+
+// replyBudget 单个视频的评论配额，在该视频的所有评论请求之间共享
+type replyBudget struct {
+	remaining int32
+}
+
+// take 占用一条配额，配额耗尽时返回 false
+func (rb *replyBudget) take() bool {
+	return atomic.AddInt32(&rb.remaining, -1) >= 0
+}
+
+func (rb *replyBudget) exhausted() bool {
+	return atomic.LoadInt32(&rb.remaining) <= 0
 }
 
-func NewBilibiliCollector(s storage.Storage, cookie string, proxy string) *BilibiliCollector {
+func init() {
+	Register("bilibili", func(s storage.Storage, cfg *config.Config, opts config.CollectorConfig) (Collector, error) {
+		return NewBilibiliCollector(s, cfg.Bilibili, opts), nil
+	})
+}
+
+// NewBilibiliCollector 创建 Bilibili 采集器
+// 封禁冷却从 cfg.BanCooldown 开始，之后每次连续触发翻倍，最长不超过 cfg.MaxBanCooldown
+// opts 中的代理和 Cookie 优先于 cfg 中的同名配置
+func NewBilibiliCollector(s storage.Storage, cfg config.BilibiliConfig, opts config.CollectorConfig) *BilibiliCollector {
 	c := colly.NewCollector(
 		colly.Async(true), // 启用异步
 	)
@@ -35,28 +83,37 @@ func NewBilibiliCollector(s storage.Storage, cookie string, proxy string) *Bilib
 	// 使用随机 User-Agent
 	extensions.RandomUserAgent(c)
 
-	// 如果配置了代理，则设置代理
-	if proxy != "" {
-		if err := c.SetProxy(proxy); err != nil {
-			logger.Error("设置代理失败", "module", "collector.bilibili", "proxy", proxy, "error", err)
-		} else {
-			logger.Info("已启用代理", "module", "collector.bilibili", "proxy", proxy)
-		}
+	if opts.Proxy == "" {
+		opts.Proxy = cfg.Proxy
+	}
+	if opts.Cookie == "" {
+		opts.Cookie = cfg.Cookie
 	}
 
-	// 设置并发和延迟以避免被封禁
-	if err := c.Limit(&colly.LimitRule{
+	// 设置代理、并发和延迟以避免被封禁
+	applyOptions(c, "collector.bilibili", opts, colly.LimitRule{
 		DomainGlob:  "*bilibili.com*",
 		Parallelism: 2,
 		RandomDelay: 5 * time.Second, // Increase delay to be safer
-	}); err != nil {
-		logger.Error("设置采集限制失败", "module", "collector.bilibili", "error", err)
-	}
+	})
 
 	bc := &BilibiliCollector{
 		storage: s,
 		c:       c,
-		cookie:  cookie,
+		breaker: NewBreaker(cfg.BanCooldown, cfg.MaxBanCooldown),
+		cookie:  opts.Cookie,
+
+		searchPages:         positiveOr(opts.Pages, defaultSearchPages),
+		replyPages:          positiveOr(cfg.ReplyPages, defaultReplyPages),
+		subReplyPages:       defaultSubReplyPages,
+		replyPageSize:       positiveOr(cfg.ReplyPageSize, defaultReplyPageSize),
+		maxCommentsPerVideo: positiveOr(cfg.MaxCommentsPerVideo, defaultMaxCommentsPerVideo),
+		sleep:               time.Sleep,
+	}
+
+	// 显式配置为 0 时不采集楼中楼，与未设置区分
+	if cfg.SubReplyPages != nil {
+		bc.subReplyPages = max(*cfg.SubReplyPages, 0)
 	}
 
 	bc.setupCallbacks()
@@ -67,48 +124,107 @@ func (b *BilibiliCollector) Name() string {
 	return "bilibili"
 }
 
+// IsBanned 返回采集器是否处于封禁状态 (冷却中或等待探测)
+func (b *BilibiliCollector) IsBanned() bool {
+	return b.breaker.Snapshot().State != BreakerClosed
+}
+
+// BanState 返回封禁熔断器的状态快照
+func (b *BilibiliCollector) BanState() BreakerSnapshot {
+	return b.breaker.Snapshot()
+}
+
+// ResetBan 手动解除封禁状态
+func (b *BilibiliCollector) ResetBan() {
+	b.breaker.Reset()
+	logger.Info("封禁状态已手动重置", "module", "collector.bilibili")
+}
+
 func (b *BilibiliCollector) Collect(ctx context.Context, keyword string) error {
 	// 检查是否处于封禁状态
-	if atomic.LoadInt32(&b.isBanned) == 1 {
-		return fmt.Errorf("collector is currently banned/rate-limited")
+	allowed, probe := b.breaker.Allow()
+	if !allowed {
+		if until := b.breaker.Snapshot().BannedUntil; until != nil {
+			return fmt.Errorf("%w until %s", ErrBanned, until.Format(time.RFC3339))
+		}
+		return ErrBanned
 	}
 
-	logger.Info("开始采集", "module", "collector.bilibili", "collector", b.Name(), "keyword", keyword)
+	logger.Info("开始采集", "module", "collector.bilibili", "collector", b.Name(), "keyword", keyword, "probe", probe)
 
-	// 采集前 3 页
-	for page := 1; page <= 3; page++ {
+	startPage := 1
+	if probe {
+		// 半开状态：先只请求第一页作为探测，根据结果决定是否恢复
+		probeCtx := b.requestSearchPage(ctx, keyword, 1)
+		b.c.Wait()
+
+		switch b.breaker.Snapshot().State {
+		case BreakerClosed:
+			// 返回 -412 以外的 API 错误说明请求未被拦截：解除封禁，本次采集按普通失败处理
+			if apiErr := probeCtx.Get("api_error"); apiErr != "" {
+				logger.Warn("探测请求未被拦截但返回 API 错误，封禁已解除", "module", "collector.bilibili", "keyword", keyword, "error", apiErr)
+				return fmt.Errorf("probe search for %q failed: %s", keyword, apiErr)
+			}
+			logger.Info("探测请求成功，继续采集", "module", "collector.bilibili", "keyword", keyword)
+		case BreakerOpen:
+			logger.Warn("探测请求再次被拦截，继续封禁冷却", "module", "collector.bilibili", "keyword", keyword, "reason", b.breaker.Snapshot().LastReason)
+			return ErrBanned
+		default:
+			// 探测未得到有效响应 (如网络错误)，释放探测资格等待下次重试
+			logger.Warn("探测请求未得到有效响应，等待下次探测", "module", "collector.bilibili", "keyword", keyword)
+			b.breaker.EndProbe()
+			return fmt.Errorf("probe request for %q did not complete", keyword)
+		}
+		startPage = 2
+	}
+
+	for page := startPage; page <= b.searchPages; page++ {
 		// 再次检查封禁状态
-		if atomic.LoadInt32(&b.isBanned) == 1 {
+		if b.breaker.Blocked() {
 			logger.Warn("检测到封禁/错误，停止当前任务", "module", "collector.bilibili", "keyword", keyword)
 			break
 		}
+		b.requestSearchPage(ctx, keyword, page)
+	}
 
-		// API: https://api.bilibili.com/x/web-interface/search/all/v2?keyword=...&page=...
-		apiURL := fmt.Sprintf("https://api.bilibili.com/x/web-interface/search/all/v2?keyword=%s&page=%d", url.QueryEscape(keyword), page)
+	b.c.Wait()
+	if b.breaker.Blocked() {
+		return ErrBanned
+	}
+	return nil
+}
 
-		// Create colly context and pass SongID if available
-		collyCtx := colly.NewContext()
-		collyCtx.Put("keyword", keyword) // Store keyword in context
-		if songID, ok := ctx.Value("song_id").(uint); ok {
-			collyCtx.Put("song_id", songID)
-		}
+// requestSearchPage 请求一页搜索结果，返回该请求的 colly 上下文 (API 错误记录在 "api_error" 中)
+func (b *BilibiliCollector) requestSearchPage(ctx context.Context, keyword string, page int) *colly.Context {
+	// API: https://api.bilibili.com/x/web-interface/search/all/v2?keyword=...&page=...
+	apiURL := fmt.Sprintf("https://api.bilibili.com/x/web-interface/search/all/v2?keyword=%s&page=%d", url.QueryEscape(keyword), page)
 
-		// 随机延迟，模拟人类行为
-		time.Sleep(time.Duration(1000+time.Now().UnixNano()%2000) * time.Millisecond)
+	// Create colly context and pass SongID if available
+	collyCtx := colly.NewContext()
+	collyCtx.Put("keyword", keyword) // Store keyword in context
+	if songID, ok := SongIDFromContext(ctx); ok {
+		collyCtx.Put("song_id", songID)
+	}
 
-		if err := b.c.Request("GET", apiURL, nil, collyCtx, nil); err != nil {
-			logger.Error("访问页面失败", "module", "collector.bilibili", "page", page, "error", err)
-		}
+	// 随机延迟，模拟人类行为
+	b.sleep(time.Duration(1000+time.Now().UnixNano()%2000) * time.Millisecond)
+
+	if err := b.c.Request("GET", apiURL, nil, collyCtx, nil); err != nil {
+		logger.Error("访问页面失败", "module", "collector.bilibili", "page", page, "error", err)
 	}
+	return collyCtx
+}
 
-	b.c.Wait()
-	return nil
+// trip 触发封禁熔断
+func (b *BilibiliCollector) trip(reason string) {
+	cooldown := b.breaker.Trip(reason)
+	logger.Error("触发反爬虫机制，已进入封禁冷却，停止后续请求", "module", "collector.bilibili", "reason", reason, "cooldown", cooldown.String())
 }
 
 func (b *BilibiliCollector) setupCallbacks() {
 	b.c.OnRequest(func(r *colly.Request) {
-		// 如果已封禁，取消请求
-		if atomic.LoadInt32(&b.isBanned) == 1 {
+		// 如果处于封禁冷却中，取消请求
+		if b.breaker.Blocked() {
 			r.Abort()
 			return
 		}
@@ -137,9 +253,8 @@ func (b *BilibiliCollector) setupCallbacks() {
 		logger.Error("请求失败", "module", "collector.bilibili", "url", r.Request.URL, "status", r.StatusCode, "error", err)
 
 		// 检查是否为反爬虫错误 (412 Precondition Failed, 403 Forbidden)
-		if r.StatusCode == http.StatusPreconditionFailed || r.StatusCode == http.StatusForbidden || r.StatusCode == 412 {
-			atomic.StoreInt32(&b.isBanned, 1)
-			logger.Error("触发反爬虫机制，已标记为封禁状态，停止后续请求", "module", "collector.bilibili")
+		if r.StatusCode == http.StatusPreconditionFailed || r.StatusCode == http.StatusForbidden {
+			b.trip(fmt.Sprintf("HTTP %d", r.StatusCode))
 		}
 	})
 
@@ -148,8 +263,10 @@ func (b *BilibiliCollector) setupCallbacks() {
 		switch r.Request.URL.Path {
 		case "/x/web-interface/search/all/v2":
 			b.handleSearchResponse(r.Body, r.Ctx)
-		case "/x/v2/reply":
-			b.handleReplyResponse(r.Body, r.Request.URL.Query().Get("oid"), r.Ctx)
+		case "/x/v2/reply/main":
+			b.handleReplyResponse(r.Body, r.Ctx)
+		case "/x/v2/reply/reply":
+			b.handleSubReplyResponse(r.Body, r.Ctx)
 		}
 	})
 }
@@ -160,10 +277,24 @@ func (b *BilibiliCollector) handleSearchResponse(body []byte, ctx *colly.Context
 	// Check for API error code
 	code := gjson.Get(json, "code").Int()
 	if code != 0 {
-		logger.Error("Bilibili API returned error", "module", "collector.bilibili", "code", code, "message", gjson.Get(json, "message").String())
+		message := gjson.Get(json, "message").String()
+		logger.Error("Bilibili API returned error", "module", "collector.bilibili", "code", code, "message", message)
+		ctx.Put("api_error", fmt.Sprintf("API code %d: %s", code, message))
+		// -412: 请求被拦截
+		if code == -412 {
+			b.trip(fmt.Sprintf("API code %d", code))
+			return
+		}
+		// 其他 API 错误说明请求已到达接口而未被拦截，半开状态下同样解除封禁
+		b.breaker.Succeed()
 		return
 	}
 
+	// 搜索请求成功，半开状态下解除封禁
+	if b.breaker.Succeed() {
+		logger.Info("探测请求成功，封禁状态已解除", "module", "collector.bilibili")
+	}
+
 	// 获取关联的歌曲信息用于相关性检查
 	var song *model.Song
 	if songIDVal := ctx.GetAny("song_id"); songIDVal != nil {
@@ -199,7 +330,7 @@ func (b *BilibiliCollector) handleSearchResponse(body []byte, ctx *colly.Context
 				// 相关性检查
 				if song != nil {
 					cleanTitle := b.cleanHTML(title)
-					if !b.isRelevant(cleanTitle, song) {
+					if !isRelevantTitle(cleanTitle, song) {
 						logger.Info("跳过不相关视频", "module", "collector.bilibili", "title", cleanTitle, "song", song.Title)
 						return true // continue
 					}
@@ -207,6 +338,12 @@ func (b *BilibiliCollector) handleSearchResponse(body []byte, ctx *colly.Context
 
 				logger.Info("Found Video", "module", "collector.bilibili", "bvid", bvid, "title", title, "author", author)
 
+				// 发布时间缺失时退回到采集时间
+				pubdate := time.Now()
+				if ts := v.Get("pubdate").Int(); ts > 0 {
+					pubdate = time.Unix(ts, 0)
+				}
+
 				// 保存视频信息为评论
 				comment := &model.Comment{
 					Source:      "Bilibili",
@@ -214,8 +351,9 @@ func (b *BilibiliCollector) handleSearchResponse(body []byte, ctx *colly.Context
 					ExternalID:  bvid,
 					Content:     desc, // 视频描述
 					Author:      author,
-					PostDate:    time.Now(), // 占位符
+					PostDate:    pubdate,
 					SearchTag:   ctx.Get("keyword"),
+					Likes:       v.Get("like").Int(),
 				}
 
 				// Link to SongID if available in context
@@ -225,7 +363,7 @@ func (b *BilibiliCollector) handleSearchResponse(body []byte, ctx *colly.Context
 					}
 				}
 
-				if err := b.storage.CreateComment(comment); err != nil {
+				if err := b.storage.UpsertComment(comment); err != nil {
 					logger.Error("Failed to save video info", "module", "collector.bilibili", "error", err)
 				}
 
@@ -237,28 +375,28 @@ func (b *BilibiliCollector) handleSearchResponse(body []byte, ctx *colly.Context
 					Description: desc,
 					Author:      author,
 					URL:         fmt.Sprintf("https://www.bilibili.com/video/%s", bvid),
-					PublishTime: time.Now(), // 占位符
+					PublishTime: pubdate,
+					Views:       v.Get("play").Int(),
+					Likes:       v.Get("like").Int(),
+					Tags:        v.Get("tag").String(),
+					Duration:    parseDuration(v.Get("duration").String()),
 				}
 				if err := b.storage.CreateVideo(video); err != nil {
 					logger.Error("Failed to save video record", "module", "collector.bilibili", "error", err)
 				}
 
-				// 获取该视频的评论
-				// API: https://api.bilibili.com/x/v2/reply?type=1&oid={aid}&sort=1&ps=20
-				replyURL := fmt.Sprintf("https://api.bilibili.com/x/v2/reply?type=1&oid=%d&sort=1&ps=20", aid)
-
+				// 获取该视频的评论 (按热度分页，游标从 0 开始)
 				newCtx := colly.NewContext()
 				newCtx.Put("title", b.cleanHTML(title))
 				newCtx.Put("bvid", bvid)
+				newCtx.Put("oid", aid)
 				newCtx.Put("keyword", ctx.Get("keyword")) // Pass keyword to reply context
 				// Pass song_id to reply context as well
 				if songIDVal := ctx.GetAny("song_id"); songIDVal != nil {
 					newCtx.Put("song_id", songIDVal)
 				}
-
-				if err := b.c.Request("GET", replyURL, nil, newCtx, nil); err != nil {
-					logger.Error("请求评论失败", "module", "collector.bilibili", "url", replyURL, "error", err)
-				}
+				newCtx.Put("budget", &replyBudget{remaining: int32(b.maxCommentsPerVideo)})
+				b.requestReplyPage(newCtx, 0, 1)
 
 				return true
 			})
@@ -278,44 +416,159 @@ func (b *BilibiliCollector) cleanHTML(src string) string {
 	return re.ReplaceAllString(src, "")
 }
 
-func (b *BilibiliCollector) handleReplyResponse(body []byte, oid string, ctx *colly.Context) {
+// replyContext 复制视频级别的上下文 (标题、关键词、歌曲ID、配额等) 用于后续评论请求
+func (b *BilibiliCollector) replyContext(ctx *colly.Context) *colly.Context {
+	newCtx := colly.NewContext()
+	for _, key := range []string{"title", "bvid", "oid", "keyword", "song_id", "budget"} {
+		if v := ctx.GetAny(key); v != nil {
+			newCtx.Put(key, v)
+		}
+	}
+	return newCtx
+}
+
+// requestReplyPage 请求一页顶层评论
+// API: https://api.bilibili.com/x/v2/reply/main?type=1&oid={aid}&mode=3&next={cursor}&ps={ps}
+func (b *BilibiliCollector) requestReplyPage(ctx *colly.Context, cursor int64, page int) {
+	ctx.Put("page", page)
+	replyURL := fmt.Sprintf("https://api.bilibili.com/x/v2/reply/main?type=1&oid=%v&mode=3&next=%d&ps=%d", ctx.GetAny("oid"), cursor, b.replyPageSize)
+	if err := b.c.Request("GET", replyURL, nil, ctx, nil); err != nil {
+		logger.Error("请求评论失败", "module", "collector.bilibili", "url", replyURL, "error", err)
+	}
+}
+
+// requestSubReplyPage 请求一页楼中楼回复
+// API: https://api.bilibili.com/x/v2/reply/reply?type=1&oid={aid}&root={rpid}&pn={pn}&ps={ps}
+func (b *BilibiliCollector) requestSubReplyPage(ctx *colly.Context, root string, page int) {
+	ctx.Put("root", root)
+	ctx.Put("page", page)
+	replyURL := fmt.Sprintf("https://api.bilibili.com/x/v2/reply/reply?type=1&oid=%v&root=%s&pn=%d&ps=%d", ctx.GetAny("oid"), root, page, b.replyPageSize)
+	if err := b.c.Request("GET", replyURL, nil, ctx, nil); err != nil {
+		logger.Error("请求楼中楼回复失败", "module", "collector.bilibili", "url", replyURL, "error", err)
+	}
+}
+
+func (b *BilibiliCollector) handleReplyResponse(body []byte, ctx *colly.Context) {
 	json := string(body)
-	title := ctx.Get("title")
+	if code := gjson.Get(json, "code").Int(); code != 0 {
+		logger.Error("获取评论失败", "module", "collector.bilibili", "bvid", ctx.Get("bvid"), "code", code, "message", gjson.Get(json, "message").String())
+		return
+	}
+
+	page, _ := ctx.GetAny("page").(int)
+	budget, _ := ctx.GetAny("budget").(*replyBudget)
 
 	// 解析评论列表
 	// 路径: data.replies
-	replies := gjson.Get(json, "data.replies")
-	replies.ForEach(func(key, value gjson.Result) bool {
-		rpid := value.Get("rpid").String()
-		content := value.Get("content.message").String()
-		author := value.Get("member.uname").String()
-		ctime := value.Get("ctime").Int()
-
-		comment := &model.Comment{
-			Source:      "Bilibili",
-			SourceTitle: title,
-			ExternalID:  rpid,
-			Content:     content,
-			Author:      author,
-			PostDate:    time.Unix(ctime, 0),
-			SearchTag:   ctx.Get("keyword"),
+	gjson.Get(json, "data.replies").ForEach(func(key, value gjson.Result) bool {
+		if !b.saveReply(value, "", ctx, budget) {
+			return false
 		}
 
-		// Link to SongID if available in context
-		if songIDVal := ctx.GetAny("song_id"); songIDVal != nil {
-			if songID, ok := songIDVal.(uint); ok {
-				comment.SongID = &songID
+		// 楼中楼：预览中未包含全部回复时，分页请求完整回复
+		rcount := value.Get("rcount").Int()
+		if rcount > 0 && b.subReplyPages > 0 {
+			if int64(len(value.Get("replies").Array())) >= rcount {
+				value.Get("replies").ForEach(func(k, sub gjson.Result) bool {
+					return b.saveReply(sub, value.Get("rpid").String(), ctx, budget)
+				})
+			} else if budget == nil || !budget.exhausted() {
+				b.requestSubReplyPage(b.replyContext(ctx), value.Get("rpid").String(), 1)
 			}
 		}
-
-		if err := b.storage.CreateComment(comment); err != nil {
-			logger.Error("保存评论失败", "module", "collector.bilibili", "rpid", rpid, "error", err)
-		}
 		return true
 	})
+
+	// 下一页
+	cursor := gjson.Get(json, "data.cursor")
+	if cursor.Get("is_end").Bool() || page >= b.replyPages || (budget != nil && budget.exhausted()) {
+		return
+	}
+	b.requestReplyPage(b.replyContext(ctx), cursor.Get("next").Int(), page+1)
+}
+
+func (b *BilibiliCollector) handleSubReplyResponse(body []byte, ctx *colly.Context) {
+	json := string(body)
+	if code := gjson.Get(json, "code").Int(); code != 0 {
+		logger.Error("获取楼中楼回复失败", "module", "collector.bilibili", "bvid", ctx.Get("bvid"), "root", ctx.Get("root"), "code", code, "message", gjson.Get(json, "message").String())
+		return
+	}
+
+	root := ctx.Get("root")
+	page, _ := ctx.GetAny("page").(int)
+	budget, _ := ctx.GetAny("budget").(*replyBudget)
+
+	replies := gjson.Get(json, "data.replies")
+	replies.ForEach(func(key, value gjson.Result) bool {
+		return b.saveReply(value, root, ctx, budget)
+	})
+
+	// 下一页
+	total := gjson.Get(json, "data.page.count").Int()
+	if len(replies.Array()) == 0 || int64(page*b.replyPageSize) >= total || page >= b.subReplyPages || (budget != nil && budget.exhausted()) {
+		return
+	}
+	next := b.replyContext(ctx)
+	b.requestSubReplyPage(next, root, page+1)
+}
+
+// saveReply 保存一条评论，配额耗尽时返回 false
+func (b *BilibiliCollector) saveReply(value gjson.Result, parentID string, ctx *colly.Context, budget *replyBudget) bool {
+	if budget != nil && !budget.take() {
+		return false
+	}
+
+	rpid := value.Get("rpid").String()
+	comment := &model.Comment{
+		Source:           "Bilibili",
+		SourceTitle:      ctx.Get("title"),
+		ExternalID:       rpid,
+		ParentExternalID: parentID,
+		Content:          value.Get("content.message").String(),
+		Author:           value.Get("member.uname").String(),
+		PostDate:         time.Unix(value.Get("ctime").Int(), 0),
+		SearchTag:        ctx.Get("keyword"),
+		Likes:            value.Get("like").Int(),
+	}
+
+	// Link to SongID if available in context
+	if songIDVal := ctx.GetAny("song_id"); songIDVal != nil {
+		if songID, ok := songIDVal.(uint); ok {
+			comment.SongID = &songID
+		}
+	}
+
+	if err := b.storage.UpsertComment(comment); err != nil {
+		logger.Error("保存评论失败", "module", "collector.bilibili", "rpid", rpid, "error", err)
+	}
+	return true
+}
+
+// parseDuration 解析搜索接口返回的时长 ("4:05" 或 "1:02:03")，返回秒数
+func parseDuration(src string) int {
+	if src == "" {
+		return 0
+	}
+	total := 0
+	for _, part := range strings.Split(src, ":") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return 0
+		}
+		total = total*60 + n
+	}
+	return total
+}
+
+func positiveOr(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
 }
 
-func (b *BilibiliCollector) isRelevant(videoTitle string, song *model.Song) bool {
+// isRelevantTitle 判断视频/帖子标题是否与歌曲相关 (包含歌曲名或有效别名)
+func isRelevantTitle(videoTitle string, song *model.Song) bool {
 	videoTitle = strings.ToLower(videoTitle)
 	songTitle := strings.ToLower(song.Title)
 
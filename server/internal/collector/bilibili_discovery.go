@@ -8,6 +8,7 @@ import (
 
 	"github.com/gocolly/colly/v2"
 	"github.com/tidwall/gjson"
+	"github.com/xumoe-c/maiecho/server/internal/config"
 	"github.com/xumoe-c/maiecho/server/internal/logger"
 	"github.com/xumoe-c/maiecho/server/internal/model"
 	"github.com/xumoe-c/maiecho/server/internal/storage"
@@ -19,7 +20,13 @@ type BilibiliDiscoveryCollector struct {
 	c       *colly.Collector
 }
 
-func NewBilibiliDiscoveryCollector(s storage.Storage) *BilibiliDiscoveryCollector {
+func init() {
+	Register("bilibili_discovery", func(s storage.Storage, cfg *config.Config, opts config.CollectorConfig) (Collector, error) {
+		return NewBilibiliDiscoveryCollector(s, opts), nil
+	})
+}
+
+func NewBilibiliDiscoveryCollector(s storage.Storage, opts config.CollectorConfig) *BilibiliDiscoveryCollector {
 	c := colly.NewCollector(
 		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"),
 		colly.Async(true),
@@ -27,13 +34,11 @@ func NewBilibiliDiscoveryCollector(s storage.Storage) *BilibiliDiscoveryCollecto
 	// 允许重复访问相同的 URL (因为我们需要定期扫描同一个搜索页面)
 	c.AllowURLRevisit = true
 
-	if err := c.Limit(&colly.LimitRule{
+	applyOptions(c, "collector.bilibili_discovery", opts, colly.LimitRule{
 		DomainGlob:  "*bilibili.com*",
 		Parallelism: 1,
 		RandomDelay: 5 * time.Second,
-	}); err != nil {
-		logger.Error("设置发现采集限制失败", "module", "collector.bilibili_discovery", "error", err)
-	}
+	})
 
 	bdc := &BilibiliDiscoveryCollector{
 		storage: s,
@@ -47,6 +52,11 @@ func (b *BilibiliDiscoveryCollector) Name() string {
 	return "bilibili_discovery"
 }
 
+// IsDiscovery 发现采集器只由定期发现任务调用，不参与关键词采集任务
+func (b *BilibiliDiscoveryCollector) IsDiscovery() bool {
+	return true
+}
+
 func (b *BilibiliDiscoveryCollector) Collect(ctx context.Context, tag string) error {
 	logger.Info("扫描标签", "module", "collector.bilibili_discovery", "collector", b.Name(), "tag", tag)
 
@@ -75,7 +85,11 @@ func (b *BilibiliDiscoveryCollector) handleDiscoveryResponse(body []byte) {
 			videos.ForEach(func(k, v gjson.Result) bool {
 				bvid := v.Get("bvid").String()
 				title := v.Get("title").String()
-				// pubDate := v.Get("pubdate").Int()
+				// 使用视频的发布时间，重复发现同一视频时不会改变其日期
+				pubdate := time.Now()
+				if ts := v.Get("pubdate").Int(); ts > 0 {
+					pubdate = time.Unix(ts, 0)
+				}
 
 				logger.Info("发现新视频", "module", "collector.bilibili_discovery", "bvid", bvid, "title", title)
 
@@ -84,10 +98,10 @@ func (b *BilibiliDiscoveryCollector) handleDiscoveryResponse(body []byte) {
 					ExternalID: bvid,
 					Content:    v.Get("description").String(),
 					Author:     v.Get("author").String(),
-					PostDate:   time.Now(), // 使用当前时间作为发现时间
+					PostDate:   pubdate,
 				}
 
-				if err := b.storage.CreateComment(comment); err != nil {
+				if err := b.storage.UpsertComment(comment); err != nil {
 					// 忽略重复错误
 					logger.Error("保存视频评论失败", "module", "collector.bilibili_discovery", "bvid", bvid, "error", err)
 				}
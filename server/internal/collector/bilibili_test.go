@@ -0,0 +1,303 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/xumoe-c/maiecho/server/internal/config"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+)
+
+// bilibiliTransport 根据接口与分页参数返回 testdata 中的响应，并记录请求
+type bilibiliTransport struct {
+	t        *testing.T
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *bilibiliTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	q := req.URL.Query()
+	var key, name string
+	switch req.URL.Path {
+	case "/x/v2/reply/main":
+		key = "main:" + q.Get("next")
+		name = map[string]string{"0": "bilibili_reply_main_1.json", "2": "bilibili_reply_main_2.json"}[q.Get("next")]
+	case "/x/v2/reply/reply":
+		key = "sub:" + q.Get("root") + ":" + q.Get("pn")
+		if q.Get("root") == "1002" {
+			name = "bilibili_reply_sub_" + q.Get("pn") + ".json"
+		}
+	}
+	f.mu.Lock()
+	f.requests = append(f.requests, key)
+	f.mu.Unlock()
+
+	status, body := http.StatusNotFound, []byte(`{"code":-404}`)
+	if name != "" {
+		status, body = http.StatusOK, readFixture(f.t, name)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// Requests 返回排序后的请求记录
+func (f *bilibiliTransport) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := append([]string(nil), f.requests...)
+	sort.Strings(requests)
+	return requests
+}
+
+// fakeVideoStorage 在评论之外记录保存的视频
+type fakeVideoStorage struct {
+	*fakeCommentStorage
+	videos []model.Video
+}
+
+func (f *fakeVideoStorage) CreateVideo(v *model.Video) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.videos = append(f.videos, *v)
+	return nil
+}
+
+func intPtr(v int) *int { return &v }
+
+// collectBilibiliFixture 以 testdata 中的搜索结果为起点采集评论，返回保存的评论与发出的评论请求
+func collectBilibiliFixture(t *testing.T, cfg config.BilibiliConfig) (*fakeVideoStorage, []string) {
+	t.Helper()
+	store := &fakeVideoStorage{fakeCommentStorage: &fakeCommentStorage{
+		song:     &model.Song{Title: "PANDORA PARADOXXX"},
+		comments: make(map[string]model.Comment),
+	}}
+	bc := NewBilibiliCollector(store, cfg, config.CollectorConfig{Name: "bilibili"})
+
+	// 使用无延迟的采集器并从 testdata 返回响应
+	transport := &bilibiliTransport{t: t}
+	bc.c = colly.NewCollector(colly.Async(true))
+	bc.c.AllowURLRevisit = true
+	bc.c.WithTransport(transport)
+	bc.setupCallbacks()
+
+	ctx := colly.NewContext()
+	ctx.Put("keyword", "PANDORA PARADOXXX")
+	ctx.Put("song_id", uint(42))
+	bc.handleSearchResponse(readFixture(t, "bilibili_search.json"), ctx)
+	bc.c.Wait()
+
+	return store, transport.Requests()
+}
+
+// savedReplies 返回保存的评论ID (不含视频)，按ID排序
+func savedReplies(store *fakeVideoStorage) []string {
+	var ids []string
+	for _, c := range store.comments {
+		if c.ExternalID != "BV1pandora" {
+			ids = append(ids, c.ExternalID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestBilibiliCollectReplies(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          config.BilibiliConfig
+		wantRequests []string
+		wantReplies  []string
+	}{
+		{
+			name:         "cursor and sub reply pagination",
+			cfg:          config.BilibiliConfig{ReplyPageSize: 2},
+			wantRequests: []string{"main:0", "main:2", "sub:1002:1", "sub:1002:2"},
+			// 预览已包含全部回复的 1001 不再请求楼中楼；1002 受 sub_reply_pages 默认值限制只采集 2 页
+			wantReplies: []string{"1001", "1002", "1003", "1101", "2001", "2002", "2003", "2004"},
+		},
+		{
+			name:         "sub replies until the last page",
+			cfg:          config.BilibiliConfig{ReplyPageSize: 2, SubReplyPages: intPtr(5)},
+			wantRequests: []string{"main:0", "main:2", "sub:1002:1", "sub:1002:2", "sub:1002:3"},
+			wantReplies:  []string{"1001", "1002", "1003", "1101", "2001", "2002", "2003", "2004", "2005"},
+		},
+		{
+			name:         "sub reply pages zero disables sub replies",
+			cfg:          config.BilibiliConfig{ReplyPageSize: 2, SubReplyPages: intPtr(0)},
+			wantRequests: []string{"main:0", "main:2"},
+			wantReplies:  []string{"1001", "1002", "1003"},
+		},
+		{
+			name:         "reply pages limit",
+			cfg:          config.BilibiliConfig{ReplyPageSize: 2, ReplyPages: 1, SubReplyPages: intPtr(0)},
+			wantRequests: []string{"main:0"},
+			wantReplies:  []string{"1001", "1002"},
+		},
+		{
+			name:         "max comments per video",
+			cfg:          config.BilibiliConfig{ReplyPageSize: 2, MaxCommentsPerVideo: 3},
+			wantRequests: []string{"main:0"},
+			// 配额在第二条顶层评论后耗尽，不再请求楼中楼和下一页
+			wantReplies: []string{"1001", "1002", "1101"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, requests := collectBilibiliFixture(t, tt.cfg)
+			if !slices.Equal(requests, tt.wantRequests) {
+				t.Errorf("requests = %v, want %v", requests, tt.wantRequests)
+			}
+			if got := savedReplies(store); !slices.Equal(got, tt.wantReplies) {
+				t.Errorf("saved replies = %v, want %v", got, tt.wantReplies)
+			}
+		})
+	}
+}
+
+func TestBilibiliCollectFields(t *testing.T) {
+	store, _ := collectBilibiliFixture(t, config.BilibiliConfig{ReplyPageSize: 2})
+
+	// 不相关的视频被跳过
+	if len(store.videos) != 1 || store.videos[0].ExternalID != "BV1pandora" || store.videos[0].Duration != 245 {
+		t.Fatalf("videos = %+v, want only BV1pandora (245s)", store.videos)
+	}
+	video, ok := store.comments["Bilibili/BV1pandora"]
+	if !ok || video.SourceTitle != "【舞萌DX】PANDORA PARADOXXX 紫谱 AP 手元" || video.Likes != 340 {
+		t.Errorf("video comment = %+v", video)
+	}
+
+	sub := store.comments["Bilibili/2003"]
+	if sub.ParentExternalID != "1002" || sub.Content != "低速练" || sub.Author != "玩家G" || sub.SourceTitle != video.SourceTitle {
+		t.Errorf("sub reply = %+v, want parent 1002 with the video title", sub)
+	}
+	if preview := store.comments["Bilibili/1101"]; preview.ParentExternalID != "1001" {
+		t.Errorf("preview reply parent = %q, want 1001", preview.ParentExternalID)
+	}
+	top := store.comments["Bilibili/1002"]
+	if top.ParentExternalID != "" || top.Likes != 30 || top.PostDate.Unix() != 1709300200 {
+		t.Errorf("top reply = %+v", top)
+	}
+	for key, c := range store.comments {
+		if c.SongID == nil || *c.SongID != 42 || c.SearchTag != "PANDORA PARADOXXX" {
+			t.Errorf("%s: SongID = %v, SearchTag = %q", key, c.SongID, c.SearchTag)
+		}
+	}
+}
+
+func TestBilibiliDiscoveryPostDate(t *testing.T) {
+	store := &fakeCommentStorage{comments: make(map[string]model.Comment)}
+	bdc := NewBilibiliDiscoveryCollector(store, config.CollectorConfig{Name: "bilibili_discovery"})
+
+	// 重复发现同一视频时保持视频的发布时间
+	for i := 0; i < 2; i++ {
+		bdc.handleDiscoveryResponse(readFixture(t, "bilibili_search.json"))
+	}
+
+	got, ok := store.comments["Bilibili_Discovery/BV1pandora"]
+	if !ok {
+		t.Fatalf("comments = %v, want BV1pandora", store.comments)
+	}
+	if got.PostDate.Unix() != 1709295300 {
+		t.Errorf("PostDate = %v, want pubdate 1709295300", got.PostDate)
+	}
+}
+
+// staticTransport 对所有请求返回同一响应
+type staticTransport struct {
+	status int
+	body   string
+}
+
+func (f *staticTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: f.status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte(f.body))),
+		Request:    req,
+	}, nil
+}
+
+func TestBilibiliProbe(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantErr   bool
+		wantBan   bool
+		wantState string
+	}{
+		{
+			name:      "success recovers",
+			status:    http.StatusOK,
+			body:      `{"code":0,"data":{"result":[]}}`,
+			wantState: BreakerClosed,
+		},
+		{
+			name:      "code -412 re-opens",
+			status:    http.StatusOK,
+			body:      `{"code":-412,"message":"请求被拦截"}`,
+			wantErr:   true,
+			wantBan:   true,
+			wantState: BreakerOpen,
+		},
+		{
+			name:      "other API error recovers and fails the task",
+			status:    http.StatusOK,
+			body:      `{"code":-400,"message":"请求错误"}`,
+			wantErr:   true,
+			wantState: BreakerClosed,
+		},
+		{
+			name:      "no response releases the probe",
+			status:    http.StatusBadGateway,
+			body:      ``,
+			wantErr:   true,
+			wantState: BreakerHalfOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeCommentStorage{comments: make(map[string]model.Comment)}
+			bc := NewBilibiliCollector(store, config.BilibiliConfig{}, config.CollectorConfig{Name: "bilibili", Pages: 1})
+			bc.c = colly.NewCollector(colly.Async(true))
+			bc.c.WithTransport(&staticTransport{status: tt.status, body: tt.body})
+			bc.setupCallbacks()
+			bc.sleep = func(time.Duration) {}
+
+			// 冷却结束后的第一次采集作为探测
+			breaker, clock := newTestBreaker(time.Minute, 5*time.Minute)
+			bc.breaker = breaker
+			breaker.Trip("HTTP 412")
+			clock.Advance(time.Minute)
+
+			err := bc.Collect(context.Background(), "PANDORA PARADOXXX")
+			if (err != nil) != tt.wantErr || errors.Is(err, ErrBanned) != tt.wantBan {
+				t.Errorf("Collect() error = %v, want error %v (banned %v)", err, tt.wantErr, tt.wantBan)
+			}
+			if got := breaker.Snapshot().State; got != tt.wantState {
+				t.Errorf("state = %s, want %s", got, tt.wantState)
+			}
+			// 未得出结论的探测释放探测资格
+			if tt.wantState == BreakerHalfOpen {
+				if allowed, probe := breaker.Allow(); !allowed || !probe {
+					t.Errorf("Allow() = %v, %v, want a new probe", allowed, probe)
+				}
+			}
+		})
+	}
+}
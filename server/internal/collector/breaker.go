@@ -0,0 +1,166 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"    // 正常采集
+	BreakerOpen     = "open"      // 封禁冷却中，拒绝所有请求
+	BreakerHalfOpen = "half_open" // 冷却结束，允许一次探测请求
+)
+
+// BreakerSnapshot 熔断器状态快照
+type BreakerSnapshot struct {
+	State       string     `json:"state"`
+	Trips       int        `json:"trips"`                  // 连续触发封禁的次数
+	LastReason  string     `json:"last_reason,omitempty"`  // 最近一次触发封禁的原因
+	BannedAt    *time.Time `json:"banned_at,omitempty"`    // 最近一次触发封禁的时间
+	BannedUntil *time.Time `json:"banned_until,omitempty"` // 冷却结束时间
+}
+
+// Breaker 采集器封禁熔断器
+// 触发封禁后进入冷却 (open)，冷却时长按连续触发次数指数增长；
+// 冷却结束后进入半开状态 (half_open)，只放行一次探测请求，探测成功则恢复，失败则再次冷却。
+type Breaker struct {
+	mu           sync.Mutex
+	baseCooldown time.Duration
+	maxCooldown  time.Duration
+
+	state       string
+	trips       int
+	lastReason  string
+	bannedAt    time.Time
+	bannedUntil time.Time
+	probing     bool             // 半开状态下是否已有探测请求在进行
+	now         func() time.Time // 当前时间，测试中可替换
+}
+
+func NewBreaker(baseCooldown, maxCooldown time.Duration) *Breaker {
+	if baseCooldown <= 0 {
+		baseCooldown = 5 * time.Minute
+	}
+	if maxCooldown < baseCooldown {
+		maxCooldown = baseCooldown
+	}
+	return &Breaker{
+		baseCooldown: baseCooldown,
+		maxCooldown:  maxCooldown,
+		state:        BreakerClosed,
+		now:          time.Now,
+	}
+}
+
+// Allow 判断是否允许开始一次采集。冷却结束后的第一次调用会获得探测资格 (probe 为 true)
+func (b *Breaker) Allow() (allowed bool, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		return true, false
+	case BreakerOpen:
+		if b.now().Before(b.bannedUntil) {
+			return false, false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true, true
+	default: // half_open
+		if b.probing {
+			return false, false
+		}
+		b.probing = true
+		return true, true
+	}
+}
+
+// Blocked 判断当前是否应拒绝发出请求 (仅冷却中拒绝，半开状态放行探测请求)
+func (b *Breaker) Blocked() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == BreakerOpen
+}
+
+// Trip 记录一次封禁，进入冷却状态
+func (b *Breaker) Trip(reason string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		// 同一轮封禁中后续失败的并发请求不重复计数
+		return b.bannedUntil.Sub(b.now())
+	}
+
+	cooldown := b.baseCooldown
+	for i := 0; i < b.trips && cooldown < b.maxCooldown; i++ {
+		cooldown *= 2
+	}
+	if cooldown > b.maxCooldown {
+		cooldown = b.maxCooldown
+	}
+
+	b.trips++
+	b.state = BreakerOpen
+	b.probing = false
+	b.lastReason = reason
+	b.bannedAt = b.now()
+	b.bannedUntil = b.bannedAt.Add(cooldown)
+	return cooldown
+}
+
+// Succeed 记录一次成功请求，半开状态下将恢复为正常状态
+func (b *Breaker) Succeed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BreakerHalfOpen {
+		return false
+	}
+	b.state = BreakerClosed
+	b.trips = 0
+	b.probing = false
+	return true
+}
+
+// EndProbe 结束探测但未得出结论 (例如探测请求未实际发出)，释放探测资格
+func (b *Breaker) EndProbe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+	}
+}
+
+// Reset 手动重置为正常状态
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.trips = 0
+	b.probing = false
+	b.bannedUntil = time.Time{}
+}
+
+// Snapshot 返回当前状态快照
+func (b *Breaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snap := BreakerSnapshot{
+		State:      b.state,
+		Trips:      b.trips,
+		LastReason: b.lastReason,
+	}
+	if !b.bannedAt.IsZero() {
+		bannedAt := b.bannedAt
+		snap.BannedAt = &bannedAt
+	}
+	if b.state != BreakerClosed {
+		until := b.bannedUntil
+		snap.BannedUntil = &until
+	}
+	return snap
+}
@@ -0,0 +1,225 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestBreaker(base, limit time.Duration) (*Breaker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	b := NewBreaker(base, limit)
+	b.now = clock.Now
+	return b, clock
+}
+
+// breakerStep 熔断器上的一次操作及其期望结果
+type breakerStep struct {
+	op           string        // allow, trip, succeed, end_probe, reset, advance
+	advance      time.Duration // op 为 advance 时推进的时长
+	wantAllowed  bool          // op 为 allow 时的期望结果
+	wantProbe    bool          // op 为 allow 时的期望结果
+	wantCooldown time.Duration // op 为 trip 时的期望冷却时长
+	wantRecover  bool          // op 为 succeed 时的期望结果
+	wantState    string        // 操作后的状态
+}
+
+func TestBreaker(t *testing.T) {
+	const base, limit = time.Minute, 5 * time.Minute
+
+	tests := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{
+			name: "closed allows without probe",
+			steps: []breakerStep{
+				{op: "allow", wantAllowed: true, wantState: BreakerClosed},
+				{op: "succeed", wantRecover: false, wantState: BreakerClosed},
+			},
+		},
+		{
+			name: "trip blocks until cooldown ends",
+			steps: []breakerStep{
+				{op: "trip", wantCooldown: base, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: false, wantState: BreakerOpen},
+				{op: "advance", advance: base - time.Second, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: false, wantState: BreakerOpen},
+				{op: "advance", advance: time.Second, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: true, wantProbe: true, wantState: BreakerHalfOpen},
+			},
+		},
+		{
+			name: "single probe in half open",
+			steps: []breakerStep{
+				{op: "trip", wantCooldown: base, wantState: BreakerOpen},
+				{op: "advance", advance: base, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: true, wantProbe: true, wantState: BreakerHalfOpen},
+				{op: "allow", wantAllowed: false, wantState: BreakerHalfOpen},
+				{op: "allow", wantAllowed: false, wantState: BreakerHalfOpen},
+			},
+		},
+		{
+			name: "probe success recovers and resets cooldown",
+			steps: []breakerStep{
+				{op: "trip", wantCooldown: base, wantState: BreakerOpen},
+				{op: "advance", advance: base, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: true, wantProbe: true, wantState: BreakerHalfOpen},
+				{op: "succeed", wantRecover: true, wantState: BreakerClosed},
+				{op: "allow", wantAllowed: true, wantState: BreakerClosed},
+				{op: "trip", wantCooldown: base, wantState: BreakerOpen},
+			},
+		},
+		{
+			name: "probe failure doubles cooldown up to cap",
+			steps: []breakerStep{
+				{op: "trip", wantCooldown: base, wantState: BreakerOpen},
+				{op: "advance", advance: base, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: true, wantProbe: true, wantState: BreakerHalfOpen},
+				{op: "trip", wantCooldown: 2 * base, wantState: BreakerOpen},
+				{op: "advance", advance: 2 * base, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: true, wantProbe: true, wantState: BreakerHalfOpen},
+				{op: "trip", wantCooldown: 4 * base, wantState: BreakerOpen},
+				{op: "advance", advance: 4 * base, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: true, wantProbe: true, wantState: BreakerHalfOpen},
+				{op: "trip", wantCooldown: limit, wantState: BreakerOpen},
+				{op: "advance", advance: limit, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: true, wantProbe: true, wantState: BreakerHalfOpen},
+				{op: "trip", wantCooldown: limit, wantState: BreakerOpen},
+			},
+		},
+		{
+			name: "concurrent trips in one ban count once",
+			steps: []breakerStep{
+				{op: "trip", wantCooldown: base, wantState: BreakerOpen},
+				{op: "advance", advance: 20 * time.Second, wantState: BreakerOpen},
+				{op: "trip", wantCooldown: base - 20*time.Second, wantState: BreakerOpen},
+				{op: "advance", advance: 40 * time.Second, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: true, wantProbe: true, wantState: BreakerHalfOpen},
+				{op: "trip", wantCooldown: 2 * base, wantState: BreakerOpen},
+			},
+		},
+		{
+			name: "end probe releases the probe slot",
+			steps: []breakerStep{
+				{op: "trip", wantCooldown: base, wantState: BreakerOpen},
+				{op: "advance", advance: base, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: true, wantProbe: true, wantState: BreakerHalfOpen},
+				{op: "end_probe", wantState: BreakerHalfOpen},
+				{op: "allow", wantAllowed: true, wantProbe: true, wantState: BreakerHalfOpen},
+			},
+		},
+		{
+			name: "end probe outside half open is a no-op",
+			steps: []breakerStep{
+				{op: "trip", wantCooldown: base, wantState: BreakerOpen},
+				{op: "end_probe", wantState: BreakerOpen},
+				{op: "allow", wantAllowed: false, wantState: BreakerOpen},
+			},
+		},
+		{
+			name: "succeed while open does not recover",
+			steps: []breakerStep{
+				{op: "trip", wantCooldown: base, wantState: BreakerOpen},
+				{op: "succeed", wantRecover: false, wantState: BreakerOpen},
+			},
+		},
+		{
+			name: "reset clears state and trips",
+			steps: []breakerStep{
+				{op: "trip", wantCooldown: base, wantState: BreakerOpen},
+				{op: "advance", advance: base, wantState: BreakerOpen},
+				{op: "allow", wantAllowed: true, wantProbe: true, wantState: BreakerHalfOpen},
+				{op: "trip", wantCooldown: 2 * base, wantState: BreakerOpen},
+				{op: "reset", wantState: BreakerClosed},
+				{op: "allow", wantAllowed: true, wantState: BreakerClosed},
+				{op: "trip", wantCooldown: base, wantState: BreakerOpen},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, clock := newTestBreaker(base, limit)
+			for i, step := range tt.steps {
+				switch step.op {
+				case "allow":
+					allowed, probe := b.Allow()
+					if allowed != step.wantAllowed || probe != step.wantProbe {
+						t.Fatalf("step %d: Allow() = %v, %v, want %v, %v", i, allowed, probe, step.wantAllowed, step.wantProbe)
+					}
+				case "trip":
+					if got := b.Trip("HTTP 412"); got != step.wantCooldown {
+						t.Fatalf("step %d: Trip() = %v, want %v", i, got, step.wantCooldown)
+					}
+				case "succeed":
+					if got := b.Succeed(); got != step.wantRecover {
+						t.Fatalf("step %d: Succeed() = %v, want %v", i, got, step.wantRecover)
+					}
+				case "end_probe":
+					b.EndProbe()
+				case "reset":
+					b.Reset()
+				case "advance":
+					clock.Advance(step.advance)
+				default:
+					t.Fatalf("step %d: unknown op %q", i, step.op)
+				}
+				if got := b.Snapshot().State; got != step.wantState {
+					t.Fatalf("step %d (%s): state = %s, want %s", i, step.op, got, step.wantState)
+				}
+				if blocked := b.Blocked(); blocked != (step.wantState == BreakerOpen) {
+					t.Fatalf("step %d (%s): Blocked() = %v", i, step.op, blocked)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerSnapshot(t *testing.T) {
+	b, clock := newTestBreaker(time.Minute, 5*time.Minute)
+	if snap := b.Snapshot(); snap.State != BreakerClosed || snap.BannedAt != nil || snap.BannedUntil != nil || snap.Trips != 0 {
+		t.Errorf("initial snapshot = %+v, want closed", snap)
+	}
+
+	bannedAt := clock.Now()
+	b.Trip("HTTP 412")
+	snap := b.Snapshot()
+	if snap.State != BreakerOpen || snap.Trips != 1 || snap.LastReason != "HTTP 412" {
+		t.Errorf("snapshot after Trip() = %+v", snap)
+	}
+	if snap.BannedAt == nil || !snap.BannedAt.Equal(bannedAt) {
+		t.Errorf("BannedAt = %v, want %v", snap.BannedAt, bannedAt)
+	}
+	if snap.BannedUntil == nil || !snap.BannedUntil.Equal(bannedAt.Add(time.Minute)) {
+		t.Errorf("BannedUntil = %v, want %v", snap.BannedUntil, bannedAt.Add(time.Minute))
+	}
+
+	// 恢复后保留最近一次封禁的原因与时间，但不再有冷却结束时间
+	clock.Advance(time.Minute)
+	b.Allow()
+	b.Succeed()
+	snap = b.Snapshot()
+	if snap.State != BreakerClosed || snap.Trips != 0 || snap.BannedUntil != nil || snap.BannedAt == nil || snap.LastReason != "HTTP 412" {
+		t.Errorf("snapshot after recovery = %+v", snap)
+	}
+}
+
+func TestNewBreakerDefaults(t *testing.T) {
+	b := NewBreaker(0, 0)
+	if b.baseCooldown != 5*time.Minute || b.maxCooldown != 5*time.Minute {
+		t.Errorf("NewBreaker(0, 0) cooldown = %v / %v, want 5m / 5m", b.baseCooldown, b.maxCooldown)
+	}
+	b = NewBreaker(10*time.Minute, time.Minute)
+	if b.maxCooldown != 10*time.Minute {
+		t.Errorf("maxCooldown = %v, want clamped to base 10m", b.maxCooldown)
+	}
+}
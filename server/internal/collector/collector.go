@@ -12,3 +12,33 @@ type Collector interface {
 	// Collect 执行针对特定关键词的采集任务
 	Collect(ctx context.Context, keyword string) error
 }
+
+// BanAware 由会被目标平台封禁/限流的采集器实现，用于汇报和重置封禁状态
+type BanAware interface {
+	IsBanned() bool
+	// BanState 返回封禁熔断器的状态快照
+	BanState() BreakerSnapshot
+	// ResetBan 手动解除封禁状态
+	ResetBan()
+}
+
+// Discoverer 由只负责发现新内容的采集器实现
+// 调度器不会在未指定来源的关键词任务中执行这类采集器
+type Discoverer interface {
+	IsDiscovery() bool
+}
+
+type contextKey string
+
+const songIDContextKey contextKey = "song_id"
+
+// WithSongID 将关联的歌曲ID注入 context，采集器据此将评论关联到歌曲
+func WithSongID(ctx context.Context, songID uint) context.Context {
+	return context.WithValue(ctx, songIDContextKey, songID)
+}
+
+// SongIDFromContext 从 context 中读取关联的歌曲ID
+func SongIDFromContext(ctx context.Context) (uint, bool) {
+	songID, ok := ctx.Value(songIDContextKey).(uint)
+	return songID, ok && songID != 0
+}
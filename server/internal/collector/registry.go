@@ -0,0 +1,143 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/xumoe-c/maiecho/server/internal/config"
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+)
+
+// Factory 根据全局配置和该采集器自身的配置项创建采集器
+type Factory func(s storage.Storage, cfg *config.Config, opts config.CollectorConfig) (Collector, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register 注册一个采集器工厂，通常在采集器所在文件的 init 中调用
+// 名称重复注册会 panic，避免两个采集器意外共用同一个名称
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("collector: Register factory is nil for " + name)
+	}
+	if _, dup := factories[name]; dup {
+		panic("collector: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// Available 返回所有已注册的采集器名称 (按字母排序)
+func Available() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Registry 按配置创建并持有启用的采集器
+type Registry struct {
+	collectors []Collector
+	byName     map[string]Collector
+	workers    int // 调度器同时执行的任务数
+}
+
+// NewRegistry 按 cfg.Collectors 的顺序创建启用的采集器
+// 配置中出现未注册或重复的名称时返回错误
+func NewRegistry(s storage.Storage, cfg *config.Config) (*Registry, error) {
+	r := &Registry{byName: make(map[string]Collector), workers: 1}
+	seen := make(map[string]bool)
+
+	for _, opts := range cfg.Collectors {
+		if seen[opts.Name] {
+			return nil, fmt.Errorf("采集器 %q 重复配置", opts.Name)
+		}
+		seen[opts.Name] = true
+
+		factoriesMu.RLock()
+		factory, ok := factories[opts.Name]
+		factoriesMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("未知的采集器 %q，可用的采集器: %v", opts.Name, Available())
+		}
+
+		if !opts.IsEnabled() {
+			logger.Info("采集器已禁用", "module", "collector.registry", "collector", opts.Name)
+			continue
+		}
+
+		c, err := factory(s, cfg, opts)
+		if err != nil {
+			return nil, fmt.Errorf("创建采集器 %q 失败: %w", opts.Name, err)
+		}
+		r.collectors = append(r.collectors, c)
+		r.byName[c.Name()] = c
+		r.workers = max(r.workers, opts.Parallelism)
+	}
+
+	logger.Info("采集器注册完成", "module", "collector.registry", "collectors", r.Names())
+	return r, nil
+}
+
+// All 按配置顺序返回所有启用的采集器
+func (r *Registry) All() []Collector {
+	return r.collectors
+}
+
+// Get 按名称获取启用的采集器
+func (r *Registry) Get(name string) (Collector, bool) {
+	c, ok := r.byName[name]
+	return c, ok
+}
+
+// Workers 返回调度器同时执行的任务数，即启用的采集器中配置的最大 parallelism (至少为 1)
+// 各采集器发出的并发请求仍受其自身的 parallelism 限制
+func (r *Registry) Workers() int {
+	return r.workers
+}
+
+// Names 按配置顺序返回所有启用的采集器名称
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		names = append(names, c.Name())
+	}
+	return names
+}
+
+// applyOptions 为 colly 采集器设置代理和限流规则，未配置的项使用 def 中的默认值
+func applyOptions(c *colly.Collector, module string, opts config.CollectorConfig, def colly.LimitRule) {
+	if opts.Proxy != "" {
+		if err := c.SetProxy(opts.Proxy); err != nil {
+			logger.Error("设置代理失败", "module", module, "proxy", opts.Proxy, "error", err)
+		} else {
+			logger.Info("已启用代理", "module", module, "proxy", opts.Proxy)
+		}
+	}
+
+	rule := def
+	if opts.Parallelism > 0 {
+		rule.Parallelism = opts.Parallelism
+	}
+	if opts.Delay > 0 {
+		rule.Delay = opts.Delay
+	}
+	if opts.RandomDelay > 0 {
+		rule.RandomDelay = opts.RandomDelay
+	}
+	if err := c.Limit(&rule); err != nil {
+		logger.Error("设置采集限制失败", "module", module, "error", err)
+	}
+}
@@ -0,0 +1,44 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+)
+
+func TestRegistryWorkers(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name       string
+		collectors []config.CollectorConfig
+		want       int
+	}{
+		{
+			name:       "defaults to one worker",
+			collectors: []config.CollectorConfig{{Name: "bilibili"}, {Name: "tieba"}},
+			want:       1,
+		},
+		{
+			name:       "largest configured parallelism",
+			collectors: []config.CollectorConfig{{Name: "bilibili", Parallelism: 3}, {Name: "tieba", Parallelism: 2}},
+			want:       3,
+		},
+		{
+			name:       "disabled collectors are ignored",
+			collectors: []config.CollectorConfig{{Name: "bilibili", Parallelism: 2}, {Name: "tieba", Parallelism: 5, Enabled: &disabled}},
+			want:       2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRegistry(nil, &config.Config{Collectors: tt.collectors})
+			if err != nil {
+				t.Fatalf("NewRegistry() error = %v", err)
+			}
+			if got := r.Workers(); got != tt.want {
+				t.Errorf("Workers() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
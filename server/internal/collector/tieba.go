@@ -0,0 +1,420 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+	"github.com/gocolly/colly/v2/extensions"
+	"github.com/tidwall/gjson"
+	"github.com/xumoe-c/maiecho/server/internal/config"
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+)
+
+// 贴吧采集的默认值
+const (
+	defaultTiebaForum       = "maimai"
+	defaultTiebaSearchPages = 2
+	defaultTiebaThreadPages = 3
+)
+
+// tiebaDateLayout 贴吧页面中的时间格式 (北京时间)
+const tiebaDateLayout = "2006-01-02 15:04"
+
+var tiebaLocation = time.FixedZone("CST", 8*60*60)
+
+// tiebaThreadIDPattern 从帖子链接中提取帖子ID，例如 /p/8812345678?pid=...
+var tiebaThreadIDPattern = regexp.MustCompile(`/p/(\d+)`)
+
+// TiebaCollector 在指定贴吧内搜索关键词，采集相关帖子的楼层内容
+type TiebaCollector struct {
+	storage storage.Storage
+	c       *colly.Collector
+	cookie  string
+
+	forum       string // 搜索的贴吧名称
+	searchPages int    // 每个关键词最多采集的搜索结果页数
+	threadPages int    // 每个帖子最多采集的页数
+}
+
+// tiebaRun 一次 Collect 调用的状态，在该次采集的所有请求之间共享
+type tiebaRun struct {
+	ctx      context.Context
+	seen     sync.Map // 同一次采集中每个帖子只抓取一次
+	searched atomic.Int32
+
+	mu   sync.Mutex
+	errs []error // 搜索页的请求或解析错误
+}
+
+// searchFailed 记录一个搜索页的错误
+func (r *tiebaRun) searchFailed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, err)
+}
+
+// tiebaSearchResult 搜索结果中的一条帖子
+type tiebaSearchResult struct {
+	ThreadID string
+	Title    string
+	Snippet  string
+	Author   string
+	PostDate time.Time
+}
+
+// tiebaPost 帖子中的一个楼层
+type tiebaPost struct {
+	PostID   string
+	Floor    int
+	Author   string
+	Content  string
+	PostDate time.Time
+}
+
+// tiebaThread 帖子的一页内容
+type tiebaThread struct {
+	Title      string
+	TotalPages int
+	Posts      []tiebaPost
+}
+
+func init() {
+	Register("tieba", func(s storage.Storage, cfg *config.Config, opts config.CollectorConfig) (Collector, error) {
+		return NewTiebaCollector(s, cfg.Tieba, opts), nil
+	})
+}
+
+func NewTiebaCollector(s storage.Storage, cfg config.TiebaConfig, opts config.CollectorConfig) *TiebaCollector {
+	c := colly.NewCollector(
+		colly.Async(true),
+	)
+	c.AllowURLRevisit = true
+	extensions.RandomUserAgent(c)
+
+	// 贴吧对频繁访问较为敏感，默认单并发并加入随机延迟
+	applyOptions(c, "collector.tieba", opts, colly.LimitRule{
+		DomainGlob:  "*tieba.baidu.com*",
+		Parallelism: 1,
+		Delay:       2 * time.Second,
+		RandomDelay: 3 * time.Second,
+	})
+
+	tc := &TiebaCollector{
+		storage:     s,
+		c:           c,
+		cookie:      opts.Cookie,
+		forum:       cfg.Forum,
+		searchPages: positiveOr(opts.Pages, defaultTiebaSearchPages),
+		threadPages: positiveOr(cfg.ThreadPages, defaultTiebaThreadPages),
+	}
+	if tc.forum == "" {
+		tc.forum = defaultTiebaForum
+	}
+
+	tc.setupCallbacks()
+	return tc
+}
+
+func (t *TiebaCollector) Name() string {
+	return "tieba"
+}
+
+// Collect 搜索关键词并采集相关帖子
+// 所有搜索页都请求或解析失败时返回错误；ctx 结束后不再发出新的请求，并返回 ctx 的错误
+func (t *TiebaCollector) Collect(ctx context.Context, keyword string) error {
+	logger.Info("开始采集", "module", "collector.tieba", "collector", t.Name(), "forum", t.forum, "keyword", keyword)
+
+	run := &tiebaRun{ctx: ctx}
+	for page := 1; page <= t.searchPages; page++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// API: https://tieba.baidu.com/f/search/res?ie=utf-8&kw={forum}&qw={keyword}&pn={page}
+		searchURL := fmt.Sprintf("https://tieba.baidu.com/f/search/res?ie=utf-8&kw=%s&qw=%s&rn=20&pn=%d",
+			url.QueryEscape(t.forum), url.QueryEscape(keyword), page)
+
+		collyCtx := colly.NewContext()
+		collyCtx.Put("keyword", keyword)
+		collyCtx.Put("run", run)
+		if songID, ok := SongIDFromContext(ctx); ok {
+			collyCtx.Put("song_id", songID)
+		}
+
+		if err := t.c.Request("GET", searchURL, nil, collyCtx, nil); err != nil {
+			logger.Error("访问搜索页失败", "module", "collector.tieba", "page", page, "error", err)
+			run.searchFailed(err)
+		}
+	}
+
+	t.c.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if run.searched.Load() == 0 {
+		if err := errors.Join(run.errs...); err != nil {
+			return fmt.Errorf("tieba search for %q failed: %w", keyword, err)
+		}
+		return fmt.Errorf("tieba search for %q returned no pages", keyword)
+	}
+	return nil
+}
+
+func (t *TiebaCollector) setupCallbacks() {
+	t.c.OnRequest(func(r *colly.Request) {
+		// 采集已取消时不再发出请求
+		if run, ok := r.Ctx.GetAny("run").(*tiebaRun); ok && run.ctx.Err() != nil {
+			r.Abort()
+			return
+		}
+
+		r.Headers.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		r.Headers.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+		r.Headers.Set("Referer", "https://tieba.baidu.com/f?kw="+url.QueryEscape(t.forum))
+		if t.cookie != "" {
+			r.Headers.Set("Cookie", t.cookie)
+		}
+		logger.Info("Visiting Tieba", "module", "collector.tieba", "url", r.URL.String())
+	})
+
+	t.c.OnError(func(r *colly.Response, err error) {
+		logger.Error("请求失败", "module", "collector.tieba", "url", r.Request.URL, "status", r.StatusCode, "error", err)
+		if run, ok := r.Ctx.GetAny("run").(*tiebaRun); ok && r.Request.URL.Path == "/f/search/res" {
+			run.searchFailed(fmt.Errorf("%s: %w", r.Request.URL, err))
+		}
+	})
+
+	t.c.OnResponse(func(r *colly.Response) {
+		switch {
+		case r.Request.URL.Path == "/f/search/res":
+			t.handleSearchResponse(r.Body, r.Ctx)
+		case strings.HasPrefix(r.Request.URL.Path, "/p/"):
+			t.handleThreadResponse(r.Body, r.Ctx)
+		}
+	})
+}
+
+func (t *TiebaCollector) handleSearchResponse(body []byte, ctx *colly.Context) {
+	run, _ := ctx.GetAny("run").(*tiebaRun)
+	results, err := parseTiebaSearch(body)
+	if err != nil {
+		logger.Error("解析搜索结果失败", "module", "collector.tieba", "keyword", ctx.Get("keyword"), "error", err)
+		if run != nil {
+			run.searchFailed(err)
+		}
+		return
+	}
+	if run != nil {
+		run.searched.Add(1)
+	}
+
+	song := t.songFromContext(ctx)
+
+	for _, result := range results {
+		// 相关性检查
+		if song != nil && !isRelevantTitle(result.Title, song) {
+			logger.Info("跳过不相关帖子", "module", "collector.tieba", "title", result.Title, "song", song.Title)
+			continue
+		}
+		if run != nil {
+			if _, loaded := run.seen.LoadOrStore(result.ThreadID, struct{}{}); loaded {
+				continue
+			}
+		}
+
+		logger.Info("Found Thread", "module", "collector.tieba", "tid", result.ThreadID, "title", result.Title)
+
+		newCtx := colly.NewContext()
+		newCtx.Put("tid", result.ThreadID)
+		newCtx.Put("title", result.Title)
+		newCtx.Put("keyword", ctx.Get("keyword"))
+		if run != nil {
+			newCtx.Put("run", run)
+		}
+		if songIDVal := ctx.GetAny("song_id"); songIDVal != nil {
+			newCtx.Put("song_id", songIDVal)
+		}
+		t.requestThreadPage(newCtx, 1)
+	}
+}
+
+// requestThreadPage 请求帖子的一页
+// URL: https://tieba.baidu.com/p/{tid}?pn={page}
+func (t *TiebaCollector) requestThreadPage(ctx *colly.Context, page int) {
+	ctx.Put("page", page)
+	threadURL := fmt.Sprintf("https://tieba.baidu.com/p/%s?pn=%d", ctx.Get("tid"), page)
+	if err := t.c.Request("GET", threadURL, nil, ctx, nil); err != nil {
+		logger.Error("请求帖子失败", "module", "collector.tieba", "url", threadURL, "error", err)
+	}
+}
+
+func (t *TiebaCollector) handleThreadResponse(body []byte, ctx *colly.Context) {
+	thread, err := parseTiebaThread(body)
+	if err != nil {
+		logger.Error("解析帖子失败", "module", "collector.tieba", "tid", ctx.Get("tid"), "error", err)
+		return
+	}
+
+	title := thread.Title
+	if title == "" {
+		title = ctx.Get("title")
+	}
+
+	for _, post := range thread.Posts {
+		comment := &model.Comment{
+			Source:      "Tieba",
+			SourceTitle: title,
+			ExternalID:  post.PostID,
+			Content:     post.Content,
+			Author:      post.Author,
+			PostDate:    post.PostDate,
+			SearchTag:   ctx.Get("keyword"),
+		}
+		if songIDVal := ctx.GetAny("song_id"); songIDVal != nil {
+			if songID, ok := songIDVal.(uint); ok {
+				comment.SongID = &songID
+			}
+		}
+
+		if err := t.storage.UpsertComment(comment); err != nil {
+			logger.Error("保存楼层失败", "module", "collector.tieba", "pid", post.PostID, "error", err)
+		}
+	}
+
+	// 下一页
+	page, _ := ctx.GetAny("page").(int)
+	if page < thread.TotalPages && page < t.threadPages {
+		next := colly.NewContext()
+		for _, key := range []string{"tid", "title", "keyword", "song_id", "run"} {
+			if v := ctx.GetAny(key); v != nil {
+				next.Put(key, v)
+			}
+		}
+		t.requestThreadPage(next, page+1)
+	}
+}
+
+func (t *TiebaCollector) songFromContext(ctx *colly.Context) *model.Song {
+	songID, ok := ctx.GetAny("song_id").(uint)
+	if !ok {
+		return nil
+	}
+	song, err := t.storage.GetSong(songID)
+	if err != nil {
+		return nil
+	}
+	return song
+}
+
+// parseTiebaSearch 解析贴吧吧内搜索结果页 (/f/search/res)
+func parseTiebaSearch(body []byte) ([]tiebaSearchResult, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var results []tiebaSearchResult
+	doc.Find("div.s_post").Each(func(i int, s *goquery.Selection) {
+		link := s.Find(".p_title a").First()
+		tid, _ := link.Attr("data-tid")
+		if tid == "" {
+			href, _ := link.Attr("href")
+			if m := tiebaThreadIDPattern.FindStringSubmatch(href); m != nil {
+				tid = m[1]
+			}
+		}
+		if tid == "" {
+			return
+		}
+
+		result := tiebaSearchResult{
+			ThreadID: tid,
+			Title:    normalizeSpace(link.Text()),
+			Snippet:  normalizeSpace(s.Find(".p_content").Text()),
+		}
+		// 作者位于 "作者：" 之后的链接中，第一个 p_violet 为贴吧名
+		if violets := s.Find("font.p_violet"); violets.Length() > 1 {
+			result.Author = normalizeSpace(violets.Last().Text())
+		}
+		if date, err := time.ParseInLocation(tiebaDateLayout, normalizeSpace(s.Find(".p_date").Text()), tiebaLocation); err == nil {
+			result.PostDate = date
+		}
+		results = append(results, result)
+	})
+	return results, nil
+}
+
+// parseTiebaThread 解析帖子页 (/p/{tid})，楼层元信息来自 data-field 属性中的 JSON
+func parseTiebaThread(body []byte) (*tiebaThread, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	thread := &tiebaThread{TotalPages: 1}
+	titleSel := doc.Find(".core_title_txt").First()
+	if title, ok := titleSel.Attr("title"); ok {
+		thread.Title = normalizeSpace(title)
+	} else {
+		thread.Title = normalizeSpace(titleSel.Text())
+	}
+
+	// 总页数："共 N 页"
+	if pages := doc.Find("li.l_reply_num span.red"); pages.Length() > 1 {
+		if n, err := strconv.Atoi(normalizeSpace(pages.Last().Text())); err == nil && n > 0 {
+			thread.TotalPages = n
+		}
+	}
+
+	doc.Find("div.l_post").Each(func(i int, s *goquery.Selection) {
+		field, ok := s.Attr("data-field")
+		if !ok || !gjson.Valid(field) {
+			return
+		}
+		data := gjson.Parse(field)
+		postID := data.Get("content.post_id").String()
+		if postID == "" {
+			return
+		}
+
+		post := tiebaPost{
+			PostID:  postID,
+			Floor:   int(data.Get("content.post_no").Int()),
+			Author:  data.Get("author.user_name").String(),
+			Content: normalizeSpace(s.Find(".d_post_content").First().Text()),
+		}
+		if post.Author == "" {
+			post.Author = normalizeSpace(s.Find(".d_name a").First().Text())
+		}
+		if post.Content == "" {
+			return
+		}
+
+		// 尾巴中的时间，例如 "2024-03-01 20:15"
+		s.Find(".post-tail-wrap .tail-info").Each(func(j int, tail *goquery.Selection) {
+			if date, err := time.ParseInLocation(tiebaDateLayout, normalizeSpace(tail.Text()), tiebaLocation); err == nil {
+				post.PostDate = date
+			}
+		})
+		thread.Posts = append(thread.Posts, post)
+	})
+	return thread, nil
+}
+
+// normalizeSpace 去除首尾空白并合并连续空白
+func normalizeSpace(src string) string {
+	return strings.Join(strings.Fields(src), " ")
+}
@@ -0,0 +1,278 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/xumoe-c/maiecho/server/internal/config"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("读取测试数据 %s 失败: %v", name, err)
+	}
+	return body
+}
+
+func TestParseTiebaSearch(t *testing.T) {
+	results, err := parseTiebaSearch(readFixture(t, "tieba_search.html"))
+	if err != nil {
+		t.Fatalf("parseTiebaSearch() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(results))
+	}
+
+	first := results[0]
+	if first.ThreadID != "8812345678" {
+		t.Errorf("ThreadID = %q, want 8812345678", first.ThreadID)
+	}
+	if first.Title != "【求助】PANDORA PARADOXXX 白谱 12 个绝赞怎么打" {
+		t.Errorf("Title = %q", first.Title)
+	}
+	if first.Author != "舞萌人" {
+		t.Errorf("Author = %q, want 舞萌人", first.Author)
+	}
+	wantDate := time.Date(2024, 3, 1, 20, 15, 0, 0, tiebaLocation)
+	if !first.PostDate.Equal(wantDate) {
+		t.Errorf("PostDate = %v, want %v", first.PostDate, wantDate)
+	}
+
+	// 没有 data-tid 时从链接中提取帖子ID
+	if results[1].ThreadID != "8899990000" {
+		t.Errorf("ThreadID from href = %q, want 8899990000", results[1].ThreadID)
+	}
+}
+
+func TestParseTiebaThread(t *testing.T) {
+	thread, err := parseTiebaThread(readFixture(t, "tieba_thread.html"))
+	if err != nil {
+		t.Fatalf("parseTiebaThread() error = %v", err)
+	}
+	if thread.Title != "【求助】PANDORA PARADOXXX 白谱 12 个绝赞怎么打" {
+		t.Errorf("Title = %q", thread.Title)
+	}
+	if thread.TotalPages != 3 {
+		t.Errorf("TotalPages = %d, want 3", thread.TotalPages)
+	}
+
+	// 无效 data-field 与空内容的楼层会被跳过
+	if len(thread.Posts) != 3 {
+		t.Fatalf("len(Posts) = %d, want 3", len(thread.Posts))
+	}
+
+	first := thread.Posts[0]
+	if first.PostID != "148800000001" || first.Floor != 1 || first.Author != "舞萌人" {
+		t.Errorf("first post = %+v", first)
+	}
+	if first.Content != "如题，中间那段交互总是断。12 个绝赞全在后半，求手法" {
+		t.Errorf("Content = %q", first.Content)
+	}
+	wantDate := time.Date(2024, 3, 1, 20, 15, 0, 0, tiebaLocation)
+	if !first.PostDate.Equal(wantDate) {
+		t.Errorf("PostDate = %v, want %v", first.PostDate, wantDate)
+	}
+
+	// data-field 中没有用户名时回退到页面上的昵称，并合并多余空白
+	third := thread.Posts[2]
+	if third.Author != "匿名玩家" {
+		t.Errorf("Author fallback = %q, want 匿名玩家", third.Author)
+	}
+	if third.Content != "绝赞 可以 提前准备" {
+		t.Errorf("Content = %q", third.Content)
+	}
+}
+
+// fixtureTransport 根据请求路径返回 testdata 中的页面
+type fixtureTransport struct {
+	t    *testing.T
+	mu   sync.Mutex
+	hits map[string]int
+
+	// status 可选，返回非 200 时以该状态码响应
+	status func(req *http.Request) int
+}
+
+func (f *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	f.hits[req.URL.Path]++
+	f.mu.Unlock()
+
+	if f.status != nil {
+		if status := f.status(req); status != http.StatusOK {
+			return &http.Response{
+				StatusCode: status,
+				Header:     http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+				Body:       io.NopCloser(strings.NewReader("")),
+				Request:    req,
+			}, nil
+		}
+	}
+
+	name := "tieba_thread.html"
+	if req.URL.Path == "/f/search/res" {
+		name = "tieba_search.html"
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+		Body:       io.NopCloser(bytes.NewReader(readFixture(f.t, name))),
+		Request:    req,
+	}, nil
+}
+
+// Hits 返回路径被请求的次数
+func (f *fixtureTransport) Hits(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.hits[path]
+}
+
+type fakeCommentStorage struct {
+	storage.Storage
+	mu       sync.Mutex
+	song     *model.Song
+	comments map[string]model.Comment
+}
+
+func (f *fakeCommentStorage) GetSong(id uint) (*model.Song, error) {
+	return f.song, nil
+}
+
+func (f *fakeCommentStorage) UpsertComment(c *model.Comment) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.comments[c.Source+"/"+c.ExternalID] = *c
+	return nil
+}
+
+// newFixtureTiebaCollector 创建无延迟、从 testdata 返回页面的贴吧采集器
+func newFixtureTiebaCollector(t *testing.T, searchPages int) (*TiebaCollector, *fakeCommentStorage, *fixtureTransport) {
+	t.Helper()
+	store := &fakeCommentStorage{
+		song:     &model.Song{Title: "PANDORA PARADOXXX"},
+		comments: make(map[string]model.Comment),
+	}
+	tc := NewTiebaCollector(store, config.TiebaConfig{ThreadPages: 2}, config.CollectorConfig{Name: "tieba", Pages: searchPages})
+
+	transport := &fixtureTransport{t: t, hits: make(map[string]int)}
+	tc.c = colly.NewCollector(colly.Async(true))
+	tc.c.AllowURLRevisit = true
+	tc.c.WithTransport(transport)
+	tc.setupCallbacks()
+	return tc, store, transport
+}
+
+func TestTiebaCollectorCollect(t *testing.T) {
+	tc, store, transport := newFixtureTiebaCollector(t, 1)
+
+	ctx := WithSongID(context.Background(), 42)
+	if err := tc.Collect(ctx, "PANDORA PARADOXXX"); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	// 两个帖子各采集 2 页 (thread_pages 限制)，同一楼层重复出现只保存一次
+	if got := transport.hits["/p/8812345678"]; got != 2 {
+		t.Errorf("thread 8812345678 requested %d times, want 2", got)
+	}
+	if len(store.comments) != 3 {
+		t.Fatalf("saved %d comments, want 3", len(store.comments))
+	}
+	for key, c := range store.comments {
+		if c.Source != "Tieba" {
+			t.Errorf("%s: Source = %q, want Tieba", key, c.Source)
+		}
+		if c.SongID == nil || *c.SongID != 42 {
+			t.Errorf("%s: SongID = %v, want 42", key, c.SongID)
+		}
+		if c.SearchTag != "PANDORA PARADOXXX" {
+			t.Errorf("%s: SearchTag = %q", key, c.SearchTag)
+		}
+	}
+}
+
+func TestTiebaCollectorSearchFailures(t *testing.T) {
+	searchPage := func(req *http.Request) string { return req.URL.Query().Get("pn") }
+
+	// 所有搜索页都失败时返回错误
+	tc, store, transport := newFixtureTiebaCollector(t, 2)
+	transport.status = func(req *http.Request) int {
+		if req.URL.Path == "/f/search/res" {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}
+	if err := tc.Collect(context.Background(), "PANDORA PARADOXXX"); err == nil {
+		t.Error("Collect() error = nil, want error when every search page fails")
+	}
+	if got := transport.Hits("/f/search/res"); got != 2 {
+		t.Errorf("search requested %d times, want 2", got)
+	}
+	if len(store.comments) != 0 {
+		t.Errorf("saved %d comments, want 0", len(store.comments))
+	}
+
+	// 部分搜索页失败时仍采集成功的部分
+	tc, store, transport = newFixtureTiebaCollector(t, 2)
+	transport.status = func(req *http.Request) int {
+		if req.URL.Path == "/f/search/res" && searchPage(req) == "2" {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}
+	if err := tc.Collect(context.Background(), "PANDORA PARADOXXX"); err != nil {
+		t.Errorf("Collect() error = %v, want nil when some search pages succeed", err)
+	}
+	if len(store.comments) != 3 {
+		t.Errorf("saved %d comments, want 3", len(store.comments))
+	}
+}
+
+func TestTiebaCollectorCancel(t *testing.T) {
+	// ctx 已结束时不发出任何请求
+	tc, _, transport := newFixtureTiebaCollector(t, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tc.Collect(ctx, "PANDORA PARADOXXX"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Collect() error = %v, want context.Canceled", err)
+	}
+	if got := transport.Hits("/f/search/res"); got != 0 {
+		t.Errorf("search requested %d times after cancel, want 0", got)
+	}
+
+	// 采集过程中 ctx 结束后不再请求帖子
+	tc, store, transport := newFixtureTiebaCollector(t, 1)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	transport.status = func(req *http.Request) int {
+		if req.URL.Path == "/f/search/res" {
+			cancel()
+		}
+		return http.StatusOK
+	}
+	if err := tc.Collect(ctx, "PANDORA PARADOXXX"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Collect() error = %v, want context.Canceled", err)
+	}
+	for path, n := range transport.hits {
+		if strings.HasPrefix(path, "/p/") && n > 0 {
+			t.Errorf("%s requested %d times after cancel, want 0", path, n)
+		}
+	}
+	if len(store.comments) != 0 {
+		t.Errorf("saved %d comments after cancel, want 0", len(store.comments))
+	}
+}
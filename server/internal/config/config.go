@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/xumoe-c/maiecho/server/internal/logger"
@@ -14,17 +15,191 @@ type Config struct {
 	LLM         LLMConfig      `mapstructure:"llm"`
 	Log         logger.Config  `mapstructure:"log"`
 	Bilibili    BilibiliConfig `mapstructure:"bilibili"`
+	Tieba       TiebaConfig    `mapstructure:"tieba"`
+
+	// Collectors 启用的采集器及其调优参数，按列表顺序注册和执行
+	Collectors []CollectorConfig `mapstructure:"collectors"`
 }
 
+// LLMConfig LLM 提供方与按角色的路由配置
+// 顶层的 api_key/base_url/model 等字段描述单一提供方 (未配置 providers 时使用，名称为 default)，
+// 同时作为 providers 中各项未设置字段的默认值
 type LLMConfig struct {
+	LLMProviderConfig `mapstructure:",squash"`
+
+	Providers []LLMProviderConfig `mapstructure:"providers"` // 可用的提供方，名称唯一
+	Routes    map[string][]string `mapstructure:"routes"`    // 角色 -> 按故障转移顺序排列的提供方名称，未配置的角色使用 default 路由
+
+	DailyBudget      float64 `mapstructure:"daily_budget"`       // 每日费用上限 (按提供方单价估算)，超出后暂停分析作业，0 表示不限制
+	DailyTokenBudget int64   `mapstructure:"daily_token_budget"` // 每日 token 总量上限，超出后暂停分析作业，0 表示不限制
+
+	CacheTTL time.Duration `mapstructure:"cache_ttl"` // 确定性调用 (匹配确认、相关性判断、清洗) 响应缓存的有效期，0 表示不缓存
+}
+
+// LLMProviderConfig 单个 LLM 提供方 (OpenAI 兼容接口 + 模型)
+type LLMProviderConfig struct {
+	Name    string `mapstructure:"name"`
 	APIKey  string `mapstructure:"api_key"`
 	BaseURL string `mapstructure:"base_url"`
 	Model   string `mapstructure:"model"`
+
+	Timeout        time.Duration `mapstructure:"timeout"`          // 单次请求的超时时间，0 表示不限制
+	MaxRetries     *int          `mapstructure:"max_retries"`      // 可重试错误 (429、5xx、超时、网络错误) 的最大重试次数
+	RetryBaseDelay time.Duration `mapstructure:"retry_base_delay"` // 首次重试前的基础等待时间，之后每次翻倍并加随机抖动
+	RetryMaxDelay  time.Duration `mapstructure:"retry_max_delay"`  // 单次等待时间的上限 (不限制服务端 Retry-After 指定的时间)
+
+	PromptPrice     float64 `mapstructure:"prompt_price"`     // 每百万输入 token 的价格，用于估算费用
+	CompletionPrice float64 `mapstructure:"completion_price"` // 每百万输出 token 的价格
+
+	JSONMode string `mapstructure:"json_mode"` // 结构化输出使用的 response_format: json_schema, json_object (默认) 或 off
+}
+
+// DefaultLLMProvider 未配置 providers 时由顶层字段生成的提供方名称
+const DefaultLLMProvider = "default"
+
+// 结构化输出 (JSON) 的请求方式，取决于提供方支持的 response_format
+const (
+	JSONModeSchema = "json_schema" // 发送由结构体生成的 JSON Schema (OpenAI Structured Outputs)
+	JSONModeObject = "json_object" // 只要求输出 JSON 对象，Schema 写在系统提示词中
+	JSONModeOff    = "off"         // 不发送 response_format，只依靠提示词
+)
+
+// Retries 最大重试次数，未设置时为 0
+func (p LLMProviderConfig) Retries() int {
+	if p.MaxRetries == nil {
+		return 0
+	}
+	return *p.MaxRetries
+}
+
+// ResolvedProviders 返回全部提供方，未设置的字段继承顶层配置
+func (c LLMConfig) ResolvedProviders() []LLMProviderConfig {
+	if len(c.Providers) == 0 {
+		p := c.LLMProviderConfig
+		p.Name = DefaultLLMProvider
+		return []LLMProviderConfig{p}
+	}
+
+	providers := make([]LLMProviderConfig, 0, len(c.Providers))
+	for _, p := range c.Providers {
+		if p.APIKey == "" {
+			p.APIKey = c.APIKey
+		}
+		if p.BaseURL == "" {
+			p.BaseURL = c.BaseURL
+		}
+		if p.Model == "" {
+			p.Model = c.Model
+		}
+		if p.Timeout == 0 {
+			p.Timeout = c.Timeout
+		}
+		if p.MaxRetries == nil {
+			p.MaxRetries = c.MaxRetries
+		}
+		if p.RetryBaseDelay == 0 {
+			p.RetryBaseDelay = c.RetryBaseDelay
+		}
+		if p.RetryMaxDelay == 0 {
+			p.RetryMaxDelay = c.RetryMaxDelay
+		}
+		if p.PromptPrice == 0 {
+			p.PromptPrice = c.PromptPrice
+		}
+		if p.CompletionPrice == 0 {
+			p.CompletionPrice = c.CompletionPrice
+		}
+		if p.JSONMode == "" {
+			p.JSONMode = c.JSONMode
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
+
+// Validate 检查提供方名称唯一、API Key 齐全，以及路由引用的提供方均已定义
+func (c LLMConfig) Validate() error {
+	if c.DailyBudget < 0 || c.DailyTokenBudget < 0 {
+		return fmt.Errorf("llm.daily_budget 与 llm.daily_token_budget 不能为负数")
+	}
+	if c.CacheTTL < 0 {
+		return fmt.Errorf("llm.cache_ttl 不能为负数")
+	}
+	known := make(map[string]bool)
+	for _, p := range c.ResolvedProviders() {
+		if p.Name == "" {
+			return fmt.Errorf("llm.providers 中存在未命名的提供方")
+		}
+		if known[p.Name] {
+			return fmt.Errorf("llm 提供方名称重复: %s", p.Name)
+		}
+		known[p.Name] = true
+		switch p.JSONMode {
+		case "", JSONModeSchema, JSONModeObject, JSONModeOff:
+		default:
+			return fmt.Errorf("llm 提供方 %s 的 json_mode 无效: %s (可选: %s, %s, %s)", p.Name, p.JSONMode, JSONModeSchema, JSONModeObject, JSONModeOff)
+		}
+		if p.APIKey == "" {
+			if p.Name == DefaultLLMProvider {
+				return fmt.Errorf("llm.api_key 是必填项")
+			}
+			return fmt.Errorf("llm 提供方 %s 缺少 api_key", p.Name)
+		}
+	}
+	for role, names := range c.Routes {
+		if len(names) == 0 {
+			return fmt.Errorf("llm.routes.%s 未指定提供方", role)
+		}
+		for _, name := range names {
+			if !known[name] {
+				return fmt.Errorf("llm.routes.%s 引用了未定义的提供方: %s", role, name)
+			}
+		}
+	}
+	return nil
 }
 
 type BilibiliConfig struct {
-	Cookie string `mapstructure:"cookie"`
-	Proxy  string `mapstructure:"proxy"`
+	Cookie         string        `mapstructure:"cookie"`
+	Proxy          string        `mapstructure:"proxy"`
+	BanCooldown    time.Duration `mapstructure:"ban_cooldown"`     // 首次触发封禁后的冷却时长
+	MaxBanCooldown time.Duration `mapstructure:"max_ban_cooldown"` // 连续封禁时冷却时长的上限
+
+	ReplyPages          int  `mapstructure:"reply_pages"`            // 每个视频最多采集的顶层评论页数
+	SubReplyPages       *int `mapstructure:"sub_reply_pages"`        // 每条顶层评论最多采集的楼中楼页数，0 表示不采集楼中楼；未设置时使用默认值
+	ReplyPageSize       int  `mapstructure:"reply_page_size"`        // 每页评论数
+	MaxCommentsPerVideo int  `mapstructure:"max_comments_per_video"` // 每个视频最多保存的评论数 (含楼中楼)
+}
+
+type TiebaConfig struct {
+	Forum       string `mapstructure:"forum"`        // 搜索的贴吧名称
+	ThreadPages int    `mapstructure:"thread_pages"` // 每个帖子最多采集的页数
+}
+
+// CollectorConfig 单个采集器的注册与调优配置，未设置的项使用采集器自身的默认值
+type CollectorConfig struct {
+	Name        string        `mapstructure:"name"`         // 采集器名称，需与已注册的采集器一致
+	Enabled     *bool         `mapstructure:"enabled"`      // 未设置时视为启用
+	Parallelism int           `mapstructure:"parallelism"`  // 同一域名的最大并发请求数
+	Delay       time.Duration `mapstructure:"delay"`        // 请求之间的固定延迟
+	RandomDelay time.Duration `mapstructure:"random_delay"` // 在固定延迟之上追加的随机延迟上限
+	Pages       int           `mapstructure:"pages"`        // 每个关键词最多采集的搜索结果页数
+	Proxy       string        `mapstructure:"proxy"`
+	Cookie      string        `mapstructure:"cookie"`
+}
+
+// IsEnabled 判断采集器是否启用
+func (c CollectorConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// DefaultCollectors 未配置 collectors 时启用的采集器
+func DefaultCollectors() []CollectorConfig {
+	return []CollectorConfig{
+		{Name: "bilibili_discovery"},
+		{Name: "bilibili"},
+		{Name: "tieba"},
+	}
 }
 
 func Load() (*Config, error) {
@@ -32,13 +207,27 @@ func Load() (*Config, error) {
 
 	// 设置默认值
 	v.SetDefault("server_port", ":8080")
-	v.SetDefault("database_url", "maiecho.db")
+	v.SetDefault("database_url", "server/sqlite_db/maiecho.db")
+	v.SetDefault("llm.api_key", "") // 注册键名，使 LLM_API_KEY 环境变量生效
 	v.SetDefault("llm.base_url", "https://dashscope.aliyuncs.com/compatible-mode/v1")
 	v.SetDefault("llm.model", "qwen-plus")
+	v.SetDefault("llm.timeout", "2m")
+	v.SetDefault("llm.max_retries", 3)
+	v.SetDefault("llm.retry_base_delay", "1s")
+	v.SetDefault("llm.retry_max_delay", "30s")
+	v.SetDefault("llm.cache_ttl", "720h")
 	v.SetDefault("log.level", "info")
 	v.SetDefault("log.output_path", "logs/maiecho.log")
 	v.SetDefault("log.llm_log_path", "logs/llm_conversations.log")
 	v.SetDefault("log.encoding", "console")
+	v.SetDefault("bilibili.ban_cooldown", "5m")
+	v.SetDefault("bilibili.max_ban_cooldown", "2h")
+	v.SetDefault("bilibili.reply_pages", 5)
+	v.SetDefault("bilibili.sub_reply_pages", 2)
+	v.SetDefault("bilibili.reply_page_size", 20)
+	v.SetDefault("bilibili.max_comments_per_video", 300)
+	v.SetDefault("tieba.forum", "maimai")
+	v.SetDefault("tieba.thread_pages", 3)
 
 	// 读取环境变量
 	v.AutomaticEnv()
@@ -67,10 +256,14 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("解析配置失败: %w", err)
 	}
 
+	if len(cfg.Collectors) == 0 {
+		cfg.Collectors = DefaultCollectors()
+	}
+
 	// 验证必填字段
-	if v.GetString("llm.api_key") == "" {
-		logger.Error("配置验证失败：llm.api_key 缺失", "module", "config")
-		return nil, fmt.Errorf("llm.api_key 是必填项")
+	if err := cfg.LLM.Validate(); err != nil {
+		logger.Error("配置验证失败", "module", "config", "error", err)
+		return nil, err
 	}
 
 	logger.Info("配置加载完成", "module", "config", "serverPort", cfg.ServerPort)
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"os"
 
 	"github.com/spf13/viper"
 	"github.com/xumoe-c/maiecho/server/internal/logger"
@@ -9,15 +12,19 @@ import (
 
 type PromptConfig struct {
 	Agent AgentPrompts `mapstructure:"agent"`
+
+	// Version 提示词文件内容的哈希，文件修改后变化 (用于使 LLM 响应缓存失效)
+	Version string `mapstructure:"-"`
 }
 
 type AgentPrompts struct {
-	Cleaner   PromptPair       `mapstructure:"cleaner"`
-	Analyst   PromptPair       `mapstructure:"analyst"`
-	Advisor   PromptPair       `mapstructure:"advisor"`
-	Mapper    MapperPrompts    `mapstructure:"mapper"`
-	Knowledge KnowledgePrompts `mapstructure:"knowledge"`
-	Relevance RelevancePrompts `mapstructure:"relevance"`
+	Cleaner      PromptPair       `mapstructure:"cleaner"`
+	Analyst      PromptPair       `mapstructure:"analyst"`
+	Advisor      PromptPair       `mapstructure:"advisor"`
+	ChartAdvisor PromptPair       `mapstructure:"chart_advisor"` // 单个谱面的顾问，额外接收谱面数据
+	Mapper       MapperPrompts    `mapstructure:"mapper"`
+	Knowledge    KnowledgePrompts `mapstructure:"knowledge"`
+	Relevance    RelevancePrompts `mapstructure:"relevance"`
 }
 
 type RelevancePrompts struct {
@@ -56,6 +63,14 @@ func LoadPrompts() (*PromptConfig, error) {
 		return nil, fmt.Errorf("解析提示词失败: %w", err)
 	}
 
-	logger.Info("提示词加载成功", "module", "config.prompt")
+	content, err := os.ReadFile(v.ConfigFileUsed())
+	if err != nil {
+		logger.Error("读取提示词文件失败", "module", "config.prompt", "error", err)
+		return nil, fmt.Errorf("读取提示词文件失败: %w", err)
+	}
+	sum := sha256.Sum256(content)
+	cfg.Version = hex.EncodeToString(sum[:8])
+
+	logger.Info("提示词加载成功", "module", "config.prompt", "version", cfg.Version)
 	return &cfg, nil
 }
@@ -1,7 +1,7 @@
 package controller
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -9,6 +9,7 @@ import (
 	"github.com/xumoe-c/maiecho/server/internal/logger"
 	"github.com/xumoe-c/maiecho/server/internal/model"
 	"github.com/xumoe-c/maiecho/server/internal/service"
+	"gorm.io/gorm"
 )
 
 type AnalysisController struct {
@@ -21,13 +22,14 @@ func NewAnalysisController(s *service.AnalysisService) *AnalysisController {
 
 // AnalyzeSong 分析歌曲
 // @Summary 分析歌曲
-// @Description 触发针对指定歌曲ID(GameID)的LLM分析流程
+// @Description 异步触发针对指定歌曲ID(GameID)的LLM分析流程，立即返回作业ID。可通过 /jobs/{id} 轮询或 /jobs/{id}/events 订阅进度
 // @Tags analysis
 // @Accept json
 // @Produce json
 // @Param id path int true "Game ID"
-// @Success 200 {object} map[string]string
+// @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /analysis/songs/{id} [post]
 func (c *AnalysisController) AnalyzeSong(ctx *gin.Context) {
@@ -39,19 +41,19 @@ func (c *AnalysisController) AnalyzeSong(ctx *gin.Context) {
 		return
 	}
 
-	logger.Info("开始分析歌曲", "module", "controller.analysis", "gameID", id)
-
-	// 运行在后台还是阻塞？
-	// 目前为了立即看到结果，采用阻塞方式，但LLM响应较慢。
-	// 为了MVP的简单性，暂时采用阻塞方式。
-	if err := c.service.AnalyzeSongByGameID(ctx.Request.Context(), id); err != nil {
-		logger.Error("歌曲分析失败", "module", "controller.analysis", "gameID", id, "error", err)
+	job, err := c.service.StartAnalysis(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "未找到对应的歌曲"})
+			return
+		}
+		logger.Error("创建分析作业失败", "module", "controller.analysis", "gameID", id, "error", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	logger.Info("歌曲分析完成", "module", "controller.analysis", "gameID", id)
-	ctx.JSON(http.StatusOK, gin.H{"message": "分析完成"})
+	logger.Info("歌曲分析任务已创建", "module", "controller.analysis", "gameID", id, "jobID", job.ID)
+	ctx.JSON(http.StatusOK, gin.H{"message": "分析任务已开始", "job_id": job.ID})
 }
 
 type BatchAnalysisRequest struct {
@@ -60,12 +62,12 @@ type BatchAnalysisRequest struct {
 
 // BatchAnalyzeSongs 批量分析歌曲
 // @Summary 批量分析歌曲
-// @Description 触发针对多个歌曲ID(GameID)的LLM分析流程
+// @Description 触发针对多个歌曲ID(GameID)的LLM分析流程，返回可用于查询进度的作业ID
 // @Tags analysis
 // @Accept json
 // @Produce json
 // @Param request body BatchAnalysisRequest true "Batch Analysis Request"
-// @Success 200 {object} map[string]string
+// @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /analysis/batch [post]
@@ -77,21 +79,15 @@ func (c *AnalysisController) BatchAnalyzeSongs(ctx *gin.Context) {
 		return
 	}
 
-	logger.Info("启动批量分析任务", "module", "controller.analysis", "count", len(req.GameIDs))
-
-	// 简单的循环调用，实际生产中应放入消息队列
-	go func() {
-		for _, id := range req.GameIDs {
-			// 使用新的 context，因为请求 context 会在请求结束时取消
-			if err := c.service.AnalyzeSongByGameID(context.Background(), id); err != nil {
-				logger.Error("批量分析中单个歌曲分析失败", "module", "controller.analysis", "gameID", id, "error", err)
-				continue
-			}
-		}
-		logger.Info("批量分析任务完成", "module", "controller.analysis", "count", len(req.GameIDs))
-	}()
+	job, err := c.service.StartBatchAnalysis(req.GameIDs)
+	if err != nil {
+		logger.Error("创建批量分析作业失败", "module", "controller.analysis", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "批量分析任务已在后台启动"})
+	logger.Info("启动批量分析任务", "module", "controller.analysis", "jobID", job.ID, "count", len(req.GameIDs))
+	ctx.JSON(http.StatusOK, gin.H{"message": "批量分析任务已在后台启动", "job_id": job.ID})
 }
 
 // AnalysisResponse 聚合了歌曲和谱面的分析结果
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/service"
+	"gorm.io/gorm"
+)
+
+type ChartController struct {
+	Service service.ChartService
+}
+
+func NewChartController(s service.ChartService) *ChartController {
+	return &ChartController{Service: s}
+}
+
+// ListChartChanges 获取定数变更列表
+// @Summary 获取定数变更列表
+// @Description 按检测时间倒序列出同步时发现的谱面定数 (DS) 或等级变更
+// @Tags charts
+// @Produce  json
+// @Param   since     query     string  false  "只返回该时间之后的变更 (RFC3339 或 2006-01-02)"
+// @Param   song_id   query     int     false  "歌曲ID (数据库ID)"
+// @Param   page      query     int     false  "页码"
+// @Param   page_size query     int     false  "每页数量"
+// @Success 200 {object} model.ChartChangeListResponse
+// @Failure 400 {object} map[string]string
+// @Router /charts/changes [get]
+func (c *ChartController) ListChartChanges(ctx *gin.Context) {
+	var filter model.ChartChangeFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		logger.Warn("获取定数变更失败:查询参数绑定错误", "module", "controller.chart", "error", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if sinceStr := ctx.Query("since"); sinceStr != "" {
+		since, err := parseSince(sinceStr)
+		if err != nil {
+			logger.Warn("获取定数变更失败:since参数无效", "module", "controller.chart", "since", sinceStr, "error", err)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的since参数，应为 RFC3339 时间或 YYYY-MM-DD 日期"})
+			return
+		}
+		filter.Since = since
+	}
+
+	// 设置默认分页
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	result, err := c.Service.GetChartChanges(filter)
+	if err != nil {
+		logger.Error("获取定数变更失败:数据库查询错误", "module", "controller.chart", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// SearchCharts 按分析结果搜索谱面
+// @Summary 按分析结果搜索谱面
+// @Description 按谱面数据与每个谱面最新一次的分析结果 (没有谱面结果时使用所属歌曲的总览结果) 搜索谱面，按定数倒序排列。pattern 与 tag 按子串匹配，可重复传入，需同时满足
+// @Tags charts
+// @Produce  json
+// @Param   difficulty query     string   false  "难度 (Basic, Advanced, Expert, Master, Re:Master)"
+// @Param   level      query     string   false  "等级 (如 13+)"
+// @Param   min_ds     query     number   false  "最小定数"
+// @Param   max_ds     query     number   false  "最大定数"
+// @Param   type       query     string   false  "谱面类型 (DX, SD)"
+// @Param   pattern    query     []string false  "谱面配置标签 (如 转圈)，可重复" collectionFormat(multi)
+// @Param   tag        query     []string false  "难度描述符标签 (如 逆诈称)，可重复" collectionFormat(multi)
+// @Param   sentiment  query     string   false  "情感倾向 (Positive, Neutral, Negative)"
+// @Param   min_delta  query     number   false  "拟合定数与定数之差 (fit_diff - ds) 的下限"
+// @Param   max_delta  query     number   false  "拟合定数与定数之差 (fit_diff - ds) 的上限"
+// @Param   page       query     int      false  "页码"
+// @Param   page_size  query     int      false  "每页数量"
+// @Success 200 {object} model.ChartSearchResponse
+// @Failure 400 {object} map[string]string
+// @Router /charts/search [get]
+func (c *ChartController) SearchCharts(ctx *gin.Context) {
+	var filter model.ChartSearchFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		logger.Warn("搜索谱面失败:查询参数绑定错误", "module", "controller.chart", "error", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch filter.Sentiment {
+	case "", "Positive", "Neutral", "Negative":
+	default:
+		logger.Warn("搜索谱面失败:sentiment参数无效", "module", "controller.chart", "sentiment", filter.Sentiment)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的sentiment参数，应为 Positive、Neutral 或 Negative"})
+		return
+	}
+
+	// 设置默认分页
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	result, err := c.Service.SearchCharts(filter)
+	if err != nil {
+		logger.Error("搜索谱面失败:数据库查询错误", "module", "controller.chart", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// parseSince 解析 RFC3339 时间或日期 (按本地时区的零点)
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+// GetChartTimeline 获取谱面历史
+// @Summary 获取谱面历史
+// @Description 获取单个谱面的定数变更事件与每次同步记录的数据快照 (定数、拟合难度、平均达成率等)，均按时间正序排列
+// @Tags charts
+// @Produce  json
+// @Param   id   path      int  true  "Chart ID (数据库ID)"
+// @Success 200 {object} model.ChartTimeline
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /charts/{id}/timeline [get]
+func (c *ChartController) GetChartTimeline(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Warn("获取谱面历史失败:ID参数无效", "module", "controller.chart", "idStr", idStr, "error", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的谱面ID"})
+		return
+	}
+
+	timeline, err := c.Service.GetChartTimeline(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "未找到对应的谱面"})
+			return
+		}
+		logger.Error("获取谱面历史失败", "module", "controller.chart", "chartID", id, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, timeline)
+}
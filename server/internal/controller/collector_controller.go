@@ -1,13 +1,14 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/xumoe-c/maiecho/server/internal/logger"
 	"github.com/xumoe-c/maiecho/server/internal/service"
+	"github.com/xumoe-c/maiecho/server/internal/status"
 )
 
 type CollectorController struct {
@@ -21,16 +22,17 @@ func NewCollectorController(s service.CollectorService) *CollectorController {
 type CollectRequest struct {
 	Keyword string `json:"keyword"`
 	GameID  int    `json:"game_id"`
+	Source  string `json:"source"` // 可选：只使用指定名称的采集器
 }
 
 // TriggerCollection 触发数据收集任务
 // @Summary 触发数据收集任务
-// @Description 针对特定关键词或GameID启动数据收集任务
+// @Description 针对特定关键词或GameID启动数据收集任务，返回可用于查询进度的作业ID。可通过 source 指定只使用某个采集器
 // @Tags collector
 // @Accept  json
 // @Produce  json
 // @Param   request  body      CollectRequest  true  "Collection Request"
-// @Success 200 {object} map[string]string
+// @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Router /collect [post]
 func (c *CollectorController) TriggerCollection(ctx *gin.Context) {
@@ -86,19 +88,16 @@ func (c *CollectorController) TriggerCollection(ctx *gin.Context) {
 			}
 		}
 
-		// 触发所有关键词的收集
-		go func() {
-			for _, kw := range keywords {
-				if err := c.Service.TriggerCollection(kw, &song.ID); err != nil {
-					logger.Error("触发关键词收集失败", "module", "controller.collector", "keyword", kw, "gameID", req.GameID, "error", err)
-				}
-				// 避免并发过高
-				time.Sleep(2 * time.Second)
-			}
-			logger.Info("基于GameID的收集任务已完成", "module", "controller.collector", "gameID", req.GameID, "keywordCount", len(keywords))
-		}()
+		// 触发所有关键词的收集，任务进入持久化队列由调度器按节奏执行
+		job, err := c.Service.TriggerCollection(keywords, &song.ID, req.Source)
+		if err != nil {
+			logger.Error("触发关键词收集失败", "module", "controller.collector", "gameID", req.GameID, "error", err)
+			ctx.JSON(collectErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
 
-		ctx.JSON(http.StatusOK, gin.H{"message": "基于GameID的数据收集任务已启动", "keywords": keywords})
+		logger.Info("基于GameID的收集作业已创建", "module", "controller.collector", "gameID", req.GameID, "jobID", job.ID, "keywordCount", len(keywords))
+		ctx.JSON(http.StatusOK, gin.H{"message": "基于GameID的数据收集任务已启动", "job_id": job.ID, "keywords": keywords})
 		return
 	}
 
@@ -111,12 +110,21 @@ func (c *CollectorController) TriggerCollection(ctx *gin.Context) {
 	// TODO:目前只是一个简单的做法，未来可以改进为更智能的关键词处理
 	searchKeyword := req.Keyword + " 舞萌 maimai 手元 谱面确认"
 
-	if err := c.Service.TriggerCollection(searchKeyword, nil); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	job, err := c.Service.TriggerCollection([]string{searchKeyword}, nil, req.Source)
+	if err != nil {
+		ctx.JSON(collectErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "数据收集任务已启动", "keyword": searchKeyword})
+	ctx.JSON(http.StatusOK, gin.H{"message": "数据收集任务已启动", "job_id": job.ID, "keyword": searchKeyword})
+}
+
+// collectErrorStatus 指定了未启用的采集器属于请求错误，其余为服务端错误
+func collectErrorStatus(err error) int {
+	if errors.Is(err, service.ErrUnknownSource) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
 }
 
 // BackfillCollection 触发回填数据收集
@@ -124,15 +132,61 @@ func (c *CollectorController) TriggerCollection(ctx *gin.Context) {
 // @Description 用于初始化数据源，为无数据的歌曲收集评论数据
 // @Tags collector
 // @Produce  json
-// @Success 200 {object} map[string]string
+// @Success 200 {object} map[string]interface{}
 // @Failure 500 {object} map[string]string
 // @Router /collect/backfill [post]
 func (c *CollectorController) BackfillCollection(ctx *gin.Context) {
-	if err := c.Service.BackfillCollection(); err != nil {
+	job, err := c.Service.BackfillCollection()
+	if err != nil {
 		logger.Error("回填数据收集任务失败", "module", "controller.collector", "error", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	logger.Info("回填数据收集任务已启动", "module", "controller.collector")
-	ctx.JSON(http.StatusOK, gin.H{"message": "回填数据收集任务已排队"})
+	logger.Info("回填数据收集任务已启动", "module", "controller.collector", "jobID", job.ID, "total", job.Total)
+	ctx.JSON(http.StatusOK, gin.H{"message": "回填数据收集任务已排队", "job_id": job.ID, "total": job.Total})
+}
+
+// CollectorListResponse 采集器状态列表
+type CollectorListResponse struct {
+	Items []status.CollectorStatus `json:"items"`
+}
+
+// ListCollectors 获取采集器状态
+// @Summary 获取采集器状态
+// @Description 列出所有采集器及其封禁熔断状态 (closed/open/half_open)、连续封禁次数与冷却结束时间
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} CollectorListResponse
+// @Router /admin/collectors [get]
+func (c *CollectorController) ListCollectors(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, CollectorListResponse{Items: c.Service.GetCollectorStatuses()})
+}
+
+// ResetCollectorBan 重置采集器封禁状态
+// @Summary 重置采集器封禁状态
+// @Description 手动解除指定采集器的封禁冷却，立即恢复采集
+// @Tags admin
+// @Produce  json
+// @Param   name path      string  true  "采集器名称 (例如 bilibili)"
+// @Success 200 {object} status.CollectorStatus
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/collectors/{name}/reset [post]
+func (c *CollectorController) ResetCollectorBan(ctx *gin.Context) {
+	name := ctx.Param("name")
+	cs, err := c.Service.ResetCollectorBan(name)
+	if err != nil {
+		logger.Warn("重置采集器封禁状态失败", "module", "controller.collector", "collector", name, "error", err)
+		switch {
+		case errors.Is(err, service.ErrCollectorNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "未找到对应的采集器"})
+		case errors.Is(err, service.ErrCollectorNotBanAware):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "该采集器不支持封禁状态管理"})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, cs)
 }
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/service"
+)
+
+// maxCommentPageSize 评论搜索单页的最大条数
+const maxCommentPageSize = 100
+
+type CommentController struct {
+	Service service.CommentService
+}
+
+func NewCommentController(s service.CommentService) *CommentController {
+	return &CommentController{Service: s}
+}
+
+// SearchComments 搜索评论
+// @Summary 搜索评论
+// @Description 在评论内容和来源标题中搜索关键词，多个词以空格分隔且需同时匹配。启用全文索引时按相关度 (score) 排序
+// @Tags comments
+// @Produce  json
+// @Param   q         query     string  true   "搜索词"
+// @Param   song_id   query     int     false  "歌曲ID (数据库ID)"
+// @Param   source    query     string  false  "来源 (Bilibili/Tieba)"
+// @Param   page      query     int     false  "页码"
+// @Param   page_size query     int     false  "每页数量 (最大 100)"
+// @Success 200 {object} model.CommentSearchResponse
+// @Failure 400 {object} map[string]string
+// @Router /comments/search [get]
+func (c *CommentController) SearchComments(ctx *gin.Context) {
+	var filter model.CommentSearchFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		logger.Warn("搜索评论失败:查询参数绑定错误", "module", "controller.comment", "error", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 设置默认分页
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	if filter.PageSize > maxCommentPageSize {
+		filter.PageSize = maxCommentPageSize
+	}
+
+	result, err := c.Service.SearchComments(filter)
+	if err != nil {
+		logger.Error("搜索评论失败:数据库查询错误", "module", "controller.comment", "query", filter.Query, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("评论搜索完成", "module", "controller.comment", "query", filter.Query, "total", result.Total)
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,256 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/progress"
+	"github.com/xumoe-c/maiecho/server/internal/service"
+	"gorm.io/gorm"
+)
+
+type JobController struct {
+	Service service.JobService
+}
+
+func NewJobController(s service.JobService) *JobController {
+	return &JobController{Service: s}
+}
+
+// ListJobs 获取作业列表
+// @Summary 获取作业列表
+// @Description 按创建时间倒序列出采集与分析作业，支持按类型和状态筛选
+// @Tags jobs
+// @Produce  json
+// @Param   type      query     string  false  "作业类型 (collect/backfill/analysis)"
+// @Param   status    query     string  false  "作业状态 (pending/running/done/failed)"
+// @Param   page      query     int     false  "页码"
+// @Param   page_size query     int     false  "每页数量"
+// @Success 200 {object} model.JobListResponse
+// @Failure 400 {object} map[string]string
+// @Router /jobs [get]
+func (c *JobController) ListJobs(ctx *gin.Context) {
+	var filter model.JobFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		logger.Warn("获取作业列表失败:查询参数绑定错误", "module", "controller.job", "error", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 设置默认分页
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	result, err := c.Service.GetJobs(filter)
+	if err != nil {
+		logger.Error("获取作业列表失败:数据库查询错误", "module", "controller.job", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// GetJob 获取作业详情
+// @Summary 获取作业详情
+// @Description 获取作业的状态、进度计数、各子项的错误信息与耗时
+// @Tags jobs
+// @Produce  json
+// @Param   id   path      int  true  "Job ID"
+// @Success 200 {object} model.Job
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /jobs/{id} [get]
+func (c *JobController) GetJob(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Warn("获取作业失败:ID参数无效", "module", "controller.job", "idStr", idStr, "error", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的作业ID"})
+		return
+	}
+
+	job, err := c.Service.GetJob(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "未找到对应的作业"})
+			return
+		}
+		logger.Error("获取作业失败", "module", "controller.job", "jobID", id, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
+
+// heartbeatInterval SSE 心跳间隔，防止代理断开空闲连接
+const heartbeatInterval = 15 * time.Second
+
+// StreamJobEvents 订阅作业进度事件
+// @Summary 订阅作业进度事件 (SSE)
+// @Description 以 Server-Sent Events 推送作业的阶段事件 (started, comments_loaded, bucketed, chart_analyzed, advisor_done, saved, song_failed, completed, failed)。连接建立后先回放已发生的事件，作业结束后服务端关闭连接
+// @Tags jobs
+// @Produce  text/event-stream
+// @Param   id   path      int  true  "Job ID"
+// @Success 200 {object} progress.Event
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /jobs/{id}/events [get]
+func (c *JobController) StreamJobEvents(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Warn("订阅作业事件失败:ID参数无效", "module", "controller.job", "idStr", idStr, "error", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的作业ID"})
+		return
+	}
+
+	// 先订阅再读取作业状态，避免两者之间作业结束导致终止事件丢失
+	history, events, cancel := c.Service.SubscribeEvents(uint(id))
+	defer cancel()
+
+	job, ok := c.loadJob(ctx, uint(id))
+	if !ok {
+		return
+	}
+	c.streamEvents(ctx, job, history, events)
+}
+
+// StreamJobSummary 订阅顾问摘要的流式输出
+// @Summary 订阅顾问摘要的流式输出 (SSE)
+// @Description 以 Server-Sent Events 推送分析作业中顾问报告摘要的生成过程：advisor_delta (增量文本 delta 与目前为止的完整文本 text)、advisor_summary (该目标的最终摘要，以此为准)。每首歌曲的每个谱面及歌曲总览各生成一次摘要，以 data.target_type / data.target_id 区分。连接建立后先回放已发生的事件，作业结束时推送 completed 或 failed 并关闭连接
+// @Tags jobs
+// @Produce  text/event-stream
+// @Param   id   path      int  true  "Job ID"
+// @Success 200 {object} progress.Event
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /jobs/{id}/summary [get]
+func (c *JobController) StreamJobSummary(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		logger.Warn("订阅顾问摘要失败:ID参数无效", "module", "controller.job", "idStr", idStr, "error", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的作业ID"})
+		return
+	}
+
+	// 先订阅再读取作业状态，避免两者之间作业结束导致终止事件丢失
+	history, events, cancel := c.Service.SubscribeSummary(uint(id))
+	defer cancel()
+
+	job, ok := c.loadJob(ctx, uint(id))
+	if !ok {
+		return
+	}
+	if job.Type != model.JobTypeAnalysis {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "只有分析作业提供顾问摘要"})
+		return
+	}
+	c.streamEvents(ctx, job, history, events)
+}
+
+// loadJob 读取作业，失败时写入 404 或 500 响应
+func (c *JobController) loadJob(ctx *gin.Context, id uint) (*model.Job, bool) {
+	job, err := c.Service.GetJob(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "未找到对应的作业"})
+			return nil, false
+		}
+		logger.Error("获取作业失败", "module", "controller.job", "jobID", id, "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return job, true
+}
+
+// streamEvents 以 SSE 推送历史事件与实时事件，直到作业结束或客户端断开
+func (c *JobController) streamEvents(ctx *gin.Context, job *model.Job, history []progress.Event, events <-chan progress.Event) {
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	for _, e := range history {
+		ctx.SSEvent(e.Stage, e)
+		if e.IsTerminal() {
+			ctx.Writer.Flush()
+			return
+		}
+	}
+	ctx.Writer.Flush()
+
+	// 作业已结束但内存中没有终止事件 (如服务重启或事件已过期)，根据数据库状态补发
+	if events == nil || isJobFinished(job) {
+		c.sendTerminalEvent(ctx, job)
+		return
+	}
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case e, ok := <-events:
+			if !ok {
+				// 订阅在收到终止事件之前关闭 (终止事件因消费过慢被丢弃)，根据数据库状态补发
+				if latest, err := c.Service.GetJob(job.ID); err == nil {
+					c.sendTerminalEvent(ctx, latest)
+				}
+				return false
+			}
+			ctx.SSEvent(e.Stage, e)
+			return !e.IsTerminal()
+		case <-ticker.C:
+			// 采集类作业不产生阶段事件，心跳时顺便检查作业是否已结束
+			if latest, err := c.Service.GetJob(job.ID); err == nil && isJobFinished(latest) {
+				c.sendTerminalEvent(ctx, latest)
+				return false
+			}
+			ctx.SSEvent("ping", time.Now().Unix())
+			return true
+		}
+	})
+}
+
+func (c *JobController) sendTerminalEvent(ctx *gin.Context, job *model.Job) {
+	if !isJobFinished(job) {
+		return
+	}
+	stage := progress.StageCompleted
+	if job.Status == model.JobStatusFailed {
+		stage = progress.StageFailed
+	}
+	ctx.SSEvent(stage, progress.Event{
+		JobID: job.ID,
+		Stage: stage,
+		Data: map[string]interface{}{
+			"total":     job.Total,
+			"succeeded": job.Succeeded,
+			"failed":    job.Failed,
+		},
+		Time: job.UpdatedAt,
+	})
+	ctx.Writer.Flush()
+}
+
+func isJobFinished(job *model.Job) bool {
+	return job.Status == model.JobStatusDone || job.Status == model.JobStatusFailed
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/service"
+)
+
+// defaultUsageDays 未指定 from 时统计的天数 (含 to 当天)
+const defaultUsageDays = 7
+
+type UsageController struct {
+	Service service.UsageService
+}
+
+func NewUsageController(s service.UsageService) *UsageController {
+	return &UsageController{Service: s}
+}
+
+// GetLLMUsage 获取LLM用量统计
+// @Summary 获取LLM用量统计
+// @Description 按本地日期和 Agent 角色汇总 LLM 调用次数、token 数与估算费用，并返回今日的预算状态。默认统计最近 7 天
+// @Tags llm
+// @Produce  json
+// @Param   from    query     string  false  "起始日期 YYYY-MM-DD (含)，默认为 to 之前 6 天"
+// @Param   to      query     string  false  "截止日期 YYYY-MM-DD (含)，默认为今天"
+// @Param   role    query     string  false  "Agent 角色 (cleaner, analyst, advisor, mapper, relevance)"
+// @Param   song_id query     int     false  "歌曲ID (数据库ID)"
+// @Param   job_id  query     int     false  "作业ID"
+// @Success 200 {object} model.LLMUsageResponse
+// @Failure 400 {object} map[string]string
+// @Router /llm/usage [get]
+func (c *UsageController) GetLLMUsage(ctx *gin.Context) {
+	var filter model.LLMUsageFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		logger.Warn("获取LLM用量失败:查询参数绑定错误", "module", "controller.usage", "error", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 设置默认时间范围
+	to := time.Now()
+	if filter.To != "" {
+		t, err := time.ParseInLocation("2006-01-02", filter.To, time.Local)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的to参数，应为 YYYY-MM-DD 日期"})
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, 1-defaultUsageDays)
+	if filter.From != "" {
+		t, err := time.ParseInLocation("2006-01-02", filter.From, time.Local)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的from参数，应为 YYYY-MM-DD 日期"})
+			return
+		}
+		from = t
+	}
+	filter.From = from.Format("2006-01-02")
+	filter.To = to.Format("2006-01-02")
+	if filter.From > filter.To {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "from 不能晚于 to"})
+		return
+	}
+
+	result, err := c.Service.GetUsage(filter)
+	if err != nil {
+		logger.Error("获取LLM用量失败:数据库查询错误", "module", "controller.usage", "error", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Cache 按内容寻址的响应缓存，键由路由的模型、系统提示词和用户提示词计算
+// 实现负责过期与失效，读写失败时应表现为未命中，不影响调用
+type Cache interface {
+	Get(key string) (response string, ok bool)
+	Set(key, model, response string)
+	Delete(key string)
+}
+
+// SetCache 设置响应缓存，需在发起请求之前调用
+func (c *Client) SetCache(cache Cache) {
+	c.shared.cache = cache
+}
+
+// ChatCached 与 Chat 相同，但相同的模型与提示词直接返回缓存的响应 (不发起请求，也不计入用量)
+// 只用于输出由输入决定的调用 (匹配确认、相关性判断、清洗等)；调用方无法使用缓存的响应时应调用 Forget
+func (c *Client) ChatCached(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	cache := c.shared.cache
+	if cache == nil {
+		return c.Chat(ctx, systemPrompt, userPrompt)
+	}
+
+	key := c.cacheKey(systemPrompt, userPrompt)
+	if response, ok := cache.Get(key); ok {
+		return response, nil
+	}
+	response, err := c.Chat(ctx, systemPrompt, userPrompt)
+	if err != nil {
+		return "", err
+	}
+	cache.Set(key, c.providers[0].model, response)
+	return response, nil
+}
+
+// Forget 删除提示词对应的缓存响应，例如响应无法解析时避免之后一直命中
+func (c *Client) Forget(systemPrompt, userPrompt string) {
+	if c.shared.cache != nil {
+		c.shared.cache.Delete(c.cacheKey(systemPrompt, userPrompt))
+	}
+}
+
+// cacheKey 计算缓存键：路由中全部模型 (故障转移顺序) + 系统提示词 + 用户提示词的 SHA-256
+func (c *Client) cacheKey(systemPrompt, userPrompt string) string {
+	h := sha256.New()
+	for _, p := range c.providers {
+		h.Write([]byte(p.model))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(systemPrompt))
+	h.Write([]byte{0})
+	h.Write([]byte(userPrompt))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+)
+
+// mapCache 基于内存的缓存
+type mapCache map[string]string
+
+func (c mapCache) Get(key string) (string, bool)   { v, ok := c[key]; return v, ok }
+func (c mapCache) Set(key, model, response string) { c[key] = response }
+func (c mapCache) Delete(key string)               { delete(c, key) }
+
+func TestChatCached(t *testing.T) {
+	client, calls := newTestClient(t, config.LLMProviderConfig{}, reply(200, completionBody))
+	cache := mapCache{}
+	client.SetCache(cache)
+
+	var recorded int
+	client.SetUsageRecorder(usageRecorderFunc(func(Usage) { recorded++ }))
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if got, err := client.ChatCached(ctx, "system", "user"); err != nil || got != "ok" {
+			t.Fatalf("ChatCached() = %q, %v", got, err)
+		}
+	}
+	// 第二次命中缓存，不发起请求也不计入用量
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("calls = %d, want 1", n)
+	}
+	if recorded != 1 {
+		t.Errorf("recorded usage = %d, want 1", recorded)
+	}
+
+	// 提示词不同时不命中
+	if _, err := client.ChatCached(ctx, "system", "another user"); err != nil {
+		t.Fatalf("ChatCached() error = %v", err)
+	}
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+
+	// Forget 后重新请求
+	client.Forget("system", "user")
+	if _, err := client.ChatCached(ctx, "system", "user"); err != nil {
+		t.Fatalf("ChatCached() error = %v", err)
+	}
+	if n := atomic.LoadInt32(calls); n != 3 {
+		t.Errorf("calls = %d, want 3", n)
+	}
+
+	// Chat 不使用缓存
+	if _, err := client.Chat(ctx, "system", "user"); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if n := atomic.LoadInt32(calls); n != 4 {
+		t.Errorf("calls = %d, want 4", n)
+	}
+	if len(cache) != 2 {
+		t.Errorf("cache entries = %d, want 2", len(cache))
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	a := &Client{providers: []*provider{{model: "qwen-plus"}}}
+	b := &Client{providers: []*provider{{model: "qwen-turbo"}}}
+	if a.cacheKey("s", "u") == b.cacheKey("s", "u") {
+		t.Error("cacheKey() equal for different models")
+	}
+	// 分隔符避免拼接歧义
+	if a.cacheKey("ab", "c") == a.cacheKey("a", "bc") {
+		t.Error("cacheKey() equal for different prompt boundaries")
+	}
+}
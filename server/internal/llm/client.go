@@ -2,62 +2,129 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+	"sync/atomic"
 
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
 	"github.com/xumoe-c/maiecho/server/internal/config"
 	"github.com/xumoe-c/maiecho/server/internal/logger"
 )
 
+// ChatClient Agent 使用的 LLM 调用接口
+// *Client 为真实实现，离线测试中使用 llmtest.Fake 替代
+type ChatClient interface {
+	Chat(ctx context.Context, systemPrompt, userPrompt string) (string, error)
+	ChatCached(ctx context.Context, systemPrompt, userPrompt string) (string, error)
+	Forget(systemPrompt, userPrompt string)
+	ChatJSON(ctx context.Context, systemPrompt, userPrompt string, out any) error
+	ChatJSONCached(ctx context.Context, systemPrompt, userPrompt string, out any) error
+	ChatJSONStream(ctx context.Context, systemPrompt, userPrompt string, out any, onDelta func(delta string)) error
+}
+
+var _ ChatClient = (*Client)(nil)
+
+// Client 某个角色使用的 LLM 客户端
+// 按顺序尝试一组提供方：当前提供方重试耗尽或返回不可重试的错误时，自动切换到下一个提供方
 type Client struct {
-	client *openai.Client
-	model  string
+	role      string
+	providers []*provider
+	shared    *sharedState // 同一 Router 创建的客户端共享
 }
 
-func NewClient(cfg config.LLMConfig) *Client {
-	client := openai.NewClient(
-		option.WithAPIKey(cfg.APIKey),
-		option.WithBaseURL(cfg.BaseURL),
-	)
+// sharedState 客户端之间共享的计数、用量记录与响应缓存
+type sharedState struct {
+	inFlight int64         // 进行中的请求数
+	recorder UsageRecorder // 为 nil 时不记录用量
+	cache    Cache         // 为 nil 时 ChatCached 不使用缓存
+}
 
+// NewClient 创建只使用单个提供方的客户端
+func NewClient(cfg config.LLMProviderConfig) *Client {
 	return &Client{
-		client: &client,
-		model:  cfg.Model,
+		role:      RoleDefault,
+		providers: []*provider{newProvider(cfg)},
+		shared:    &sharedState{},
 	}
 }
 
-func (c *Client) Chat(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
-	chatCompletion, err := c.client.Chat.Completions.New(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Messages: []openai.ChatCompletionMessageParamUnion{
-				openai.SystemMessage(systemPrompt),
-				openai.UserMessage(userPrompt),
-			},
-			Model: c.model,
-		},
-	)
+// InFlight 返回当前进行中的 LLM 请求数
+func (c *Client) InFlight() int64 {
+	return atomic.LoadInt64(&c.shared.inFlight)
+}
 
-	if err != nil {
-		logger.Error("LLM请求失败", "module", "llm", "model", c.model, "error", err)
-		logger.LogLLMConversation(c.model, systemPrompt, userPrompt, "", err)
-		return "", fmt.Errorf("LLM请求失败: %w", err)
+// SetUsageRecorder 设置用量记录，需在发起请求之前调用
+func (c *Client) SetUsageRecorder(recorder UsageRecorder) {
+	c.shared.recorder = recorder
+}
+
+// Providers 按故障转移顺序返回提供方名称
+func (c *Client) Providers() []string {
+	names := make([]string, 0, len(c.providers))
+	for _, p := range c.providers {
+		names = append(names, p.name)
 	}
+	return names
+}
 
-	if len(chatCompletion.Choices) == 0 {
-		logger.Error("LLM未返回任何选项", "module", "llm", "model", c.model)
-		logger.LogLLMConversation(c.model, systemPrompt, userPrompt, "", fmt.Errorf("no choices returned"))
-		return "", fmt.Errorf("LLM未返回任何选项")
+// Chat 执行一次对话，成功时按角色及 ctx 中的歌曲、作业归属记录用量 (见 WithSongID / WithJobID)
+func (c *Client) Chat(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return c.chat(ctx, chatRequest{system: systemPrompt, user: userPrompt})
+}
+
+// ErrStreamInterrupted 流式输出已经开始后请求失败，不再重试或切换提供方
+var ErrStreamInterrupted = errors.New("LLM流式输出中断")
+
+// ChatStream 以流式方式执行对话，每收到一段输出调用一次 onDelta，返回完整输出
+// 输出开始之前的失败与 Chat 一样重试和故障转移；输出开始之后失败时返回 ErrStreamInterrupted，
+// 调用方应丢弃已收到的内容
+func (c *Client) ChatStream(ctx context.Context, systemPrompt, userPrompt string, onDelta func(delta string)) (string, error) {
+	return c.chat(ctx, chatRequest{system: systemPrompt, user: userPrompt, onDelta: onDelta})
+}
+
+// chat 按故障转移顺序依次尝试各提供方
+func (c *Client) chat(ctx context.Context, req chatRequest) (string, error) {
+	atomic.AddInt64(&c.shared.inFlight, 1)
+	defer atomic.AddInt64(&c.shared.inFlight, -1)
+
+	var err error
+	for i, p := range c.providers {
+		var response string
+		var usage Usage
+		response, usage, err = p.chat(ctx, req, i+1 < len(c.providers))
+		if err == nil {
+			if i > 0 {
+				logger.Info("LLM故障转移成功", "module", "llm", "role", c.role, "provider", p.name)
+			}
+			c.recordUsage(ctx, usage)
+			return response, nil
+		}
+		// 流式输出已经开始时不切换提供方，避免调用方收到重复的内容
+		if ctx.Err() != nil || errors.Is(err, ErrStreamInterrupted) {
+			break
+		}
+		if i+1 < len(c.providers) {
+			logger.Warn("LLM提供方请求失败，切换到下一个提供方", "module", "llm", "role", c.role, "provider", p.name, "next", c.providers[i+1].name, "error", err)
+		}
 	}
 
-	response := chatCompletion.Choices[0].Message.Content
-	logger.LogLLMConversation(c.model, systemPrompt, userPrompt, response, nil)
-	return response, nil
+	logger.Error("LLM请求失败", "module", "llm", "role", c.role, "providers", c.Providers(), "error", err)
+	return "", fmt.Errorf("LLM请求失败: %w", err)
 }
 
+func (c *Client) recordUsage(ctx context.Context, usage Usage) {
+	if c.shared.recorder == nil {
+		return
+	}
+	usage.Role = c.role
+	usage.SongID, usage.JobID = attribution(ctx)
+	c.shared.recorder.RecordUsage(usage)
+}
+
+// thinkingPattern 模型在 <thinking> 标签中输出的推理过程
+var thinkingPattern = regexp.MustCompile(`(?s)<thinking>(.*?)</thinking>`)
+
 // ChatWithReasoning 执行对话并分离推理过程 (<thinking>标签) 和最终内容
 func (c *Client) ChatWithReasoning(ctx context.Context, systemPrompt, userPrompt string) (content string, reasoning string, err error) {
 	fullResponse, err := c.Chat(ctx, systemPrompt, userPrompt)
@@ -67,13 +134,12 @@ func (c *Client) ChatWithReasoning(ctx context.Context, systemPrompt, userPrompt
 	}
 
 	// 提取 <thinking> 内容
-	re := regexp.MustCompile(`(?s)<thinking>(.*?)</thinking>`)
-	matches := re.FindStringSubmatch(fullResponse)
+	matches := thinkingPattern.FindStringSubmatch(fullResponse)
 
 	if len(matches) > 1 {
 		reasoning = strings.TrimSpace(matches[1])
 		// 从响应中移除 <thinking> 部分，只保留剩下的内容（通常是 JSON）
-		content = strings.TrimSpace(re.ReplaceAllString(fullResponse, ""))
+		content = strings.TrimSpace(thinkingPattern.ReplaceAllString(fullResponse, ""))
 		logger.Info("成功提取推理内容", "module", "llm", "reasoningLength", len(reasoning))
 	} else {
 		// 如果没有找到标签，假设全部是内容
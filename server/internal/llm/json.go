@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+)
+
+// ErrInvalidJSON 修复后的输出仍不符合要求的 JSON 结构
+var ErrInvalidJSON = errors.New("LLM输出不符合JSON结构")
+
+// jsonInstruction 追加在系统提示词之后，说明输出格式 (json_object 模式要求提示词中出现 "JSON")
+const jsonInstruction = "\n\n【输出格式】\n只输出一个符合以下 JSON Schema 的 JSON 对象，不要输出 Markdown 代码块或其他文字:\n"
+
+// repairInstruction 修复轮次的追加消息，%s 为逐条列出的问题
+const repairInstruction = "你上一次的输出不符合要求:\n%s\n请修正以上问题，重新输出完整的 JSON 对象，只输出 JSON 本身。"
+
+// ChatJSON 请求结构化输出并解析到 out (指向结构体的指针)
+// 根据 out 的类型生成 JSON Schema，按提供方的 json_mode 设置 response_format，并将 Schema 附在系统提示词之后。
+// 输出不是有效 JSON 或不符合 Schema 时，将问题反馈给模型并自动修复一次，仍不符合时返回 ErrInvalidJSON
+func (c *Client) ChatJSON(ctx context.Context, systemPrompt, userPrompt string, out any) error {
+	return c.chatJSON(ctx, systemPrompt, userPrompt, out, false, nil)
+}
+
+// ChatJSONCached 与 ChatJSON 相同，但相同的模型与提示词直接使用缓存的输出 (见 ChatCached)
+// 只缓存通过校验的输出，缓存的输出不再符合结构 (例如结构体已修改) 时重新请求
+func (c *Client) ChatJSONCached(ctx context.Context, systemPrompt, userPrompt string, out any) error {
+	return c.chatJSON(ctx, systemPrompt, userPrompt, out, true, nil)
+}
+
+// ChatJSONStream 与 ChatJSON 相同，但以流式方式请求，onDelta 接收模型输出的原始片段 (JSON 文本)
+// 需要修复时，修复请求不再流式输出；调用方应以返回后 out 中的内容为准
+func (c *Client) ChatJSONStream(ctx context.Context, systemPrompt, userPrompt string, out any, onDelta func(delta string)) error {
+	return c.chatJSON(ctx, systemPrompt, userPrompt, out, false, onDelta)
+}
+
+func (c *Client) chatJSON(ctx context.Context, systemPrompt, userPrompt string, out any, cached bool, onDelta func(string)) error {
+	schema, err := SchemaFor(out)
+	if err != nil {
+		return err
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	req := chatRequest{
+		system: systemPrompt + jsonInstruction + string(schemaJSON),
+		user:   userPrompt,
+		schema: schema,
+	}
+
+	first := req
+	first.onDelta = onDelta
+
+	cache := c.shared.cache
+	var key string
+	if cached && cache != nil {
+		key = c.cacheKey(req.system, req.user)
+		if response, ok := cache.Get(key); ok {
+			if content, problems := checkJSON(response, schema); len(problems) == 0 && json.Unmarshal([]byte(content), out) == nil {
+				return nil
+			}
+			cache.Delete(key)
+		}
+	}
+
+	response, err := c.chat(ctx, first)
+	if err != nil {
+		return err
+	}
+	content, problems := checkJSON(response, schema)
+	if len(problems) > 0 {
+		logger.Warn("LLM输出不符合JSON结构，尝试修复", "module", "llm", "role", c.role, "schema", schema.Name, "problems", problems)
+		req.previous = response
+		req.feedback = fmt.Sprintf(repairInstruction, "- "+strings.Join(problems, "\n- "))
+		response, err = c.chat(ctx, req)
+		if err != nil {
+			return fmt.Errorf("修复JSON输出失败: %w", err)
+		}
+		content, problems = checkJSON(response, schema)
+		if len(problems) > 0 {
+			logger.Error("LLM输出修复后仍不符合JSON结构", "module", "llm", "role", c.role, "schema", schema.Name, "problems", problems, "response", response)
+			return fmt.Errorf("%w (%s): %s", ErrInvalidJSON, schema.Name, strings.Join(problems, "; "))
+		}
+	}
+
+	if err := json.Unmarshal([]byte(content), out); err != nil {
+		return fmt.Errorf("%w (%s): %v", ErrInvalidJSON, schema.Name, err)
+	}
+	if key != "" {
+		cache.Set(key, c.providers[0].model, content)
+	}
+	return nil
+}
+
+// DecodeJSON 按 out 的结构校验响应并解析 (不请求修复)，供 ChatClient 的其他实现复用
+func DecodeJSON(response string, out any) error {
+	schema, err := SchemaFor(out)
+	if err != nil {
+		return err
+	}
+	content, problems := checkJSON(response, schema)
+	if len(problems) > 0 {
+		return fmt.Errorf("%w (%s): %s", ErrInvalidJSON, schema.Name, strings.Join(problems, "; "))
+	}
+	if err := json.Unmarshal([]byte(content), out); err != nil {
+		return fmt.Errorf("%w (%s): %v", ErrInvalidJSON, schema.Name, err)
+	}
+	return nil
+}
+
+// checkJSON 取出响应中的 JSON 并按结构校验，返回 JSON 文本与发现的问题
+func checkJSON(response string, schema *Schema) (string, []string) {
+	content := extractJSON(response)
+	if content == "" {
+		return "", []string{"响应中没有 JSON 对象"}
+	}
+	return content, schema.Validate([]byte(content))
+}
+
+// extractJSON 去掉 <thinking> 推理、Markdown 代码块及 JSON 前后的说明文字
+func extractJSON(response string) string {
+	s := thinkingPattern.ReplaceAllString(response, "")
+	start := strings.IndexAny(s, "{[")
+	end := strings.LastIndexAny(s, "}]")
+	if start < 0 || end < start {
+		return ""
+	}
+	return s[start : end+1]
+}
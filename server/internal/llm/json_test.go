@@ -0,0 +1,264 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+)
+
+type testVerdict struct {
+	Score float64 `json:"score"`
+	Label string  `json:"label" enum:"good,bad"`
+}
+
+type testOutput struct {
+	Reasoning string `json:"reasoning" desc:"思考过程"`
+	testVerdict
+	Tags  []string `json:"tags"`
+	Count int      `json:"count,omitempty"`
+	Note  *string  `json:"note,omitempty"`
+	skip  string
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema, err := SchemaFor(&testOutput{})
+	if err != nil {
+		t.Fatalf("SchemaFor() error = %v", err)
+	}
+	if schema.Name != "testOutput" {
+		t.Errorf("Name = %q, want testOutput", schema.Name)
+	}
+
+	// 嵌入的结构体展开，属性保持字段顺序
+	var names []string
+	for _, p := range schema.Properties {
+		names = append(names, p.Name)
+	}
+	if want := []string{"reasoning", "score", "label", "tags", "count", "note"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("properties = %v, want %v", names, want)
+	}
+	if want := []string{"reasoning", "score", "label", "tags"}; !reflect.DeepEqual(schema.Required, want) {
+		t.Errorf("required = %v, want %v", schema.Required, want)
+	}
+	if schema.strict() {
+		t.Error("strict() = true with optional fields")
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"type":"object","properties":{` +
+		`"reasoning":{"type":"string","description":"思考过程"},` +
+		`"score":{"type":"number"},` +
+		`"label":{"type":"string","enum":["good","bad"]},` +
+		`"tags":{"type":"array","items":{"type":"string"}},` +
+		`"count":{"type":"integer"},` +
+		`"note":{"type":"string"}},` +
+		`"required":["reasoning","score","label","tags"],"additionalProperties":false}`
+	if string(data) != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", data, want)
+	}
+
+	if _, err := SchemaFor([]string{}); err == nil {
+		t.Error("SchemaFor(slice) error = nil, want error")
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := SchemaFor(testOutput{})
+	if err != nil {
+		t.Fatalf("SchemaFor() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"valid", `{"reasoning":"r","score":0.5,"label":"good","tags":["a"],"extra":1}`, nil},
+		{"optional null", `{"reasoning":"r","score":1,"label":"bad","tags":[],"count":null,"note":null}`, nil},
+		{"missing and enum", `{"reasoning":"r","score":1,"label":"ok"}`, []string{
+			`$: 缺少必填字段 "tags"`, `$.label: 取值 "ok" 不在 [good bad] 中`,
+		}},
+		{"wrong types", `{"reasoning":null,"score":"1","label":"good","tags":[1],"count":1.5}`, []string{
+			"$.reasoning: 不能为 null，应为 string", "$.score: 应为数字", "$.tags[0]: 应为字符串", "$.count: 应为整数，实际为 1.5",
+		}},
+		{"not an object", `[]`, []string{"$: 应为对象"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schema.Validate([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := schema.Validate([]byte(`{"a":`)); len(got) != 1 || !strings.HasPrefix(got[0], "不是有效的 JSON") {
+		t.Errorf("Validate(truncated) = %q", got)
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"a":1}`:                 `{"a":1}`,
+		"```json\n{\"a\":1}\n```": `{"a":1}`,
+		"<thinking>{maybe}</thinking>\n结果: {\"a\":1}": `{"a":1}`,
+		"没有 JSON": "",
+	}
+	for in, want := range tests {
+		if got := extractJSON(in); got != want {
+			t.Errorf("extractJSON(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// capturedRequest 模拟服务端收到的请求中与结构化输出相关的部分
+type capturedRequest struct {
+	Messages []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+	ResponseFormat json.RawMessage `json:"response_format"`
+}
+
+// replyContents 按顺序以 contents 作为模型输出响应 (超出后重复最后一个)，并记录收到的请求
+func replyContents(t *testing.T, requests *[]capturedRequest, contents ...string) http.HandlerFunc {
+	var mu sync.Mutex
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var req capturedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		n := len(*requests)
+		*requests = append(*requests, req)
+		if n >= len(contents) {
+			n = len(contents) - 1
+		}
+		body, _ := json.Marshal(map[string]any{
+			"id": "1", "object": "chat.completion", "created": 1, "model": "qwen-plus",
+			"choices": []map[string]any{{"index": 0, "finish_reason": "stop",
+				"message": map[string]any{"role": "assistant", "content": contents[n]}}},
+		})
+		reply(200, string(body))(w, r)
+	}
+}
+
+func TestChatJSONRepair(t *testing.T) {
+	invalid := `{"reasoning":"r","score":1,"label":"great","tags":[]}`
+	valid := "```json\n" + `{"reasoning":"r","score":1,"label":"good","tags":["x"]}` + "\n```"
+
+	var requests []capturedRequest
+	client, _ := newTestClient(t, config.LLMProviderConfig{}, replyContents(t, &requests, invalid, valid))
+
+	var out testOutput
+	if err := client.ChatJSON(context.Background(), "system", "user", &out); err != nil {
+		t.Fatalf("ChatJSON() error = %v", err)
+	}
+	if out.Label != "good" || !reflect.DeepEqual(out.Tags, []string{"x"}) {
+		t.Errorf("out = %+v", out)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+
+	first := requests[0]
+	if len(first.Messages) != 2 || !strings.Contains(first.Messages[0].Content, `"enum":["good","bad"]`) {
+		t.Errorf("first request messages = %+v, want schema in system prompt", first.Messages)
+	}
+	if string(first.ResponseFormat) != `{"type":"json_object"}` {
+		t.Errorf("response_format = %s, want json_object by default", first.ResponseFormat)
+	}
+
+	// 修复轮次附带上一次的输出与发现的问题
+	repair := requests[1].Messages
+	if len(repair) != 4 || repair[2].Role != "assistant" || repair[2].Content != invalid ||
+		repair[3].Role != "user" || !strings.Contains(repair[3].Content, `$.label: 取值 "great"`) {
+		t.Errorf("repair request messages = %+v", repair)
+	}
+}
+
+func TestChatJSONInvalidAfterRepair(t *testing.T) {
+	var requests []capturedRequest
+	client, _ := newTestClient(t, config.LLMProviderConfig{}, replyContents(t, &requests, "抱歉，我无法回答"))
+
+	var out testOutput
+	err := client.ChatJSON(context.Background(), "system", "user", &out)
+	if !errors.Is(err, ErrInvalidJSON) {
+		t.Fatalf("ChatJSON() error = %v, want ErrInvalidJSON", err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("requests = %d, want 2 (one repair)", len(requests))
+	}
+}
+
+func TestChatJSONResponseFormat(t *testing.T) {
+	valid := `{"reasoning":"r","score":1,"label":"good","tags":[]}`
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{config.JSONModeSchema, `"type":"json_schema"`},
+		{config.JSONModeObject, `{"type":"json_object"}`},
+		{config.JSONModeOff, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			var requests []capturedRequest
+			client, _ := newTestClient(t, config.LLMProviderConfig{JSONMode: tt.mode}, replyContents(t, &requests, valid))
+			var out testVerdict
+			if err := client.ChatJSON(context.Background(), "system", "user", &out); err != nil {
+				t.Fatalf("ChatJSON() error = %v", err)
+			}
+			got := string(requests[0].ResponseFormat)
+			if tt.want == "" && got != "" {
+				t.Errorf("response_format = %s, want none", got)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("response_format = %s, want %s", got, tt.want)
+			}
+			if tt.mode == config.JSONModeSchema && !strings.Contains(got, `"strict":true`) {
+				t.Errorf("response_format = %s, want strict schema", got)
+			}
+		})
+	}
+}
+
+func TestChatJSONCached(t *testing.T) {
+	valid := `{"score":1,"label":"good"}`
+	var requests []capturedRequest
+	client, calls := newTestClient(t, config.LLMProviderConfig{}, replyContents(t, &requests, valid))
+	cache := mapCache{}
+	client.SetCache(cache)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		var out testVerdict
+		if err := client.ChatJSONCached(ctx, "system", "user", &out); err != nil || out.Label != "good" {
+			t.Fatalf("ChatJSONCached() = %+v, %v", out, err)
+		}
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("calls = %d, want 1", n)
+	}
+
+	// 缓存的输出不再符合结构时重新请求
+	for key := range cache {
+		cache[key] = `{"score":"bad"}`
+	}
+	var out testVerdict
+	if err := client.ChatJSONCached(ctx, "system", "user", &out); err != nil {
+		t.Fatalf("ChatJSONCached() error = %v", err)
+	}
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+}
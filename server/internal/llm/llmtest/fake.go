@@ -0,0 +1,201 @@
+// Package llmtest 提供离线测试使用的 LLM 替身
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xumoe-c/maiecho/server/internal/llm"
+)
+
+// ErrUnscripted 没有为该提示词准备响应
+var ErrUnscripted = errors.New("llmtest: 未准备该提示词的响应")
+
+// Call 一次调用的记录
+type Call struct {
+	System   string
+	User     string
+	Cached   bool // 通过 ChatCached / ChatJSONCached 调用
+	Streamed bool // 通过 ChatJSONStream 调用
+	Response string
+	Err      error
+}
+
+// script 一条响应规则
+type script struct {
+	match     func(system, user string) bool
+	responses []string
+	err       error
+	next      int
+}
+
+// Fake 按提示词回放预先录制的响应，实现 llm.ChatClient
+// 规则按注册顺序匹配，第一条匹配的规则生效；同一规则有多个响应时依次返回，用完后重复最后一个。
+// 没有匹配的规则时返回 ErrUnscripted，便于发现测试未覆盖的调用。
+// ChatJSON 与真实客户端一样按结构校验响应，但不进行修复。并发安全
+type Fake struct {
+	mu      sync.Mutex
+	scripts []*script
+	calls   []Call
+	forgot  []string
+}
+
+var _ llm.ChatClient = (*Fake)(nil)
+
+// NewFake 创建没有任何响应规则的替身
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+// Reply 为完全相同的系统与用户提示词录制响应
+func (f *Fake) Reply(systemPrompt, userPrompt string, responses ...string) *Fake {
+	return f.add(func(system, user string) bool {
+		return system == systemPrompt && user == userPrompt
+	}, responses, nil)
+}
+
+// ReplyContaining 为系统或用户提示词包含 substr 的调用录制响应
+func (f *Fake) ReplyContaining(substr string, responses ...string) *Fake {
+	return f.add(func(system, user string) bool {
+		return strings.Contains(system, substr) || strings.Contains(user, substr)
+	}, responses, nil)
+}
+
+// FailContaining 系统或用户提示词包含 substr 的调用返回 err
+func (f *Fake) FailContaining(substr string, err error) *Fake {
+	return f.add(func(system, user string) bool {
+		return strings.Contains(system, substr) || strings.Contains(user, substr)
+	}, nil, err)
+}
+
+func (f *Fake) add(match func(system, user string) bool, responses []string, err error) *Fake {
+	if err == nil && len(responses) == 0 {
+		panic("llmtest: 至少需要一个响应")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scripts = append(f.scripts, &script{match: match, responses: responses, err: err})
+	return f
+}
+
+// Calls 返回按调用顺序排列的全部调用记录
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsContaining 返回系统或用户提示词包含 substr 的调用记录
+func (f *Fake) CallsContaining(substr string) []Call {
+	var calls []Call
+	for _, c := range f.Calls() {
+		if strings.Contains(c.System, substr) || strings.Contains(c.User, substr) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Forgotten 返回调用过 Forget 的用户提示词
+func (f *Fake) Forgotten() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.forgot...)
+}
+
+func (f *Fake) respond(systemPrompt, userPrompt string, cached, streamed bool) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call := Call{System: systemPrompt, User: userPrompt, Cached: cached, Streamed: streamed}
+	call.Err = fmt.Errorf("%w: %s", ErrUnscripted, preview(userPrompt))
+	for _, s := range f.scripts {
+		if !s.match(systemPrompt, userPrompt) {
+			continue
+		}
+		if s.err != nil {
+			call.Err = s.err
+			break
+		}
+		call.Response, call.Err = s.responses[s.next], nil
+		if s.next < len(s.responses)-1 {
+			s.next++
+		}
+		break
+	}
+	f.calls = append(f.calls, call)
+	return call.Response, call.Err
+}
+
+// preview 截取提示词开头，用于错误信息
+func preview(prompt string) string {
+	const maxRunes = 80
+	runes := []rune(prompt)
+	if len(runes) > maxRunes {
+		return string(runes[:maxRunes]) + "..."
+	}
+	return prompt
+}
+
+// Chat 返回匹配规则的下一个响应
+func (f *Fake) Chat(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return f.respond(systemPrompt, userPrompt, false, false)
+}
+
+// ChatCached 与 Chat 相同，调用记录中标记为缓存调用 (替身本身不缓存)
+func (f *Fake) ChatCached(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return f.respond(systemPrompt, userPrompt, true, false)
+}
+
+// Forget 记录被删除缓存的提示词
+func (f *Fake) Forget(systemPrompt, userPrompt string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.forgot = append(f.forgot, userPrompt)
+}
+
+// ChatJSON 返回匹配规则的下一个响应，并按 out 的结构校验、解析
+func (f *Fake) ChatJSON(ctx context.Context, systemPrompt, userPrompt string, out any) error {
+	response, err := f.Chat(ctx, systemPrompt, userPrompt)
+	if err != nil {
+		return err
+	}
+	return llm.DecodeJSON(response, out)
+}
+
+// ChatJSONCached 与 ChatJSON 相同，调用记录中标记为缓存调用
+func (f *Fake) ChatJSONCached(ctx context.Context, systemPrompt, userPrompt string, out any) error {
+	response, err := f.ChatCached(ctx, systemPrompt, userPrompt)
+	if err != nil {
+		return err
+	}
+	return llm.DecodeJSON(response, out)
+}
+
+// StreamChunkRunes ChatJSONStream 将响应切分为片段时每段的字符数
+const StreamChunkRunes = 4
+
+// ChatJSONStream 将匹配规则的下一个响应按 StreamChunkRunes 切分后依次传给 onDelta，再按 out 的结构校验、解析
+func (f *Fake) ChatJSONStream(ctx context.Context, systemPrompt, userPrompt string, out any, onDelta func(delta string)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	response, err := f.respond(systemPrompt, userPrompt, false, true)
+	if err != nil {
+		return err
+	}
+	runes := []rune(response)
+	for i := 0; i < len(runes); i += StreamChunkRunes {
+		onDelta(string(runes[i:min(i+StreamChunkRunes, len(runes))]))
+	}
+	return llm.DecodeJSON(response, out)
+}
@@ -0,0 +1,226 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
+	"github.com/xumoe-c/maiecho/server/internal/config"
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+)
+
+// provider 单个 OpenAI 兼容的模型服务，负责本提供方内的超时与重试
+type provider struct {
+	name     string
+	client   *openai.Client
+	model    string
+	timeout  time.Duration // 单次请求超时，0 表示不限制
+	retry    RetryPolicy
+	jsonMode string // 结构化输出的 response_format，见 config.JSONMode*
+
+	promptPrice     float64 // 每百万输入 token 的价格
+	completionPrice float64 // 每百万输出 token 的价格
+}
+
+func newProvider(cfg config.LLMProviderConfig) *provider {
+	client := openai.NewClient(
+		option.WithAPIKey(cfg.APIKey),
+		option.WithBaseURL(cfg.BaseURL),
+		// 重试由 provider 统一处理 (区分可重试错误并记录日志)，关闭 SDK 自带的重试
+		option.WithMaxRetries(0),
+	)
+
+	jsonMode := cfg.JSONMode
+	if jsonMode == "" {
+		jsonMode = config.JSONModeObject
+	}
+	return &provider{
+		name:     cfg.Name,
+		client:   &client,
+		model:    cfg.Model,
+		timeout:  cfg.Timeout,
+		retry:    NewRetryPolicy(cfg),
+		jsonMode: jsonMode,
+
+		promptPrice:     cfg.PromptPrice,
+		completionPrice: cfg.CompletionPrice,
+	}
+}
+
+// chatRequest 一次对话请求
+type chatRequest struct {
+	system string
+	user   string
+
+	// 修复轮次：模型上一次的输出及指出问题的追加消息，为空时只发送 system + user
+	previous string
+	feedback string
+
+	schema *Schema // 非 nil 时要求输出符合该结构的 JSON
+
+	// onDelta 非 nil 时以流式方式请求，每收到一段输出调用一次
+	onDelta func(delta string)
+}
+
+func (r chatRequest) messages() []openai.ChatCompletionMessageParamUnion {
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(r.system),
+		openai.UserMessage(r.user),
+	}
+	if r.feedback != "" {
+		messages = append(messages, openai.AssistantMessage(r.previous), openai.UserMessage(r.feedback))
+	}
+	return messages
+}
+
+// logUser 对话日志中记录的用户消息 (修复轮次附带上一次的输出与修正要求)
+func (r chatRequest) logUser() string {
+	if r.feedback == "" {
+		return r.user
+	}
+	return r.user + "\n\n[上一次输出]\n" + r.previous + "\n\n[修正要求]\n" + r.feedback
+}
+
+// chat 执行一次对话
+// 429、5xx、单次请求超时和网络错误按重试策略以指数退避重试 (服务端返回 Retry-After 时按其等待)，
+// 其余错误或 ctx 结束时立即返回。成功时同时返回该次请求的用量 (失败的尝试不计入)。
+// hasFallback 为 true (后面还有备用提供方) 时，限流或 Retry-After 超过等待上限不再原地重试，立即返回以便切换。
+// 流式请求已经输出部分内容后失败时不再重试，返回 ErrStreamInterrupted
+func (p *provider) chat(ctx context.Context, req chatRequest, hasFallback bool) (string, Usage, error) {
+	streamed := false
+	if onDelta := req.onDelta; onDelta != nil {
+		req.onDelta = func(delta string) {
+			streamed = true
+			onDelta(delta)
+		}
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var response string
+		var usage Usage
+		response, usage, err = p.chatOnce(ctx, req)
+		if err == nil {
+			logger.LogLLMConversation(p.model, req.system, req.logUser(), response, nil)
+			return response, usage, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if streamed {
+			err = fmt.Errorf("%w: %w", ErrStreamInterrupted, err)
+			break
+		}
+
+		retryable, retryAfter := classifyError(err)
+		if !retryable || attempt >= p.retry.MaxRetries {
+			break
+		}
+		if hasFallback && p.retry.shouldFailOver(err, retryAfter) {
+			logger.Warn("LLM提供方限流，不再重试并切换到备用提供方", "module", "llm", "provider", p.name, "model", p.model, "retryAfter", retryAfter, "error", err)
+			break
+		}
+		delay := retryAfter
+		if delay <= 0 {
+			delay = p.retry.backoff(attempt)
+		}
+		logger.Warn("LLM请求失败，等待后重试", "module", "llm", "provider", p.name, "model", p.model, "attempt", attempt+1, "maxRetries", p.retry.MaxRetries, "delay", delay, "error", err)
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			break
+		}
+	}
+
+	logger.LogLLMConversation(p.model, req.system, req.logUser(), "", err)
+	return "", Usage{}, err
+}
+
+// chatOnce 发送一次请求，超时只作用于本次请求
+func (p *provider) chatOnce(ctx context.Context, req chatRequest) (string, Usage, error) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Messages: req.messages(),
+		Model:    p.model,
+	}
+	if req.schema != nil {
+		params.ResponseFormat = p.responseFormat(req.schema)
+	}
+	if req.onDelta != nil {
+		return p.chatStream(ctx, params, req.onDelta)
+	}
+
+	chatCompletion, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	if len(chatCompletion.Choices) == 0 {
+		return "", Usage{}, errNoChoices
+	}
+
+	return chatCompletion.Choices[0].Message.Content, p.usage(chatCompletion.Usage), nil
+}
+
+// chatStream 以流式方式发送请求，超时作用于整个流
+func (p *provider) chatStream(ctx context.Context, params openai.ChatCompletionNewParams, onDelta func(string)) (string, Usage, error) {
+	// 最后一个分块附带整个请求的用量
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+
+	stream := p.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	acc := openai.ChatCompletionAccumulator{}
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			onDelta(chunk.Choices[0].Delta.Content)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return "", Usage{}, err
+	}
+	if len(acc.Choices) == 0 {
+		return "", Usage{}, errNoChoices
+	}
+	return acc.Choices[0].Message.Content, p.usage(acc.Usage), nil
+}
+
+func (p *provider) usage(u openai.CompletionUsage) Usage {
+	usage := Usage{
+		Provider:         p.name,
+		Model:            p.model,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+	}
+	usage.Cost = cost(usage.PromptTokens, usage.CompletionTokens, p.promptPrice, p.completionPrice)
+	return usage
+}
+
+// responseFormat 按提供方支持的方式请求 JSON 输出
+func (p *provider) responseFormat(schema *Schema) openai.ChatCompletionNewParamsResponseFormatUnion {
+	switch p.jsonMode {
+	case config.JSONModeSchema:
+		return openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   schema.Name,
+					Schema: schema,
+					Strict: openai.Bool(schema.strict()),
+				},
+			},
+		}
+	case config.JSONModeObject:
+		return openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONObject: &shared.ResponseFormatJSONObjectParam{},
+		}
+	default:
+		return openai.ChatCompletionNewParamsResponseFormatUnion{}
+	}
+}
@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/xumoe-c/maiecho/server/internal/config"
+)
+
+// errNoChoices 服务端返回了空的 choices，通常是上游的瞬时故障，按可重试处理
+var errNoChoices = errors.New("LLM未返回任何选项")
+
+// RetryPolicy LLM 请求的重试策略
+type RetryPolicy struct {
+	MaxRetries int           // 最大重试次数，0 表示不重试
+	BaseDelay  time.Duration // 首次重试前的基础等待时间
+	MaxDelay   time.Duration // 单次等待时间的上限 (不限制 Retry-After)
+}
+
+// NewRetryPolicy 根据配置生成重试策略
+func NewRetryPolicy(cfg config.LLMProviderConfig) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: cfg.Retries(),
+		BaseDelay:  cfg.RetryBaseDelay,
+		MaxDelay:   cfg.RetryMaxDelay,
+	}
+}
+
+// backoff 第 attempt 次重试 (从 0 开始) 前的等待时间
+// 指数增长并在上半区间内随机抖动，避免并发请求同时重试
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 0; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// classifyError 判断错误是否可以重试，以及服务端通过 Retry-After 要求的等待时间
+//   - 429 (配额耗尽除外)、408、409 和 5xx 可重试
+//   - 其余 4xx (参数错误、鉴权失败、模型不存在等) 重试也不会成功，直接返回
+//   - 单次请求超时、网络错误和空响应可重试；调用方取消 (ctx) 由调用方单独判断
+func classifyError(err error) (retryable bool, retryAfter time.Duration) {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		if apiErr.Response != nil {
+			retryAfter = parseRetryAfter(apiErr.Response.Header)
+		}
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			// 账户余额或配额耗尽同样返回 429，等待无法恢复
+			return apiErr.Code != "insufficient_quota", retryAfter
+		case apiErr.StatusCode == http.StatusRequestTimeout,
+			apiErr.StatusCode == http.StatusConflict,
+			apiErr.StatusCode >= http.StatusInternalServerError:
+			return true, retryAfter
+		default:
+			return false, 0
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errNoChoices) {
+		return true, 0
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true, 0
+	}
+	// 连接被重置、响应被截断等未包装为 net.Error 的传输层错误
+	return !errors.Is(err, context.Canceled), 0
+}
+
+// shouldFailOver 判断存在备用提供方时是否放弃本提供方的重试、立即切换
+// 限流 (429) 的提供方短时间内难以恢复，Retry-After 超过等待上限时原地等待也不如切换
+func (p RetryPolicy) shouldFailOver(err error, retryAfter time.Duration) bool {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return p.MaxDelay > 0 && retryAfter > p.MaxDelay
+}
+
+// parseRetryAfter 解析 Retry-After-Ms / Retry-After (秒数或 HTTP 日期)
+func parseRetryAfter(h http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(h.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := h.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleepContext 等待 d，ctx 结束时提前返回其错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
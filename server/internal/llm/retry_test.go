@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+)
+
+const completionBody = `{"id":"1","object":"chat.completion","created":1,"model":"qwen-plus",
+"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`
+
+// newTestClient 启动一个按顺序使用 handlers 响应的模拟服务端 (超出后重复最后一个)，返回客户端与请求计数
+func newTestClient(t *testing.T, cfg config.LLMProviderConfig, handlers ...http.HandlerFunc) (*Client, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1)) - 1
+		if n >= len(handlers) {
+			n = len(handlers) - 1
+		}
+		handlers[n](w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg.APIKey = "test"
+	cfg.BaseURL = server.URL
+	cfg.Model = "qwen-plus"
+	if cfg.RetryBaseDelay == 0 {
+		cfg.RetryBaseDelay = time.Millisecond
+	}
+	return NewClient(cfg), &calls
+}
+
+func intPtr(n int) *int { return &n }
+
+func reply(status int, body string, headers ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}
+}
+
+func TestChatRetry(t *testing.T) {
+	rateLimited := `{"error":{"code":"rate_limit_exceeded","message":"slow down"}}`
+	tests := []struct {
+		name      string
+		handlers  []http.HandlerFunc
+		wantCalls int32
+		wantErr   bool
+	}{
+		{
+			name:      "retries 429 and 5xx",
+			handlers:  []http.HandlerFunc{reply(429, rateLimited), reply(503, `{}`), reply(200, completionBody)},
+			wantCalls: 3,
+		},
+		{
+			name:      "gives up after max retries",
+			handlers:  []http.HandlerFunc{reply(500, `{}`)},
+			wantCalls: 3,
+			wantErr:   true,
+		},
+		{
+			name:      "does not retry bad request",
+			handlers:  []http.HandlerFunc{reply(400, `{"error":{"code":"invalid_parameter","message":"bad"}}`), reply(200, completionBody)},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "does not retry exhausted quota",
+			handlers:  []http.HandlerFunc{reply(429, `{"error":{"code":"insufficient_quota","message":"no money"}}`), reply(200, completionBody)},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "retries empty choices",
+			handlers:  []http.HandlerFunc{reply(200, `{"id":"1","choices":[]}`), reply(200, completionBody)},
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, calls := newTestClient(t, config.LLMProviderConfig{MaxRetries: intPtr(2)}, tt.handlers...)
+			got, err := client.Chat(context.Background(), "system", "user")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Chat() = %q, %v, wantErr %v", got, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != "ok" {
+				t.Errorf("Chat() = %q, want ok", got)
+			}
+			if n := atomic.LoadInt32(calls); n != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestChatHonoursRetryAfter(t *testing.T) {
+	client, calls := newTestClient(t, config.LLMProviderConfig{MaxRetries: intPtr(1)},
+		reply(429, `{}`, "Retry-After-Ms", "150"), reply(200, completionBody))
+
+	start := time.Now()
+	if _, err := client.Chat(context.Background(), "system", "user"); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("retried after %v, want at least the Retry-After of 150ms", elapsed)
+	}
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+}
+
+func TestChatTimeout(t *testing.T) {
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}
+
+	// 单次请求超时后重试
+	client, calls := newTestClient(t, config.LLMProviderConfig{MaxRetries: intPtr(1), Timeout: 50 * time.Millisecond},
+		slow, reply(200, completionBody))
+	if _, err := client.Chat(context.Background(), "system", "user"); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+
+	// 调用方取消时不再重试
+	client, calls = newTestClient(t, config.LLMProviderConfig{MaxRetries: intPtr(3)}, slow)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Chat(ctx, "system", "user"); err == nil {
+		t.Fatal("Chat() error = nil, want context deadline")
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("calls = %d, want 1", n)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, limit := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		limit *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(attempt); d < limit/2 || d > limit {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", attempt, d, limit/2, limit)
+			}
+		}
+	}
+}
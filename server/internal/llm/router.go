@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"fmt"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+)
+
+// Agent 角色，对应 llm.routes 中的键
+const (
+	RoleDefault   = "default"   // 未单独配置路由的角色
+	RoleCleaner   = "cleaner"   // 评论语义清洗
+	RoleAnalyst   = "analyst"   // 评论分析 (提取客观事实)
+	RoleAdvisor   = "advisor"   // 生成推分建议与报告
+	RoleMapper    = "mapper"    // 评论与歌曲匹配的二次确认 (VerifyMatch)
+	RoleRelevance = "relevance" // 采集内容的相关性判断
+)
+
+// Roles 全部可配置路由的角色
+var Roles = []string{RoleDefault, RoleCleaner, RoleAnalyst, RoleAdvisor, RoleMapper, RoleRelevance}
+
+// Router 根据 llm.routes 为每个角色提供客户端
+type Router struct {
+	clients map[string]*Client
+	shared  *sharedState
+}
+
+// NewRouter 根据配置创建各提供方及角色路由
+// 未配置 default 路由时，default 按 providers 的定义顺序依次故障转移；其余未配置的角色使用 default 路由
+func NewRouter(cfg config.LLMConfig) (*Router, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	resolved := cfg.ResolvedProviders()
+	providers := make(map[string]*provider, len(resolved))
+	all := make([]*provider, 0, len(resolved))
+	for _, pc := range resolved {
+		p := newProvider(pc)
+		providers[pc.Name] = p
+		all = append(all, p)
+	}
+
+	for role := range cfg.Routes {
+		if !isKnownRole(role) {
+			return nil, fmt.Errorf("llm.routes 中存在未知的角色: %s (可用角色: %v)", role, Roles)
+		}
+	}
+
+	r := &Router{clients: make(map[string]*Client, len(Roles)), shared: &sharedState{}}
+	chain := func(role string, fallback []*provider) []*provider {
+		names, ok := cfg.Routes[role]
+		if !ok {
+			return fallback
+		}
+		ps := make([]*provider, 0, len(names))
+		for _, name := range names {
+			ps = append(ps, providers[name])
+		}
+		return ps
+	}
+	defaultChain := chain(RoleDefault, all)
+	for _, role := range Roles {
+		r.clients[role] = &Client{role: role, providers: chain(role, defaultChain), shared: r.shared}
+	}
+	return r, nil
+}
+
+func isKnownRole(role string) bool {
+	for _, known := range Roles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
+
+// For 返回角色对应的客户端，未知角色使用 default 路由
+func (r *Router) For(role string) *Client {
+	if c, ok := r.clients[role]; ok {
+		return c
+	}
+	return r.clients[RoleDefault]
+}
+
+// InFlight 返回所有角色进行中的 LLM 请求总数
+func (r *Router) InFlight() int64 {
+	return r.clients[RoleDefault].InFlight()
+}
+
+// SetUsageRecorder 为所有角色设置用量记录，需在发起请求之前调用
+func (r *Router) SetUsageRecorder(recorder UsageRecorder) {
+	r.shared.recorder = recorder
+}
+
+// SetCache 为所有角色设置响应缓存，需在发起请求之前调用
+func (r *Router) SetCache(cache Cache) {
+	r.shared.cache = cache
+}
+
+// Describe 返回各角色的提供方顺序 (用于启动日志)
+func (r *Router) Describe() map[string][]string {
+	routes := make(map[string][]string, len(r.clients))
+	for role, c := range r.clients {
+		routes[role] = c.Providers()
+	}
+	return routes
+}
@@ -0,0 +1,236 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+)
+
+// newProviderServer 启动一个总是使用 handler 响应的模拟提供方，返回其地址与请求计数
+func newProviderServer(t *testing.T, handler http.HandlerFunc) (string, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &calls
+}
+
+func TestRouterFailover(t *testing.T) {
+	limitedURL, limitedCalls := newProviderServer(t, reply(429, `{"error":{"code":"rate_limit_exceeded"}}`))
+	brokenURL, brokenCalls := newProviderServer(t, reply(401, `{"error":{"code":"invalid_api_key"}}`))
+	healthyURL, healthyCalls := newProviderServer(t, reply(200, completionBody))
+
+	cfg := config.LLMConfig{
+		LLMProviderConfig: config.LLMProviderConfig{APIKey: "shared", MaxRetries: intPtr(1)},
+		Providers: []config.LLMProviderConfig{
+			{Name: "primary", BaseURL: limitedURL, Model: "qwen-plus"},
+			{Name: "fallback", BaseURL: healthyURL, Model: "deepseek-chat"},
+			{Name: "cheap", BaseURL: brokenURL, Model: "qwen-turbo", MaxRetries: intPtr(0)},
+		},
+		Routes: map[string][]string{
+			RoleDefault: {"primary", "fallback"},
+			RoleMapper:  {"cheap", "fallback"},
+		},
+	}
+	router, err := NewRouter(cfg)
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	wantRoutes := map[string][]string{
+		RoleDefault: {"primary", "fallback"}, RoleCleaner: {"primary", "fallback"},
+		RoleAnalyst: {"primary", "fallback"}, RoleAdvisor: {"primary", "fallback"},
+		RoleMapper: {"cheap", "fallback"}, RoleRelevance: {"primary", "fallback"},
+	}
+	if got := router.Describe(); !reflect.DeepEqual(got, wantRoutes) {
+		t.Errorf("Describe() = %v, want %v", got, wantRoutes)
+	}
+
+	// 主提供方限流时不在本提供方重试，直接切换到备用提供方
+	if got, err := router.For(RoleAnalyst).Chat(context.Background(), "system", "user"); err != nil || got != "ok" {
+		t.Fatalf("analyst Chat() = %q, %v", got, err)
+	}
+	if n := atomic.LoadInt32(limitedCalls); n != 1 {
+		t.Errorf("primary calls = %d, want 1 (no retry with a fallback)", n)
+	}
+
+	// 不可重试的错误不在本提供方重试，但仍然切换到下一个提供方
+	if got, err := router.For(RoleMapper).Chat(context.Background(), "system", "user"); err != nil || got != "ok" {
+		t.Fatalf("mapper Chat() = %q, %v", got, err)
+	}
+	if n := atomic.LoadInt32(brokenCalls); n != 1 {
+		t.Errorf("cheap calls = %d, want 1", n)
+	}
+	if n := atomic.LoadInt32(healthyCalls); n != 2 {
+		t.Errorf("fallback calls = %d, want 2", n)
+	}
+	if n := router.InFlight(); n != 0 {
+		t.Errorf("InFlight() = %d after calls finished, want 0", n)
+	}
+
+	// 全部提供方失败时返回最后一个错误
+	only := config.LLMConfig{
+		LLMProviderConfig: config.LLMProviderConfig{APIKey: "k"},
+		Providers:         []config.LLMProviderConfig{{Name: "cheap", BaseURL: brokenURL}},
+	}
+	router, err = NewRouter(only)
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	if _, err := router.For(RoleRelevance).Chat(context.Background(), "system", "user"); err == nil {
+		t.Error("Chat() error = nil, want error when every provider fails")
+	}
+}
+
+func TestRouterFailoverRetryAfter(t *testing.T) {
+	limitedURL, limitedCalls := newProviderServer(t, reply(429, `{"error":{"code":"rate_limit_exceeded"}}`, "Retry-After", "600"))
+	overloadedURL, overloadedCalls := newProviderServer(t, reply(503, `{}`, "Retry-After", "600"))
+	healthyURL, healthyCalls := newProviderServer(t, reply(200, completionBody))
+
+	cfg := config.LLMConfig{
+		LLMProviderConfig: config.LLMProviderConfig{APIKey: "k", MaxRetries: intPtr(3), RetryMaxDelay: time.Second},
+		Providers: []config.LLMProviderConfig{
+			{Name: "limited", BaseURL: limitedURL, Model: "qwen-plus"},
+			{Name: "overloaded", BaseURL: overloadedURL, Model: "qwen-max"},
+			{Name: "fallback", BaseURL: healthyURL, Model: "deepseek-chat"},
+		},
+		Routes: map[string][]string{
+			RoleDefault: {"limited", "fallback"},
+			RoleAdvisor: {"overloaded", "fallback"},
+		},
+	}
+	router, err := NewRouter(cfg)
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	// 429 与超过等待上限的 Retry-After 都不等待，立即切换到备用提供方
+	for _, role := range []string{RoleAnalyst, RoleAdvisor} {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		start := time.Now()
+		got, err := router.For(role).Chat(ctx, "system", "user")
+		cancel()
+		if err != nil || got != "ok" {
+			t.Fatalf("%s Chat() = %q, %v", role, got, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s failed over after %v, want immediately", role, elapsed)
+		}
+	}
+	if n := atomic.LoadInt32(limitedCalls); n != 1 {
+		t.Errorf("limited calls = %d, want 1", n)
+	}
+	if n := atomic.LoadInt32(overloadedCalls); n != 1 {
+		t.Errorf("overloaded calls = %d, want 1", n)
+	}
+	if n := atomic.LoadInt32(healthyCalls); n != 2 {
+		t.Errorf("fallback calls = %d, want 2", n)
+	}
+
+	// 备用提供方本身也会限流时，链路中的最后一个提供方仍按 Retry-After 等待重试
+	last := config.LLMConfig{
+		LLMProviderConfig: config.LLMProviderConfig{APIKey: "k", MaxRetries: intPtr(3)},
+		Providers:         []config.LLMProviderConfig{{Name: "limited", BaseURL: limitedURL}},
+	}
+	router, err = NewRouter(last)
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := router.For(RoleAnalyst).Chat(ctx, "system", "user"); err == nil {
+		t.Fatal("Chat() error = nil, want rate limit error")
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("gave up after %v, want to wait for Retry-After until the deadline", elapsed)
+	}
+	if n := atomic.LoadInt32(limitedCalls); n != 2 {
+		t.Errorf("limited calls = %d, want 2 (no retry before Retry-After)", n)
+	}
+}
+
+func TestNewRouterValidation(t *testing.T) {
+	base := config.LLMProviderConfig{APIKey: "k", BaseURL: "http://localhost", Model: "qwen-plus"}
+	tests := []struct {
+		name    string
+		cfg     config.LLMConfig
+		wantErr bool
+	}{
+		{name: "single provider from top level", cfg: config.LLMConfig{LLMProviderConfig: base}},
+		{name: "missing api key", cfg: config.LLMConfig{}, wantErr: true},
+		{
+			name: "duplicate provider",
+			cfg: config.LLMConfig{LLMProviderConfig: base, Providers: []config.LLMProviderConfig{
+				{Name: "a"}, {Name: "a"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "unknown provider in route",
+			cfg: config.LLMConfig{LLMProviderConfig: base, Providers: []config.LLMProviderConfig{{Name: "a"}},
+				Routes: map[string][]string{RoleAnalyst: {"b"}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown role",
+			cfg:     config.LLMConfig{LLMProviderConfig: base, Routes: map[string][]string{"summarizer": {config.DefaultLLMProvider}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRouter(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRouter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type usageRecorderFunc func(Usage)
+
+func (f usageRecorderFunc) RecordUsage(u Usage) { f(u) }
+
+func TestChatRecordsUsage(t *testing.T) {
+	body := `{"id":"1","object":"chat.completion","created":1,"model":"qwen-plus",
+"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}],
+"usage":{"prompt_tokens":1200,"completion_tokens":300,"total_tokens":1500}}`
+	brokenURL, _ := newProviderServer(t, reply(500, `{}`))
+	healthyURL, _ := newProviderServer(t, reply(200, body))
+
+	cfg := config.LLMConfig{
+		LLMProviderConfig: config.LLMProviderConfig{APIKey: "k", Model: "qwen-plus", PromptPrice: 0.8, CompletionPrice: 2},
+		Providers: []config.LLMProviderConfig{
+			{Name: "broken", BaseURL: brokenURL},
+			{Name: "healthy", BaseURL: healthyURL},
+		},
+	}
+	router, err := NewRouter(cfg)
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	var got []Usage
+	router.SetUsageRecorder(usageRecorderFunc(func(u Usage) { got = append(got, u) }))
+
+	ctx := WithJobID(WithSongID(context.Background(), 42), 7)
+	if _, err := router.For(RoleCleaner).Chat(ctx, "system", "user"); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	// 只记录成功的请求，归属到实际响应的提供方
+	want := Usage{Role: RoleCleaner, Provider: "healthy", Model: "qwen-plus", SongID: 42, JobID: 7,
+		PromptTokens: 1200, CompletionTokens: 300, Cost: (1200*0.8 + 300*2) / 1e6}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("recorded usage = %+v, want [%+v]", got, want)
+	}
+}
@@ -0,0 +1,306 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schema 由 Go 结构体生成的 JSON Schema (仅包含描述 Agent 输出所需的子集)
+// 结构体字段按 json 标签命名，未标记 omitempty 的字段为必填项；
+// 字段可以用 desc 标签添加说明，用 enum 标签 (逗号分隔) 限定字符串的取值
+type Schema struct {
+	Name        string // 类型名，用作 response_format 的名称
+	Type        string
+	Description string
+	Enum        []string
+	Items       *Schema // Type 为 array 时的元素结构
+	Properties  []Property
+	Required    []string
+}
+
+// Property 对象的一个属性，保持结构体中的字段顺序 (模型按该顺序生成，例如先写 reasoning 再写结论)
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+var schemaCache sync.Map // reflect.Type -> *Schema
+
+// SchemaFor 生成 v (结构体或指向结构体的指针) 对应的 JSON Schema
+func SchemaFor(v any) (*Schema, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("JSON 输出只支持结构体，实际为 %T", v)
+	}
+	if cached, ok := schemaCache.Load(t); ok {
+		return cached.(*Schema), nil
+	}
+	s, err := schemaForType(t)
+	if err != nil {
+		return nil, err
+	}
+	s.Name = schemaName(t)
+	schemaCache.Store(t, s)
+	return s, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaForType(t reflect.Type) (*Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Description: "RFC3339 时间"}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Struct:
+		s := &Schema{Type: "object"}
+		if err := addProperties(s, t); err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("JSON Schema 不支持的类型: %s", t)
+	}
+}
+
+// addProperties 将结构体的导出字段加入对象结构，匿名嵌入且没有 json 名称的结构体字段展开到同一层
+func addProperties(s *Schema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		// 与 encoding/json 一致：未导出的嵌入结构体的导出字段同样会被展开
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			if err := addProperties(s, ft); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop, err := schemaForType(f.Type)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+		}
+		if desc := f.Tag.Get("desc"); desc != "" {
+			prop.Description = desc
+		}
+		if enum := f.Tag.Get("enum"); enum != "" {
+			prop.Enum = strings.Split(enum, ",")
+		}
+		s.Properties = append(s.Properties, Property{Name: name, Schema: prop})
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
+
+var schemaNamePattern = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// schemaName 生成 response_format 要求的名称 (仅字母数字、下划线和连字符)
+func schemaName(t reflect.Type) string {
+	name := schemaNamePattern.ReplaceAllString(t.Name(), "_")
+	if name == "" {
+		return "output"
+	}
+	return name
+}
+
+// strict 所有层级的属性均为必填时才能使用 OpenAI 的 strict 模式
+func (s *Schema) strict() bool {
+	if s.Items != nil && !s.Items.strict() {
+		return false
+	}
+	if len(s.Required) != len(s.Properties) {
+		return false
+	}
+	for _, p := range s.Properties {
+		if !p.Schema.strict() {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON 按 JSON Schema 格式输出，properties 保持字段顺序
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":`)
+	writeJSON(&buf, s.Type)
+	if s.Description != "" {
+		buf.WriteString(`,"description":`)
+		writeJSON(&buf, s.Description)
+	}
+	if len(s.Enum) > 0 {
+		buf.WriteString(`,"enum":`)
+		writeJSON(&buf, s.Enum)
+	}
+	if s.Items != nil {
+		buf.WriteString(`,"items":`)
+		items, err := s.Items.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(items)
+	}
+	if s.Type == "object" {
+		buf.WriteString(`,"properties":{`)
+		for i, p := range s.Properties {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSON(&buf, p.Name)
+			buf.WriteByte(':')
+			prop, err := p.Schema.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(prop)
+		}
+		buf.WriteString(`},"required":`)
+		required := s.Required
+		if required == nil {
+			required = []string{}
+		}
+		writeJSON(&buf, required)
+		buf.WriteString(`,"additionalProperties":false`)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, v any) {
+	b, _ := json.Marshal(v)
+	buf.Write(b)
+}
+
+// Validate 检查 data 是否符合结构，返回全部问题 (为空表示通过)
+// 多余的属性会被忽略，不视为错误
+func (s *Schema) Validate(data []byte) []string {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return []string{"不是有效的 JSON: " + err.Error()}
+	}
+	if dec.More() {
+		return []string{"JSON 之后存在多余的内容"}
+	}
+	var problems []string
+	s.validate(v, "$", &problems)
+	return problems
+}
+
+func (s *Schema) validate(v any, path string, problems *[]string) {
+	fail := func(format string, args ...any) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+	if v == nil {
+		fail("不能为 null，应为 %s", s.Type)
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			fail("应为对象")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				fail("缺少必填字段 %q", name)
+			}
+		}
+		for _, p := range s.Properties {
+			value, ok := obj[p.Name]
+			// 选填字段允许为 null
+			if !ok || (value == nil && !contains(s.Required, p.Name)) {
+				continue
+			}
+			p.Schema.validate(value, path+"."+p.Name, problems)
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			fail("应为数组")
+			return
+		}
+		for i, item := range arr {
+			s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("应为字符串")
+			return
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			fail("取值 %q 不在 %v 中", str, s.Enum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("应为布尔值")
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			fail("应为整数")
+			return
+		}
+		if _, err := n.Int64(); err != nil {
+			fail("应为整数，实际为 %s", n)
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			fail("应为数字")
+		}
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+)
+
+// streamReply 以 SSE 分块返回 deltas，最后发送用量分块；broken 为 true 时在内容之后发送无法解析的分块
+func streamReply(broken bool, deltas ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for _, d := range deltas {
+			chunk, _ := json.Marshal(map[string]any{
+				"id": "1", "object": "chat.completion.chunk", "created": 1, "model": "qwen-plus",
+				"choices": []map[string]any{{"index": 0, "delta": map[string]any{"role": "assistant", "content": d}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+			w.(http.Flusher).Flush()
+		}
+		if broken {
+			fmt.Fprint(w, "data: {not json\n\n")
+			return
+		}
+		fmt.Fprint(w, `data: {"id":"1","object":"chat.completion.chunk","created":1,"model":"qwen-plus","choices":[],`+
+			`"usage":{"prompt_tokens":100,"completion_tokens":20,"total_tokens":120}}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	var streamRequested atomic.Bool
+	handler := streamReply(false, "你好", "，", "世界")
+	client, calls := newTestClient(t, config.LLMProviderConfig{MaxRetries: intPtr(1)},
+		reply(503, `{}`),
+		func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Stream        bool `json:"stream"`
+				StreamOptions struct {
+					IncludeUsage bool `json:"include_usage"`
+				} `json:"stream_options"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			streamRequested.Store(body.Stream && body.StreamOptions.IncludeUsage)
+			handler(w, r)
+		})
+	var usages []Usage
+	client.SetUsageRecorder(usageRecorderFunc(func(u Usage) { usages = append(usages, u) }))
+
+	var deltas []string
+	got, err := client.ChatStream(context.Background(), "system", "user", func(d string) { deltas = append(deltas, d) })
+	if err != nil || got != "你好，世界" {
+		t.Fatalf("ChatStream() = %q, %v", got, err)
+	}
+	if strings.Join(deltas, "|") != "你好|，|世界" {
+		t.Errorf("deltas = %q", deltas)
+	}
+	// 输出开始之前的失败照常重试
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+	if !streamRequested.Load() {
+		t.Error("request did not ask for stream with usage")
+	}
+	if len(usages) != 1 || usages[0].PromptTokens != 100 || usages[0].CompletionTokens != 20 {
+		t.Errorf("recorded usage = %+v", usages)
+	}
+}
+
+func TestChatStreamInterrupted(t *testing.T) {
+	brokenURL, brokenCalls := newProviderServer(t, streamReply(true, "半句"))
+	healthyURL, healthyCalls := newProviderServer(t, streamReply(false, "完整"))
+	router, err := NewRouter(config.LLMConfig{
+		LLMProviderConfig: config.LLMProviderConfig{APIKey: "k", Model: "qwen-plus", MaxRetries: intPtr(2)},
+		Providers: []config.LLMProviderConfig{
+			{Name: "broken", BaseURL: brokenURL},
+			{Name: "healthy", BaseURL: healthyURL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	var deltas []string
+	_, err = router.For(RoleAdvisor).ChatStream(context.Background(), "system", "user", func(d string) { deltas = append(deltas, d) })
+	if !errors.Is(err, ErrStreamInterrupted) {
+		t.Fatalf("ChatStream() error = %v, want ErrStreamInterrupted", err)
+	}
+	// 已经输出部分内容后既不重试也不切换提供方
+	if n := atomic.LoadInt32(brokenCalls); n != 1 {
+		t.Errorf("broken calls = %d, want 1", n)
+	}
+	if n := atomic.LoadInt32(healthyCalls); n != 0 {
+		t.Errorf("healthy calls = %d, want 0", n)
+	}
+	if len(deltas) != 1 || deltas[0] != "半句" {
+		t.Errorf("deltas = %q", deltas)
+	}
+}
+
+func TestChatJSONStream(t *testing.T) {
+	var requests []capturedRequest
+	valid := `{"score":1,"label":"good"}`
+	client, calls := newTestClient(t, config.LLMProviderConfig{},
+		streamReply(false, `{"score":1,`, `"label":"great"}`),
+		replyContents(t, &requests, valid))
+
+	var streamed strings.Builder
+	var out testVerdict
+	if err := client.ChatJSONStream(context.Background(), "system", "user", &out, func(d string) { streamed.WriteString(d) }); err != nil {
+		t.Fatalf("ChatJSONStream() error = %v", err)
+	}
+	// 流式输出不符合结构时，修复请求不再流式输出
+	if out.Label != "good" {
+		t.Errorf("out = %+v, want repaired output", out)
+	}
+	if streamed.String() != `{"score":1,"label":"great"}` {
+		t.Errorf("streamed = %q", streamed.String())
+	}
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Errorf("calls = %d, want 2", n)
+	}
+	if len(requests) != 1 || len(requests[0].Messages) != 4 {
+		t.Errorf("repair requests = %+v", requests)
+	}
+}
@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+)
+
+// Usage 一次成功调用消耗的 token 及估算费用
+type Usage struct {
+	Role             string
+	Provider         string
+	Model            string
+	SongID           uint // 调用所属的歌曲，0 表示无
+	JobID            uint // 调用所属的作业，0 表示无
+	PromptTokens     int64
+	CompletionTokens int64
+	Cost             float64 // 按提供方配置的单价估算，未配置单价时为 0
+}
+
+// UsageRecorder 接收每次成功调用的用量 (例如写入数据库)
+// 在请求的 goroutine 中同步调用，实现应尽快返回并自行处理错误
+type UsageRecorder interface {
+	RecordUsage(u Usage)
+}
+
+type usageContextKey int
+
+const (
+	songIDKey usageContextKey = iota
+	jobIDKey
+)
+
+// WithSongID 将之后的 LLM 调用归属到歌曲
+func WithSongID(ctx context.Context, songID uint) context.Context {
+	return context.WithValue(ctx, songIDKey, songID)
+}
+
+// WithJobID 将之后的 LLM 调用归属到作业
+func WithJobID(ctx context.Context, jobID uint) context.Context {
+	return context.WithValue(ctx, jobIDKey, jobID)
+}
+
+// attribution 读取 ctx 中的歌曲与作业归属
+func attribution(ctx context.Context) (songID, jobID uint) {
+	songID, _ = ctx.Value(songIDKey).(uint)
+	jobID, _ = ctx.Value(jobIDKey).(uint)
+	return songID, jobID
+}
+
+// cost 按每百万 token 的单价估算费用
+func cost(promptTokens, completionTokens int64, promptPrice, completionPrice float64) float64 {
+	return (float64(promptTokens)*promptPrice + float64(completionTokens)*completionPrice) / 1e6
+}
@@ -1,6 +1,8 @@
 package logger
 
 import (
+	"os"
+
 	"github.com/pterm/pterm"
 )
 
@@ -38,15 +40,25 @@ func (p *ProgressTracker) Stop() {
 	}
 }
 
+// Spinner 终端中的加载动画，零值不输出任何内容
 type Spinner struct {
 	spinner *pterm.SpinnerPrinter
 }
 
+// StartSpinner 启动加载动画；标准输出不是终端 (例如以服务方式运行或在测试中) 时返回不输出任何内容的 Spinner
 func StartSpinner(text string) *Spinner {
+	if !isTerminal(os.Stdout) {
+		return &Spinner{}
+	}
 	spinner, _ := pterm.DefaultSpinner.Start(text)
 	return &Spinner{spinner: spinner}
 }
 
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 func (s *Spinner) Success(text string) {
 	if s.spinner != nil {
 		s.spinner.Success(text)
@@ -3,6 +3,7 @@ package logger
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"sync"
 
@@ -16,8 +17,77 @@ var (
 	LLMLog  *zap.Logger // LLM对话专用日志
 	lastLog string
 	logMu   sync.RWMutex
+
+	// 最近的错误日志，供状态接口展示
+	recentErrors []ErrorEntry
 )
 
+// maxRecentErrors 保留的最近错误日志条数
+const maxRecentErrors = 20
+
+// ErrorEntry 代表一条错误级别的日志
+type ErrorEntry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Module  string    `json:"module,omitempty"`
+	Message string    `json:"message"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// errorCore 捕获错误级别的日志及其字段，保存到 recentErrors 中
+type errorCore struct {
+	zapcore.LevelEnabler
+	fields []zapcore.Field
+}
+
+func (c *errorCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &errorCore{LevelEnabler: c.LevelEnabler, fields: merged}
+}
+
+func (c *errorCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+func (c *errorCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	record := ErrorEntry{
+		Time:    entry.Time,
+		Level:   entry.Level.CapitalString(),
+		Message: entry.Message,
+	}
+	if module, ok := enc.Fields["module"].(string); ok {
+		record.Module = module
+	}
+	if errMsg, ok := enc.Fields["error"].(string); ok {
+		record.Error = errMsg
+	}
+
+	logMu.Lock()
+	recentErrors = append(recentErrors, record)
+	if len(recentErrors) > maxRecentErrors {
+		recentErrors = recentErrors[len(recentErrors)-maxRecentErrors:]
+	}
+	logMu.Unlock()
+	return nil
+}
+
+func (c *errorCore) Sync() error {
+	return nil
+}
+
 func init() {
 	// 初始化一个默认的 Logger，防止在 Init 被调用前使用导致 panic
 	config := zap.NewDevelopmentConfig()
@@ -33,6 +103,17 @@ func GetLastLog() string {
 	return lastLog
 }
 
+// GetRecentErrors 返回最近的错误日志，按时间从新到旧排列
+func GetRecentErrors() []ErrorEntry {
+	logMu.RLock()
+	defer logMu.RUnlock()
+	entries := make([]ErrorEntry, 0, len(recentErrors))
+	for i := len(recentErrors) - 1; i >= 0; i-- {
+		entries = append(entries, recentErrors[i])
+	}
+	return entries
+}
+
 type Config struct {
 	Level      string `mapstructure:"level"`
 	OutputPath string `mapstructure:"output_path"`
@@ -92,6 +173,9 @@ func Init(cfg Config) error {
 		cores = append(cores, fileCore)
 	}
 
+	// 错误日志捕获 Core（供状态接口展示最近的错误）
+	cores = append(cores, &errorCore{LevelEnabler: zapcore.ErrorLevel})
+
 	core := zapcore.NewTee(cores...)
 
 	// 添加 Hook 以捕获最后一条日志
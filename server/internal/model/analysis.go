@@ -1,16 +1,75 @@
 package model
 
 import (
+	"strings"
+
 	"gorm.io/gorm"
 )
 
 // AnalysisResult 存储对歌曲或谱面的分析结果
 type AnalysisResult struct {
 	gorm.Model
-	TargetType         string `gorm:"index" json:"target_type"` // Song or Chart
-	TargetID           uint   `gorm:"index" json:"target_id"`
-	Summary            string `json:"summary"`
-	RatingAdvice       string `json:"rating_advice"`
-	DifficultyAnalysis string `json:"difficulty_analysis"`
-	ReasoningLog       string `json:"reasoning_log" gorm:"type:text"` // 存储 LLM 的推理过程
+	TargetType         string       `gorm:"index" json:"target_type"` // Song or Chart
+	TargetID           uint         `gorm:"index" json:"target_id"`
+	Summary            string       `json:"summary"`
+	RatingAdvice       string       `json:"rating_advice"`
+	DifficultyAnalysis string       `json:"difficulty_analysis"`
+	ReasoningLog       string       `json:"reasoning_log" gorm:"type:text"`                          // 存储 LLM 的推理过程
+	ChartReport        *ChartReport `json:"chart_report,omitempty" gorm:"type:text;serializer:json"` // 谱面顾问的结构化报告，仅谱面结果包含
+
+	// 分析师从评论中提取的结构化事实
+	Sentiment string        `gorm:"index" json:"sentiment"`                // Positive, Neutral, Negative
+	Tags      []AnalysisTag `gorm:"foreignKey:ResultID" json:"tags"`       // 难度描述符与谱面配置，单独成表以便筛选
+	Pros      []string      `gorm:"type:text;serializer:json" json:"pros"` // 优点
+	Cons      []string      `gorm:"type:text;serializer:json" json:"cons"` // 缺点
+}
+
+// 分析标签的类别
+const (
+	AnalysisTagDifficulty = "difficulty" // 难度描述符，如 "体力谱"、"逆诈称"
+	AnalysisTagPattern    = "pattern"    // 谱面配置，如 "纵连"、"转圈"
+)
+
+// AnalysisTag 分析结果上的一个标签
+// 同一结果内 (Kind, Value) 唯一；按 (Kind, Value) 建索引，用于按配置或难度描述符筛选谱面
+type AnalysisTag struct {
+	ID       uint   `gorm:"primarykey" json:"-"`
+	ResultID uint   `gorm:"uniqueIndex:idx_analysis_tags_result_kind_value,priority:1" json:"-"`
+	Kind     string `gorm:"uniqueIndex:idx_analysis_tags_result_kind_value,priority:2;index:idx_analysis_tags_kind_value,priority:1" json:"kind"`
+	Value    string `gorm:"uniqueIndex:idx_analysis_tags_result_kind_value,priority:3;index:idx_analysis_tags_kind_value,priority:2" json:"value"`
+}
+
+// NewAnalysisTags 将一组标签值转换为指定类别的 AnalysisTag，去除首尾空白、空值和重复值
+func NewAnalysisTags(kind string, values []string) []AnalysisTag {
+	tags := make([]AnalysisTag, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		tags = append(tags, AnalysisTag{Kind: kind, Value: v})
+	}
+	return tags
+}
+
+// ChartReport 谱面顾问针对单个谱面生成的结构化报告
+type ChartReport struct {
+	KeySections      []ChartSection `json:"key_sections"`      // 关键段落 (难点、杀点)
+	RecommendedLevel LevelRange     `json:"recommended_level"` // 推荐挑战的玩家水平
+	PracticeTips     []string       `json:"practice_tips"`     // 练习建议
+	Tricks           []string       `json:"tricks"`            // 配置技巧 (如拆法、手法)
+}
+
+// ChartSection 谱面中的一个关键段落
+type ChartSection struct {
+	Name        string `json:"name"`        // 段落名称，如 "尾杀"、"中段交互"
+	Description string `json:"description"` // 段落的配置与难点
+}
+
+// LevelRange 玩家水平区间，以玩家能稳定 SSS 的谱面等级表示 (如 "13+" ~ "14")
+type LevelRange struct {
+	Min string `json:"min"`
+	Max string `json:"max"`
 }
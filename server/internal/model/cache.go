@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// LLMCacheEntry 确定性 LLM 调用的缓存响应
+// Key 为路由的模型与提示词的 SHA-256，PromptVersion 为写入时 prompts.yaml 的版本
+type LLMCacheEntry struct {
+	Key           string    `gorm:"primaryKey;size:64" json:"key"`
+	Model         string    `json:"model"`
+	PromptVersion string    `gorm:"index" json:"prompt_version"`
+	Response      string    `gorm:"type:text" json:"response"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `gorm:"index" json:"expires_at"`
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // SongFilter 定义了歌曲查询的过滤条件
 type SongFilter struct {
 	Version  string  `form:"version"`
@@ -18,3 +20,164 @@ type SongListResponse struct {
 	Total int64  `json:"total"`
 	Items []Song `json:"items"`
 }
+
+// JobFilter 定义了作业查询的过滤条件
+type JobFilter struct {
+	Type     string `form:"type"`
+	Status   string `form:"status"`
+	Page     int    `form:"page,default=1"`
+	PageSize int    `form:"page_size,default=20"`
+}
+
+// JobListResponse 定义了作业列表的返回结构
+type JobListResponse struct {
+	Total int64 `json:"total"`
+	Items []Job `json:"items"`
+}
+
+// CommentSearchFilter 定义了评论搜索的条件
+type CommentSearchFilter struct {
+	Query    string `form:"q" binding:"required"` // 搜索词，多个词以空格分隔，需同时匹配
+	SongID   uint   `form:"song_id"`
+	Source   string `form:"source"` // Bilibili, Tieba, etc.
+	Page     int    `form:"page,default=1"`
+	PageSize int    `form:"page_size,default=20"`
+}
+
+// CommentSearchHit 评论搜索结果，Score 越大越相关
+type CommentSearchHit struct {
+	Comment
+	Score float64 `json:"score"`
+}
+
+// CommentSearchResponse 定义了评论搜索的返回结构
+type CommentSearchResponse struct {
+	Total int64              `json:"total"`
+	Items []CommentSearchHit `json:"items"`
+}
+
+// ChartChangeFilter 定义了定数变更查询的过滤条件
+type ChartChangeFilter struct {
+	Since    time.Time `form:"-"` // 只返回该时间之后检测到的变更，零值表示不限
+	SongID   uint      `form:"song_id"`
+	Page     int       `form:"page,default=1"`
+	PageSize int       `form:"page_size,default=20"`
+}
+
+// ChartChangeItem 定数变更事件及所属歌曲的基本信息
+type ChartChangeItem struct {
+	ChartChange
+	GameID int    `json:"game_id"`
+	Title  string `json:"title"`
+	Type   string `json:"type"`
+}
+
+// ChartChangeListResponse 定义了定数变更列表的返回结构
+type ChartChangeListResponse struct {
+	Total int64             `json:"total"`
+	Items []ChartChangeItem `json:"items"`
+}
+
+// ChartTimeline 单个谱面的历史：定数变更事件与数据快照，均按时间正序排列
+type ChartTimeline struct {
+	Chart     Chart           `json:"chart"`
+	Changes   []ChartChange   `json:"changes"`
+	Snapshots []ChartSnapshot `json:"snapshots"`
+}
+
+// ChartSearchFilter 定义了按分析结果搜索谱面的条件
+// 分析条件以每个谱面最新一次的分析结果为准 (没有时使用所属歌曲最新的总览结果)，
+// pattern / tag 按子串匹配，多个需同时满足
+type ChartSearchFilter struct {
+	Difficulty string   `form:"difficulty"` // Basic, Advanced, Expert, Master, Re:Master
+	Level      string   `form:"level"`      // 13, 13+, 14, etc.
+	MinDS      float64  `form:"min_ds"`
+	MaxDS      float64  `form:"max_ds"`
+	Type       string   `form:"type"`    // DX or SD
+	Patterns   []string `form:"pattern"` // 谱面配置标签，如 "转圈"
+	Tags       []string `form:"tag"`     // 难度描述符标签，如 "逆诈称"
+	Sentiment  string   `form:"sentiment"`
+	MinDelta   *float64 `form:"min_delta"` // 拟合定数与定数之差 (fit_diff - ds) 的下限，设置后只返回有拟合定数的谱面
+	MaxDelta   *float64 `form:"max_delta"` // 拟合定数与定数之差的上限
+	Page       int      `form:"page,default=1"`
+	PageSize   int      `form:"page_size,default=20"`
+}
+
+// ChartSearchItem 谱面搜索结果：谱面数据、所属歌曲的基本信息与最新分析结果的摘要
+type ChartSearchItem struct {
+	Chart
+	GameID       int           `json:"game_id"`
+	Title        string        `json:"title"`
+	Type         string        `json:"type"`
+	FitDiffDelta float64       `json:"fit_diff_delta"` // fit_diff - ds，正数表示实际难度高于定数
+	ResultID     uint          `json:"result_id"`
+	ResultType   string        `json:"result_type"` // chart: 谱面自身的分析结果; song: 谱面没有单独结果时使用的歌曲总览
+	Summary      string        `json:"summary"`
+	Sentiment    string        `json:"sentiment"`
+	Tags         []AnalysisTag `json:"tags" gorm:"-"`
+}
+
+// ChartSearchResponse 定义了谱面搜索的返回结构
+type ChartSearchResponse struct {
+	Total int64             `json:"total"`
+	Items []ChartSearchItem `json:"items"`
+}
+
+// LLMUsageFilter 定义了 LLM 用量查询的条件，日期为本地日期 YYYY-MM-DD (含首尾)
+type LLMUsageFilter struct {
+	From   string `form:"from"`
+	To     string `form:"to"`
+	Role   string `form:"role"`
+	SongID uint   `form:"song_id"`
+	JobID  uint   `form:"job_id"`
+}
+
+// LLMUsageTotals 一组调用的用量合计
+type LLMUsageTotals struct {
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Add 累加另一组用量
+func (t *LLMUsageTotals) Add(o LLMUsageTotals) {
+	t.Calls += o.Calls
+	t.PromptTokens += o.PromptTokens
+	t.CompletionTokens += o.CompletionTokens
+	t.TotalTokens += o.TotalTokens
+	t.Cost += o.Cost
+}
+
+// LLMUsageDay 单日用量合计
+type LLMUsageDay struct {
+	Day string `json:"day"`
+	LLMUsageTotals
+}
+
+// LLMUsageRole 单个角色的用量合计
+type LLMUsageRole struct {
+	Role string `json:"role"`
+	LLMUsageTotals
+}
+
+// LLMBudgetStatus 今日用量与每日预算，预算为 0 表示不限制
+type LLMBudgetStatus struct {
+	Day              string  `json:"day"`
+	DailyBudget      float64 `json:"daily_budget"`
+	DailyTokenBudget int64   `json:"daily_token_budget"`
+	Cost             float64 `json:"cost"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Exceeded         bool    `json:"exceeded"` // 超出预算时分析作业暂停到次日
+}
+
+// LLMUsageResponse 定义了 LLM 用量统计的返回结构
+type LLMUsageResponse struct {
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Total  LLMUsageTotals  `json:"total"`
+	ByDay  []LLMUsageDay   `json:"by_day"`
+	ByRole []LLMUsageRole  `json:"by_role"`
+	Budget LLMBudgetStatus `json:"budget"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 作业类型
+const (
+	JobTypeCollect  = "collect"
+	JobTypeBackfill = "backfill"
+	JobTypeAnalysis = "analysis"
+)
+
+// 作业状态
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusPaused  = "paused" // 分析作业因超出每日 LLM 预算暂停，见 ResumeAt
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+// Job 代表一次由 API 触发的采集或分析作业，用于追踪整体进度
+type Job struct {
+	gorm.Model
+	Type       string     `gorm:"index" json:"type"`   // collect, backfill, analysis
+	Status     string     `gorm:"index" json:"status"` // pending, running, paused, done, failed
+	Total      int        `json:"total"`               // 子项总数
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ResumeAt   *time.Time `json:"resume_at,omitempty"` // 暂停中的作业预计恢复的时间
+	Items      []JobItem  `json:"items,omitempty"`
+}
+
+// JobItem 记录作业中单个子项（一首歌曲或一个关键词）的执行结果
+type JobItem struct {
+	gorm.Model
+	JobID      uint       `gorm:"index" json:"job_id"`
+	SongID     uint       `json:"song_id,omitempty"`
+	GameID     int        `json:"game_id,omitempty"`
+	Keyword    string     `json:"keyword,omitempty"`
+	Status     string     `json:"status"` // done, failed
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+}
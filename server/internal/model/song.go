@@ -1,25 +1,27 @@
 package model
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 // Song 代表音乐游戏中的一首歌曲
 type Song struct {
 	gorm.Model
-	GameID      int         `gorm:"uniqueIndex" json:"id"` // 来自 Diving-Fish 的 ID
-	Title       string      `gorm:"index" json:"title"`
-	Type        string      `json:"type"` // DX or 标准
-	Artist      string      `json:"artist"`
-	Genre       string      `json:"genre"`
-	BPM         float64     `json:"bpm"`
-	ReleaseDate string      `json:"release_date"`
-	Version     string      `json:"version"` // 乐曲更新版本
-	IsNew       bool        `json:"is_new"`
-	CoverURL    string      `json:"cover_url"`
-	LastScraped *string     `json:"last_scraped"` // 上次采集时间 (ISO8601 字符串或时间戳)
-	Charts      []Chart     `json:"charts,omitempty"`
-	Aliases     []SongAlias `json:"aliases,omitempty"`
+	GameID        int         `gorm:"uniqueIndex" json:"id"` // 来自 Diving-Fish 的 ID
+	Title         string      `gorm:"index" json:"title"`
+	Type          string      `json:"type"` // DX or 标准
+	Artist        string      `json:"artist"`
+	Genre         string      `json:"genre"`
+	BPM           float64     `json:"bpm"`
+	ReleaseDate   string      `json:"release_date"`
+	Version       string      `json:"version"` // 乐曲更新版本
+	IsNew         bool        `json:"is_new"`
+	CoverURL      string      `json:"cover_url"`
+	LastScrapedAt *time.Time  `json:"last_scraped_at"` // 上次成功采集的时间
+	Charts        []Chart     `json:"charts,omitempty"`
+	Aliases       []SongAlias `json:"aliases,omitempty"`
 }
 
 type SongAlias struct {
@@ -30,14 +32,15 @@ type SongAlias struct {
 }
 
 // Chart 代表歌曲的特定难度谱面
+// 同一歌曲的同一难度只有一条记录，同步时原地更新，ID 保持不变
 type Chart struct {
 	gorm.Model
-	SongID         uint    `gorm:"index" json:"song_id"`
-	Difficulty     string  `json:"difficulty"` // Basic, Advanced, Expert, Master, Re:Master
-	Level          string  `json:"level"`      // 13, 13+, 14, etc.
-	DS             float64 `json:"ds"`         // Internal decimal level
-	Notes          string  `json:"notes"`      // JSON array: [tap, hold, slide, touch, break]
-	Charter        string  `json:"charter"`    // NotesDesigner
+	SongID         uint    `gorm:"index;uniqueIndex:idx_charts_song_difficulty" json:"song_id"`
+	Difficulty     string  `gorm:"uniqueIndex:idx_charts_song_difficulty" json:"difficulty"` // Basic, Advanced, Expert, Master, Re:Master
+	Level          string  `json:"level"`                                                    // 13, 13+, 14, etc.
+	DS             float64 `json:"ds"`                                                       // Internal decimal level
+	Notes          string  `json:"notes"`                                                    // JSON array: [tap, hold, slide, touch, break]
+	Charter        string  `json:"charter"`                                                  // NotesDesigner
 	FitDiff        float64 `json:"fit_diff"`
 	AvgAchievement float64 `json:"avg_achievement"`
 	AvgDX          float64 `json:"avg_dx"`
@@ -45,3 +48,30 @@ type Chart struct {
 	SampleCount    int     `json:"sample_count"`
 	Song           Song    `json:"-"`
 }
+
+// ChartSnapshot 谱面数据的历史快照
+// 谱面首次同步以及定数、等级或统计数据发生变化时各记录一条，CreatedAt 即同步时间
+type ChartSnapshot struct {
+	gorm.Model
+	ChartID        uint    `gorm:"index" json:"chart_id"`
+	Level          string  `json:"level"`
+	DS             float64 `json:"ds"`
+	FitDiff        float64 `json:"fit_diff"`
+	AvgAchievement float64 `json:"avg_achievement"`
+	AvgDX          float64 `json:"avg_dx"`
+	StdDev         float64 `json:"std_dev"`
+	SampleCount    int     `json:"sample_count"`
+}
+
+// ChartChange 谱面定数或等级的变更事件 (定数变更)
+// 同步时已有谱面的 DS 或 Level 与上次不同则记录一条，CreatedAt 即检测到变更的时间
+type ChartChange struct {
+	gorm.Model
+	ChartID    uint    `gorm:"index" json:"chart_id"`
+	SongID     uint    `gorm:"index" json:"song_id"`
+	Difficulty string  `json:"difficulty"`
+	OldLevel   string  `json:"old_level"`
+	NewLevel   string  `json:"new_level"`
+	OldDS      float64 `json:"old_ds"`
+	NewDS      float64 `json:"new_ds"`
+}
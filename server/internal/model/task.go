@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 任务状态
+const (
+	TaskStatusPending = "pending"
+	TaskStatusRunning = "running"
+	TaskStatusDone    = "done"
+	TaskStatusFailed  = "failed"
+)
+
+// Task 代表调度器持久化队列中的一条采集任务
+type Task struct {
+	gorm.Model
+	JobID       uint       `gorm:"index" json:"job_id"` // 所属作业ID
+	Keyword     string     `json:"keyword"`
+	Source      string     `json:"source"`                   // 可选：指定采集器名称
+	SongID      uint       `gorm:"index" json:"song_id"`     // 可选：关联的歌曲ID
+	Status      string     `gorm:"index" json:"status"`      // pending, running, done, failed
+	Attempts    int        `json:"attempts"`                 // 已尝试次数
+	MaxAttempts int        `json:"max_attempts"`             // 最大尝试次数
+	LastError   string     `json:"last_error"`               // 最近一次失败的错误信息
+	DoneSources string     `json:"done_sources,omitempty"`   // 已完成的采集器名称 (逗号分隔)，重试时跳过
+	NextRunAt   time.Time  `gorm:"index" json:"next_run_at"` // 最早可执行时间 (用于失败重试退避)
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
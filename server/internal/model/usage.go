@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// LLMUsage 按天聚合的 LLM 用量
+// 同一天内角色、提供方、模型、歌曲和作业都相同的调用累加到同一行
+type LLMUsage struct {
+	ID               uint      `gorm:"primarykey" json:"id"`
+	Day              string    `gorm:"size:10;uniqueIndex:idx_llm_usages_key,priority:1" json:"day"` // 本地日期 YYYY-MM-DD
+	Role             string    `gorm:"uniqueIndex:idx_llm_usages_key,priority:2" json:"role"`
+	Provider         string    `gorm:"uniqueIndex:idx_llm_usages_key,priority:3" json:"provider"`
+	Model            string    `gorm:"uniqueIndex:idx_llm_usages_key,priority:4" json:"model"`
+	SongID           uint      `gorm:"uniqueIndex:idx_llm_usages_key,priority:5" json:"song_id"` // 0 表示不属于某首歌曲
+	JobID            uint      `gorm:"uniqueIndex:idx_llm_usages_key,priority:6" json:"job_id"`  // 0 表示不属于某个作业
+	Calls            int64     `json:"calls"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	Cost             float64   `json:"cost"` // 按配置的单价估算
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -15,5 +15,9 @@ type Video struct {
 	Description string    `json:"description"`
 	Author      string    `json:"author"`
 	URL         string    `json:"url"`
-	PublishTime time.Time `json:"publish_time"`
+	PublishTime time.Time `gorm:"index" json:"publish_time"`
+	Views       int64     `json:"views"`    // 播放量
+	Likes       int64     `json:"likes"`    // 点赞数
+	Tags        string    `json:"tags"`     // 视频标签，逗号分隔
+	Duration    int       `json:"duration"` // 时长 (秒)
 }
@@ -0,0 +1,218 @@
+package progress
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 分析流程的阶段事件
+const (
+	StageStarted        = "started"         // 作业开始执行
+	StageCommentsLoaded = "comments_loaded" // 评论加载完成
+	StageBucketed       = "bucketed"        // 评论清洗与分桶完成
+	StageChartAnalyzed  = "chart_analyzed"  // 单个谱面分析完成
+	StageAdvisorDone    = "advisor_done"    // 顾问报告生成完成
+	StageSaved          = "saved"           // 分析结果已保存
+	StageSongFailed     = "song_failed"     // 单首歌曲分析失败
+	StageBudgetPaused   = "budget_paused"   // 今日 LLM 用量超出预算，作业暂停到次日
+	StageBudgetResumed  = "budget_resumed"  // 预算重置，作业继续执行
+	StageAdvisorDelta   = "advisor_delta"   // 顾问报告摘要的增量文本 (只推送到摘要流)
+	StageAdvisorSummary = "advisor_summary" // 顾问报告摘要生成完成 (只推送到摘要流)
+	StageCompleted      = "completed"       // 作业结束 (终止事件)
+	StageFailed         = "failed"          // 作业失败 (终止事件)
+)
+
+// historyRetention 作业结束后保留事件历史的时长，便于晚到的订阅者回放
+const historyRetention = 10 * time.Minute
+
+// Event 代表作业执行过程中的一个进度事件
+type Event struct {
+	JobID   uint                   `json:"job_id"`
+	SongID  uint                   `json:"song_id,omitempty"`
+	Stage   string                 `json:"stage"`
+	Message string                 `json:"message,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Time    time.Time              `json:"time"`
+}
+
+// IsTerminal 判断事件是否表示作业已经结束
+func (e Event) IsTerminal() bool {
+	return e.Stage == StageCompleted || e.Stage == StageFailed
+}
+
+type stream struct {
+	history  []Event
+	deltas   []Event // 尚未完成的目标各自最新的一条增量事件，不计入 history
+	subs     map[chan Event]struct{}
+	finished bool
+}
+
+// record 将事件加入历史
+// 增量事件 (advisor_delta) 携带了截至当前的全文，每个目标只保留最新一条；
+// 该目标的摘要完成 (advisor_summary) 或作业结束后不再保留，避免长作业的历史随增量无限增长
+func (st *stream) record(e Event) {
+	switch {
+	case e.Stage == StageAdvisorDelta:
+		key := targetKey(e)
+		for i := range st.deltas {
+			if targetKey(st.deltas[i]) == key {
+				st.deltas[i] = e
+				return
+			}
+		}
+		st.deltas = append(st.deltas, e)
+		return
+	case e.Stage == StageAdvisorSummary:
+		key := targetKey(e)
+		for i := range st.deltas {
+			if targetKey(st.deltas[i]) == key {
+				st.deltas = append(st.deltas[:i], st.deltas[i+1:]...)
+				break
+			}
+		}
+	case e.IsTerminal():
+		st.deltas = nil
+	}
+	st.history = append(st.history, e)
+}
+
+// replay 返回晚到的订阅者需要回放的事件
+func (st *stream) replay() []Event {
+	events := make([]Event, 0, len(st.history)+len(st.deltas))
+	events = append(events, st.history...)
+	return append(events, st.deltas...)
+}
+
+// targetKey 增量与摘要事件所属的分析目标 (歌曲或谱面)
+func targetKey(e Event) string {
+	return fmt.Sprintf("%d/%v/%v", e.SongID, e.Data["target_type"], e.Data["target_id"])
+}
+
+// Hub 在内存中按作业ID分发进度事件
+type Hub struct {
+	mu      sync.Mutex
+	streams map[uint]*stream
+}
+
+func NewHub() *Hub {
+	return &Hub{streams: make(map[uint]*stream)}
+}
+
+func (h *Hub) getStream(jobID uint) *stream {
+	st, ok := h.streams[jobID]
+	if !ok {
+		st = &stream{subs: make(map[chan Event]struct{})}
+		h.streams[jobID] = st
+	}
+	return st
+}
+
+// Publish 发布事件，终止事件会关闭该作业的所有订阅
+func (h *Hub) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	st := h.getStream(e.JobID)
+	if st.finished {
+		return
+	}
+	st.record(e)
+
+	for ch := range st.subs {
+		select {
+		case ch <- e:
+		default:
+			// 订阅者消费过慢，丢弃事件以免阻塞分析流程
+		}
+	}
+
+	if e.IsTerminal() {
+		st.finished = true
+		for ch := range st.subs {
+			close(ch)
+		}
+		st.subs = nil
+
+		jobID := e.JobID
+		time.AfterFunc(historyRetention, func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.streams, jobID)
+		})
+	}
+}
+
+// Subscribe 订阅作业的事件，返回已有的历史事件和实时事件通道
+// 如果作业已经结束，返回的通道为 nil。调用方结束订阅时必须调用 cancel
+func (h *Hub) Subscribe(jobID uint) (history []Event, events <-chan Event, cancel func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	st := h.getStream(jobID)
+	history = st.replay()
+	if st.finished {
+		return history, nil, func() {}
+	}
+
+	ch := make(chan Event, 64)
+	st.subs[ch] = struct{}{}
+	cancel = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := st.subs[ch]; ok {
+			delete(st.subs, ch)
+			close(ch)
+		}
+		// 没有任何事件的空订阅不保留
+		if len(st.subs) == 0 && len(st.history) == 0 && len(st.deltas) == 0 && h.streams[jobID] == st {
+			delete(h.streams, jobID)
+		}
+	}
+	return history, ch, cancel
+}
+
+type contextKey string
+
+const reporterContextKey contextKey = "progress_reporter"
+
+// Reporter 接收分析流程中的阶段事件
+type Reporter func(stage, message string, data map[string]interface{})
+
+// WithReporter 将进度汇报函数注入 context
+func WithReporter(ctx context.Context, r Reporter) context.Context {
+	return context.WithValue(ctx, reporterContextKey, r)
+}
+
+// Report 向 context 中的汇报函数发送阶段事件，未注入时不做任何事
+func Report(ctx context.Context, stage, message string, data map[string]interface{}) {
+	if r, ok := ctx.Value(reporterContextKey).(Reporter); ok && r != nil {
+		r(stage, message, data)
+	}
+}
+
+const streamReporterContextKey contextKey = "progress_stream_reporter"
+
+// WithStreamReporter 注入接收流式输出 (如顾问摘要的增量文本) 的汇报函数
+// 与 WithReporter 分开，避免高频的增量事件挤占阶段事件
+func WithStreamReporter(ctx context.Context, r Reporter) context.Context {
+	return context.WithValue(ctx, streamReporterContextKey, r)
+}
+
+// Streaming 判断 context 中是否注入了流式输出的汇报函数，未注入时调用方无需以流式方式请求 LLM
+func Streaming(ctx context.Context) bool {
+	r, ok := ctx.Value(streamReporterContextKey).(Reporter)
+	return ok && r != nil
+}
+
+// ReportStream 向 context 中的流式输出汇报函数发送事件，未注入时不做任何事
+func ReportStream(ctx context.Context, stage, message string, data map[string]interface{}) {
+	if r, ok := ctx.Value(streamReporterContextKey).(Reporter); ok && r != nil {
+		r(stage, message, data)
+	}
+}
@@ -0,0 +1,95 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+)
+
+func deltaEvent(songID, chartID uint, text string) Event {
+	return Event{JobID: 1, SongID: songID, Stage: StageAdvisorDelta, Data: map[string]interface{}{
+		"target_type": "chart", "target_id": chartID, "text": text,
+	}}
+}
+
+func summaryEvent(songID, chartID uint, summary string) Event {
+	return Event{JobID: 1, SongID: songID, Stage: StageAdvisorSummary, Data: map[string]interface{}{
+		"target_type": "chart", "target_id": chartID, "summary": summary,
+	}}
+}
+
+// stages 以 "阶段:文本" 的形式描述回放的事件
+func stages(events []Event) string {
+	var parts []string
+	for _, e := range events {
+		text, _ := e.Data["text"].(string)
+		if summary, ok := e.Data["summary"].(string); ok {
+			text = summary
+		}
+		parts = append(parts, e.Stage+":"+text)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestHubCompactsAdvisorDeltas(t *testing.T) {
+	h := NewHub()
+
+	h.Publish(Event{JobID: 1, Stage: StageStarted})
+	h.Publish(deltaEvent(7, 501, "尾"))
+	h.Publish(deltaEvent(7, 501, "尾杀"))
+	h.Publish(deltaEvent(7, 501, "尾杀决定成绩"))
+
+	// 进行中的目标只回放最新的一条增量
+	history, _, cancel := h.Subscribe(1)
+	cancel()
+	if got, want := stages(history), "started: advisor_delta:尾杀决定成绩"; got != want {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+
+	// 摘要完成后不再保留该目标的增量
+	h.Publish(summaryEvent(7, 501, "尾杀决定成绩。"))
+	h.Publish(deltaEvent(7, 502, "交互"))
+	history, events, cancel := h.Subscribe(1)
+	defer cancel()
+	if got, want := stages(history), "started: advisor_summary:尾杀决定成绩。 advisor_delta:交互"; got != want {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+
+	// 实时订阅者仍收到每一条增量
+	h.Publish(deltaEvent(7, 502, "交互很多"))
+	if e := <-events; e.Stage != StageAdvisorDelta || e.Data["text"] != "交互很多" {
+		t.Errorf("live event = %+v, want delta 交互很多", e)
+	}
+
+	// 作业结束后未完成的增量也不再保留
+	h.Publish(Event{JobID: 1, Stage: StageCompleted})
+	history, events, _ = h.Subscribe(1)
+	if events != nil {
+		t.Error("Subscribe() after completion returned a live channel")
+	}
+	if got, want := stages(history), "started: advisor_summary:尾杀决定成绩。 completed:"; got != want {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+}
+
+func TestHubSubscribeFinished(t *testing.T) {
+	h := NewHub()
+	history, events, cancel := h.Subscribe(2)
+	if len(history) != 0 || events == nil {
+		t.Fatalf("Subscribe() = %v, %v, want empty history and live channel", history, events)
+	}
+
+	h.Publish(Event{JobID: 2, Stage: StageFailed, Message: "boom"})
+	if e, ok := <-events; !ok || e.Stage != StageFailed {
+		t.Errorf("event = %+v, %v, want failed", e, ok)
+	}
+	if _, ok := <-events; ok {
+		t.Error("channel not closed after terminal event")
+	}
+	cancel()
+
+	// 结束后发布的事件被忽略
+	h.Publish(Event{JobID: 2, Stage: StageStarted})
+	if history, _, _ := h.Subscribe(2); len(history) != 1 {
+		t.Errorf("history = %+v, want only the terminal event", history)
+	}
+}
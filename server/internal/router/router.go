@@ -9,7 +9,7 @@ import (
 	"github.com/xumoe-c/maiecho/server/internal/service"
 )
 
-func NewRouter(songService service.SongService, collectorService service.CollectorService, analysisService *service.AnalysisService) *gin.Engine {
+func NewRouter(songService service.SongService, collectorService service.CollectorService, analysisService *service.AnalysisService, jobService service.JobService, commentService service.CommentService, chartService service.ChartService, usageService service.UsageService) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 
@@ -21,6 +21,10 @@ func NewRouter(songService service.SongService, collectorService service.Collect
 	collectorController := controller.NewCollectorController(collectorService)
 	analysisController := controller.NewAnalysisController(analysisService)
 	statusController := controller.NewStatusController()
+	jobController := controller.NewJobController(jobService)
+	commentController := controller.NewCommentController(commentService)
+	chartController := controller.NewChartController(chartService)
+	usageController := controller.NewUsageController(usageService)
 
 	v1 := r.Group("/api/v1")
 	{
@@ -33,12 +37,32 @@ func NewRouter(songService service.SongService, collectorService service.Collect
 		v1.POST("/songs/sync", songController.SyncSongs)
 		v1.POST("/songs/aliases/refresh", songController.RefreshAliases)
 
+		v1.GET("/charts/changes", chartController.ListChartChanges)
+		v1.GET("/charts/search", chartController.SearchCharts)
+		v1.GET("/charts/:id/timeline", chartController.GetChartTimeline)
+
+		v1.GET("/comments/search", commentController.SearchComments)
+
 		v1.POST("/collect", collectorController.TriggerCollection)
 		v1.POST("/collect/backfill", collectorController.BackfillCollection)
 
 		v1.POST("/analysis/songs/:id", analysisController.AnalyzeSong)
 		v1.POST("/analysis/batch", analysisController.BatchAnalyzeSongs)
 		v1.GET("/analysis/songs/:id", analysisController.GetAnalysisResult)
+
+		v1.GET("/llm/usage", usageController.GetLLMUsage)
+
+		v1.GET("/jobs", jobController.ListJobs)
+		v1.GET("/jobs/:id", jobController.GetJob)
+		v1.GET("/jobs/:id/events", jobController.StreamJobEvents)
+		v1.GET("/jobs/:id/summary", jobController.StreamJobSummary)
+
+		// Admin
+		admin := v1.Group("/admin")
+		{
+			admin.GET("/collectors", collectorController.ListCollectors)
+			admin.POST("/collectors/:name/reset", collectorController.ResetCollectorBan)
+		}
 	}
 
 	return r
@@ -2,48 +2,76 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xumoe-c/maiecho/server/internal/collector"
+	"github.com/xumoe-c/maiecho/server/internal/llm"
 	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/status"
 	"github.com/xumoe-c/maiecho/server/internal/storage"
 )
 
+// ErrUnknownSource 任务指定的采集器未注册或未启用
+var ErrUnknownSource = errors.New("unknown collector source")
+
 type Task struct {
+	JobID   uint // 可选：所属作业ID，用于汇报进度
 	Keyword string
 	Source  string // 可选：指定采集器名称以仅使用特定采集器
 	SongID  uint   // 可选：关联的歌曲ID
 }
 
-type contextKey string
-
-const songIDContextKey contextKey = "song_id"
+const (
+	// defaultMaxAttempts 任务失败后的最大尝试次数
+	defaultMaxAttempts = 3
+	// pollInterval 队列为空时 Worker 轮询数据库的间隔
+	pollInterval = 5 * time.Second
+	// collectorPace 每次调用采集器之前的等待时间
+	collectorPace = 5 * time.Second
+)
 
 type Scheduler struct {
-	taskQueue   chan Task
 	collectors  []collector.Collector
 	storage     storage.Storage
 	workerCount int
-	wg          sync.WaitGroup
-	ctx         context.Context
-	cancel      context.CancelFunc
+	notify      chan struct{} // 有新任务入队时唤醒空闲的 Worker
+	busyWorkers int32         // 正在执行任务的 Worker 数量
+	pace        time.Duration // 每次调用采集器之前的等待时间
+	// startSpinner 创建任务的终端加载动画，测试中替换为不输出的实现
+	startSpinner func(text string) *logger.Spinner
+	wg           sync.WaitGroup
+	ctx          context.Context
+	cancel       context.CancelFunc
 }
 
-func NewScheduler(collectors []collector.Collector, s storage.Storage, workerCount int, bufferSize int) *Scheduler {
+func NewScheduler(collectors []collector.Collector, s storage.Storage, workerCount int) *Scheduler {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
-		taskQueue:   make(chan Task, bufferSize),
-		collectors:  collectors,
-		storage:     s,
-		workerCount: workerCount,
-		ctx:         ctx,
-		cancel:      cancel,
+		collectors:   collectors,
+		storage:      s,
+		workerCount:  workerCount,
+		notify:       make(chan struct{}, 1),
+		pace:         collectorPace,
+		startSpinner: logger.StartSpinner,
+		ctx:          ctx,
+		cancel:       cancel,
 	}
 }
 
 func (s *Scheduler) Start() {
+	// 恢复上次退出时中断的任务
+	if n, err := s.storage.ResetRunningTasks(); err != nil {
+		logger.Error("恢复中断任务失败", "module", "scheduler", "error", err)
+	} else if n > 0 {
+		logger.Info("已恢复中断的任务", "module", "scheduler", "count", n)
+	}
+
 	for i := 0; i < s.workerCount; i++ {
 		s.wg.Add(1)
 		go s.worker(i)
@@ -54,75 +82,380 @@ func (s *Scheduler) Start() {
 func (s *Scheduler) Stop() {
 	s.cancel()
 	s.wg.Wait()
-	// TODO:清空队列？还是直接关闭。
-	// 目前，我们只是停止处理。
+	// 未完成的任务保留在数据库中，下次启动时继续执行
 	logger.Info("调度器停止", "module", "scheduler")
 }
 
-func (s *Scheduler) AddTask(task Task) {
+// HasCollector 判断指定名称的采集器是否已启用
+func (s *Scheduler) HasCollector(name string) bool {
+	for _, c := range s.collectors {
+		if c.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTask 将任务持久化到队列中，指定的 Source 必须是已启用的采集器
+func (s *Scheduler) AddTask(task Task) error {
+	if task.Source != "" && !s.HasCollector(task.Source) {
+		return fmt.Errorf("%w: %s", ErrUnknownSource, task.Source)
+	}
+
+	record := &model.Task{
+		JobID:       task.JobID,
+		Keyword:     task.Keyword,
+		Source:      task.Source,
+		SongID:      task.SongID,
+		Status:      model.TaskStatusPending,
+		MaxAttempts: defaultMaxAttempts,
+		NextRunAt:   time.Now(),
+	}
+	if err := s.storage.CreateTask(record); err != nil {
+		logger.Error("任务入队失败", "module", "scheduler", "keyword", task.Keyword, "error", err)
+		return fmt.Errorf("任务入队失败: %w", err)
+	}
+
+	logger.Info("任务已添加到队列", "module", "scheduler", "taskID", record.ID, "keyword", task.Keyword)
+
+	// 非阻塞地唤醒一个空闲 Worker
 	select {
-	case s.taskQueue <- task:
-		logger.Info("任务已添加到队列", "module", "scheduler", "keyword", task.Keyword)
+	case s.notify <- struct{}{}:
 	default:
-		logger.Warn("任务队列已满，丢弃任务", "module", "scheduler", "keyword", task.Keyword)
 	}
+	return nil
+}
+
+// Stats 返回调度器当前的队列与 Worker 状态
+func (s *Scheduler) Stats() status.SchedulerStatus {
+	stats := status.SchedulerStatus{
+		Workers:     s.workerCount,
+		BusyWorkers: int(atomic.LoadInt32(&s.busyWorkers)),
+	}
+
+	counts, err := s.storage.CountTasksByStatus()
+	if err != nil {
+		logger.Error("统计任务队列失败", "module", "scheduler", "error", err)
+		return stats
+	}
+	stats.QueueLength = counts[model.TaskStatusPending]
+	stats.RunningTasks = counts[model.TaskStatusRunning]
+	stats.FailedTasks = counts[model.TaskStatusFailed]
+	return stats
 }
 
 func (s *Scheduler) worker(id int) {
 	defer s.wg.Done()
 	logger.Debug("任务启动", "module", "scheduler", "worker_id", id)
 
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
 	for {
+		if s.ctx.Err() != nil {
+			logger.Debug("任务停止", "module", "scheduler", "worker_id", id)
+			return
+		}
+
+		task, err := s.storage.ClaimNextTask()
+		if err != nil {
+			logger.Error("领取任务失败", "module", "scheduler", "worker_id", id, "error", err)
+		}
+		if task != nil {
+			atomic.AddInt32(&s.busyWorkers, 1)
+			s.runTask(id, task)
+			atomic.AddInt32(&s.busyWorkers, -1)
+			continue
+		}
+
+		// 队列为空，等待新任务或下一次轮询
 		select {
 		case <-s.ctx.Done():
 			logger.Debug("任务停止", "module", "scheduler", "worker_id", id)
 			return
-		case task := <-s.taskQueue:
-			// 任务处理的视觉反馈
-			spinnerText := fmt.Sprintf("任务 %d 处理中: %s", id, task.Keyword)
-			spinner := logger.StartSpinner(spinnerText)
-
-			// 在所有适用的采集器上执行任务
-			for _, c := range s.collectors {
-				if task.Source != "" && c.Name() != task.Source {
-					continue
-				}
+		case <-s.notify:
+		case <-ticker.C:
+		}
+	}
+}
 
-				// 全局节奏控制：在任务之间稍作休眠以确保安全
-				// 这是对内部采集器速率限制的补充
-				time.Sleep(5 * time.Second)
+func (s *Scheduler) runTask(id int, task *model.Task) {
+	// 任务处理的视觉反馈，任务结束时根据最终状态只结束一次
+	spinner := s.startSpinner(fmt.Sprintf("任务 %d 处理中: %s", id, task.Keyword))
+	var failures []string
+	defer func() {
+		if task.Status == model.TaskStatusDone {
+			spinner.Success(fmt.Sprintf("任务 %d 完成: %s", id, task.Keyword))
+		} else {
+			spinner.Fail(fmt.Sprintf("任务 %d: %s", id, strings.Join(failures, "; ")))
+		}
+	}()
 
-				// Create a context with SongID if present
-				ctx := s.ctx
-				if task.SongID != 0 {
-					ctx = context.WithValue(ctx, songIDContextKey, task.SongID)
-				}
+	// 作业的第一个任务被领取时作业即开始运行
+	if task.JobID != 0 {
+		if err := s.storage.StartJob(task.JobID); err != nil {
+			logger.Error("更新作业状态失败", "module", "scheduler", "job_id", task.JobID, "task_id", task.ID, "error", err)
+		}
+	}
+
+	var errs []error // 封禁以外的错误
+	var banned []bannedSource
+	ran := 0
+	skipped := 0 // 之前的尝试中已完成的采集器
+	done := doneSources(task)
+
+	// 在所有适用的采集器上执行任务
+	for _, c := range s.collectors {
+		if task.Source != "" && c.Name() != task.Source {
+			continue
+		}
+		// 未指定来源时跳过只用于发现任务的采集器
+		if d, ok := c.(collector.Discoverer); ok && task.Source == "" && d.IsDiscovery() {
+			continue
+		}
+		// 重试时只执行之前失败的采集器
+		if done[c.Name()] {
+			skipped++
+			continue
+		}
+		// 全局节奏控制：在任务之间稍作休眠以确保安全
+		// 这是对内部采集器速率限制的补充
+		select {
+		case <-s.ctx.Done():
+		case <-time.After(s.pace):
+		}
+		if s.ctx.Err() != nil {
+			break
+		}
+		ran++
 
-				if err := c.Collect(ctx, task.Keyword); err != nil {
-					spinner.Fail(fmt.Sprintf("任务 %d: 采集器 %s 处理 %s 失败: %v", id, c.Name(), task.Keyword, err))
-					logger.Error("采集失败",
-						"module", "scheduler",
-						"worker_id", id,
-						"collector", c.Name(),
-						"keyword", task.Keyword,
-						"error", err,
-					)
-				} else {
-					logger.Info("采集成功",
-						"module", "scheduler",
-						"worker_id", id,
-						"collector", c.Name(),
-						"keyword", task.Keyword,
-					)
-					// 如果任务关联了 SongID，更新 LastScrapedTime
-					if task.SongID != 0 {
-						if err := s.storage.UpdateSongLastScrapedTime(task.SongID); err != nil {
-							logger.Error("更新上次采集时间失败", "module", "scheduler", "songID", task.SongID, "error", err)
-						}
+		// Create a context with SongID if present
+		ctx := llm.WithJobID(s.ctx, task.JobID)
+		if task.SongID != 0 {
+			ctx = collector.WithSongID(ctx, task.SongID)
+			ctx = llm.WithSongID(ctx, task.SongID)
+		}
+
+		if err := c.Collect(ctx, task.Keyword); err != nil {
+			if errors.Is(err, collector.ErrBanned) {
+				b := bannedSource{name: c.Name(), err: fmt.Errorf("%s: %w", c.Name(), err)}
+				if ba, ok := c.(collector.BanAware); ok {
+					if until := ba.BanState().BannedUntil; until != nil {
+						b.until = *until
 					}
 				}
+				banned = append(banned, b)
+			} else {
+				errs = append(errs, fmt.Errorf("%s: %w", c.Name(), err))
 			}
-			spinner.Success(fmt.Sprintf("任务 %d 完成: %s", id, task.Keyword))
+			failures = append(failures, fmt.Sprintf("采集器 %s 处理 %s 失败: %v", c.Name(), task.Keyword, err))
+			logger.Error("采集失败",
+				"module", "scheduler",
+				"worker_id", id,
+				"task_id", task.ID,
+				"collector", c.Name(),
+				"keyword", task.Keyword,
+				"error", err,
+			)
+		} else {
+			logger.Info("采集成功",
+				"module", "scheduler",
+				"worker_id", id,
+				"task_id", task.ID,
+				"collector", c.Name(),
+				"keyword", task.Keyword,
+			)
+			s.markSourceDone(task, c.Name())
+			// 如果任务关联了 SongID，更新 LastScrapedAt
+			if task.SongID != 0 {
+				if err := s.storage.UpdateSongLastScrapedTime(task.SongID); err != nil {
+					logger.Error("更新上次采集时间失败", "module", "scheduler", "songID", task.SongID, "error", err)
+				}
+			}
+		}
+	}
+
+	// 调度器停止导致的中断不算失败，任务放回队列，下次启动时继续执行
+	if s.ctx.Err() != nil {
+		s.requeueInterruptedTask(task)
+		failures = append(failures, fmt.Sprintf("调度器停止，%s 已放回队列", task.Keyword))
+		return
+	}
+
+	if ran == 0 {
+		// 之前失败的采集器已被禁用，剩余的采集器都已完成
+		if skipped > 0 {
+			s.finishTask(task, nil)
+			return
+		}
+		// 入队后采集器被禁用 (例如修改配置后重启) 的任务没有可执行的采集器
+		task.Attempts = task.MaxAttempts
+		s.finishTask(task, fmt.Errorf("%w: %s", ErrUnknownSource, task.Source))
+		failures = append(failures, fmt.Sprintf("没有可用的采集器处理 %s", task.Keyword))
+		return
+	}
+
+	// 执行的采集器全部处于封禁冷却：整个任务延后到冷却结束
+	if len(banned) == ran {
+		var bannedErrs []error
+		var resumeAt time.Time
+		for _, b := range banned {
+			bannedErrs = append(bannedErrs, b.err)
+			if b.until.After(resumeAt) {
+				resumeAt = b.until
+			}
+		}
+		s.deferBannedTask(task, errors.Join(bannedErrs...), resumeAt)
+		return
+	}
+
+	// 部分采集器被封禁：被封禁的来源单独追加任务延后执行，本任务重试时不再执行这些来源，
+	// 其余采集器的结果正常结束本任务
+	for _, b := range banned {
+		if s.deferBannedSource(task, b) {
+			s.markSourceDone(task, b.name)
 		}
 	}
+
+	s.finishTask(task, errors.Join(errs...))
+}
+
+// doneSources 返回任务在之前的尝试中已完成的采集器
+func doneSources(task *model.Task) map[string]bool {
+	done := make(map[string]bool)
+	for _, name := range strings.Split(task.DoneSources, ",") {
+		if name != "" {
+			done[name] = true
+		}
+	}
+	return done
+}
+
+// markSourceDone 记录任务中已完成的采集器并立即保存，进程异常退出后恢复的任务也不会重复执行
+func (s *Scheduler) markSourceDone(task *model.Task, name string) {
+	if doneSources(task)[name] {
+		return
+	}
+	if task.DoneSources != "" {
+		task.DoneSources += ","
+	}
+	task.DoneSources += name
+	if err := s.storage.UpdateTask(task); err != nil {
+		logger.Error("更新任务状态失败", "module", "scheduler", "task_id", task.ID, "error", err)
+	}
+}
+
+// bannedSource 本次执行中处于封禁冷却的采集器
+type bannedSource struct {
+	name  string
+	until time.Time // 冷却结束时间，未知时为零值
+	err   error
+}
+
+// deferBannedSource 为被封禁的采集器追加一条只使用该采集器的任务，在冷却结束后执行
+// 返回该来源是否已由追加的任务 (或已存在的相同任务) 接手
+func (s *Scheduler) deferBannedSource(task *model.Task, b bannedSource) bool {
+	nextRunAt := b.until
+	if nextRunAt.Before(time.Now()) {
+		nextRunAt = time.Now().Add(pollInterval)
+	}
+	followUp := &model.Task{
+		JobID:       task.JobID,
+		Keyword:     task.Keyword,
+		Source:      b.name,
+		SongID:      task.SongID,
+		Status:      model.TaskStatusPending,
+		MaxAttempts: defaultMaxAttempts,
+		LastError:   b.err.Error(),
+		NextRunAt:   nextRunAt,
+	}
+	created, err := s.storage.CreateFollowUpTask(followUp)
+	if err != nil {
+		logger.Error("追加封禁来源任务失败", "module", "scheduler", "task_id", task.ID, "collector", b.name, "error", err)
+		return false
+	}
+	if created {
+		logger.Warn("采集器封禁冷却中，该来源单独延后执行", "module", "scheduler", "task_id", task.ID, "follow_up_id", followUp.ID, "collector", b.name, "next_run_at", nextRunAt)
+	}
+	return true
+}
+
+// deferBannedTask 采集器处于封禁冷却时延后任务到冷却结束，不消耗重试次数
+func (s *Scheduler) deferBannedTask(task *model.Task, err error, resumeAt time.Time) {
+	if resumeAt.Before(time.Now()) {
+		resumeAt = time.Now().Add(pollInterval)
+	}
+	task.Status = model.TaskStatusPending
+	task.Attempts--
+	task.LastError = err.Error()
+	task.NextRunAt = resumeAt
+	logger.Warn("采集器封禁冷却中，任务延后执行", "module", "scheduler", "task_id", task.ID, "next_run_at", task.NextRunAt)
+
+	if err := s.storage.UpdateTask(task); err != nil {
+		logger.Error("更新任务状态失败", "module", "scheduler", "task_id", task.ID, "error", err)
+	}
+}
+
+// requeueInterruptedTask 将因调度器停止而中断的任务放回队列，归还本次消耗的尝试次数
+func (s *Scheduler) requeueInterruptedTask(task *model.Task) {
+	task.Status = model.TaskStatusPending
+	task.Attempts--
+	task.NextRunAt = time.Now()
+	logger.Info("调度器停止，任务已放回队列", "module", "scheduler", "task_id", task.ID)
+
+	if err := s.storage.UpdateTask(task); err != nil {
+		logger.Error("更新任务状态失败", "module", "scheduler", "task_id", task.ID, "error", err)
+	}
+}
+
+// finishTask 根据执行结果更新任务状态，失败的任务按指数退避重新排队
+func (s *Scheduler) finishTask(task *model.Task, err error) {
+	now := time.Now()
+	switch {
+	case err == nil:
+		task.Status = model.TaskStatusDone
+		task.LastError = ""
+		task.FinishedAt = &now
+	case task.Attempts < task.MaxAttempts:
+		task.Status = model.TaskStatusPending
+		task.LastError = err.Error()
+		task.NextRunAt = now.Add(time.Duration(task.Attempts*task.Attempts) * time.Minute)
+		logger.Warn("任务失败，稍后重试", "module", "scheduler", "task_id", task.ID, "attempts", task.Attempts, "next_run_at", task.NextRunAt)
+	default:
+		task.Status = model.TaskStatusFailed
+		task.LastError = err.Error()
+		task.FinishedAt = &now
+		logger.Error("任务重试次数耗尽", "module", "scheduler", "task_id", task.ID, "attempts", task.Attempts, "error", err)
+	}
+
+	if err := s.storage.UpdateTask(task); err != nil {
+		logger.Error("更新任务状态失败", "module", "scheduler", "task_id", task.ID, "error", err)
+	}
+
+	// 任务进入终态后向所属作业汇报结果
+	if task.JobID != 0 && task.FinishedAt != nil {
+		s.reportJobItem(task)
+	}
+}
+
+func (s *Scheduler) reportJobItem(task *model.Task) {
+	item := &model.JobItem{
+		JobID:      task.JobID,
+		SongID:     task.SongID,
+		Keyword:    task.Keyword,
+		Status:     model.JobStatusDone,
+		Error:      task.LastError,
+		StartedAt:  task.StartedAt,
+		FinishedAt: task.FinishedAt,
+	}
+	if task.Status == model.TaskStatusFailed {
+		item.Status = model.JobStatusFailed
+	}
+	if task.StartedAt != nil {
+		item.DurationMs = task.FinishedAt.Sub(*task.StartedAt).Milliseconds()
+	}
+
+	if err := s.storage.RecordJobItem(item); err != nil {
+		logger.Error("更新作业进度失败", "module", "scheduler", "job_id", task.JobID, "task_id", task.ID, "error", err)
+	}
 }
@@ -0,0 +1,409 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/collector"
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+)
+
+// fakeCollector 按 collect 函数返回结果的采集器替身
+type fakeCollector struct {
+	name    string
+	collect func(ctx context.Context, keyword string) error
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *fakeCollector) Name() string { return f.name }
+
+func (f *fakeCollector) Collect(ctx context.Context, keyword string) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, keyword)
+	f.mu.Unlock()
+	if f.collect == nil {
+		return nil
+	}
+	return f.collect(ctx, keyword)
+}
+
+func (f *fakeCollector) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func newTestDatabase(t *testing.T) *storage.Database {
+	t.Helper()
+	d, err := storage.NewDatabase(filepath.Join(t.TempDir(), "maiecho.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+	return d
+}
+
+// newTestScheduler 创建不做节奏控制、不输出加载动画的调度器
+func newTestScheduler(d *storage.Database, collectors ...collector.Collector) *Scheduler {
+	s := NewScheduler(collectors, d, 1)
+	s.pace = 0
+	s.startSpinner = func(string) *logger.Spinner { return &logger.Spinner{} }
+	return s
+}
+
+func loadTask(t *testing.T, d *storage.Database, id uint) model.Task {
+	t.Helper()
+	var task model.Task
+	if err := d.DB.First(&task, id).Error; err != nil {
+		t.Fatalf("读取任务 %d 失败: %v", id, err)
+	}
+	return task
+}
+
+// claim 将任务置为到期后领取，用于跳过重试退避
+func claim(t *testing.T, d *storage.Database) *model.Task {
+	t.Helper()
+	if err := d.DB.Model(&model.Task{}).Where("status = ?", model.TaskStatusPending).Update("next_run_at", time.Now()).Error; err != nil {
+		t.Fatalf("更新 next_run_at 失败: %v", err)
+	}
+	task, err := d.ClaimNextTask()
+	if err != nil || task == nil {
+		t.Fatalf("ClaimNextTask() = %v, %v", task, err)
+	}
+	return task
+}
+
+// waitForStatus 轮询直到任务进入指定状态
+func waitForStatus(t *testing.T, d *storage.Database, id uint, status string) model.Task {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		task := loadTask(t, d, id)
+		if task.Status == status {
+			return task
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task %d status = %s, want %s", id, task.Status, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunTask(t *testing.T) {
+	d := newTestDatabase(t)
+	ok := &fakeCollector{name: "bilibili"}
+	other := &fakeCollector{name: "tieba"}
+	s := newTestScheduler(d, ok, other)
+
+	if err := s.AddTask(Task{Keyword: "PANDORA PARADOXXX", Source: "bilibili"}); err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+	if err := s.AddTask(Task{Keyword: "x", Source: "missing"}); !errors.Is(err, ErrUnknownSource) {
+		t.Errorf("AddTask(missing source) error = %v, want ErrUnknownSource", err)
+	}
+
+	task := claim(t, d)
+	if task.Status != model.TaskStatusRunning || task.Attempts != 1 {
+		t.Fatalf("claimed task = %+v, want running with 1 attempt", task)
+	}
+	// 已领取的任务不会被再次领取
+	if again, err := d.ClaimNextTask(); err != nil || again != nil {
+		t.Fatalf("ClaimNextTask() again = %+v, %v, want nil", again, err)
+	}
+
+	s.runTask(0, task)
+	got := loadTask(t, d, task.ID)
+	if got.Status != model.TaskStatusDone || got.FinishedAt == nil || got.LastError != "" {
+		t.Errorf("task = %+v, want done", got)
+	}
+	if calls := ok.Calls(); len(calls) != 1 || calls[0] != "PANDORA PARADOXXX" {
+		t.Errorf("bilibili calls = %v", calls)
+	}
+	// 指定 Source 的任务只交给对应的采集器
+	if calls := other.Calls(); len(calls) != 0 {
+		t.Errorf("tieba calls = %v, want none", calls)
+	}
+}
+
+func TestRunTaskStartsJob(t *testing.T) {
+	d := newTestDatabase(t)
+	var during model.Job
+	slow := &fakeCollector{name: "bilibili"}
+	s := newTestScheduler(d, slow)
+
+	job := &model.Job{Type: model.JobTypeCollect, Status: model.JobStatusPending, Total: 1}
+	if err := d.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob() error = %v", err)
+	}
+	slow.collect = func(context.Context, string) error {
+		got, err := d.GetJob(job.ID)
+		if err != nil {
+			t.Errorf("GetJob() error = %v", err)
+			return nil
+		}
+		during = *got
+		return nil
+	}
+	if err := s.AddTask(Task{JobID: job.ID, Keyword: "PANDORA PARADOXXX"}); err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+
+	s.runTask(0, claim(t, d))
+
+	// 第一个任务开始执行时作业即为运行中，而不是等到第一个任务结束
+	if during.Status != model.JobStatusRunning || during.StartedAt == nil {
+		t.Errorf("job during collect = %s (started_at %v), want running", during.Status, during.StartedAt)
+	}
+	if got, _ := d.GetJob(job.ID); got.Status != model.JobStatusDone || !got.StartedAt.Equal(*during.StartedAt) {
+		t.Errorf("job after task = %+v, want done with unchanged started_at", got)
+	}
+}
+
+func TestRunTaskRetryAndExhaustion(t *testing.T) {
+	d := newTestDatabase(t)
+	failing := &fakeCollector{name: "bilibili", collect: func(context.Context, string) error {
+		return errors.New("connection reset")
+	}}
+	s := newTestScheduler(d, failing)
+	if err := s.AddTask(Task{Keyword: "PANDORA PARADOXXX"}); err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+
+	// 失败后按 Attempts² 分钟退避重新排队
+	for _, want := range []struct {
+		attempts int
+		backoff  time.Duration
+	}{{1, time.Minute}, {2, 4 * time.Minute}} {
+		task := claim(t, d)
+		before := time.Now()
+		s.runTask(0, task)
+		got := loadTask(t, d, task.ID)
+		if got.Status != model.TaskStatusPending || got.Attempts != want.attempts || got.LastError == "" {
+			t.Fatalf("task after attempt %d = %+v, want pending", want.attempts, got)
+		}
+		if delay := got.NextRunAt.Sub(before); delay < want.backoff-time.Second || delay > want.backoff+time.Second {
+			t.Errorf("attempt %d backoff = %v, want %v", want.attempts, delay, want.backoff)
+		}
+		// 退避期间不能被领取
+		if early, err := d.ClaimNextTask(); err != nil || early != nil {
+			t.Fatalf("ClaimNextTask() during backoff = %+v, %v, want nil", early, err)
+		}
+	}
+
+	// 第三次失败后重试次数耗尽
+	task := claim(t, d)
+	s.runTask(0, task)
+	got := loadTask(t, d, task.ID)
+	if got.Status != model.TaskStatusFailed || got.Attempts != defaultMaxAttempts || got.FinishedAt == nil {
+		t.Errorf("task = %+v, want failed after %d attempts", got, defaultMaxAttempts)
+	}
+	if calls := failing.Calls(); len(calls) != defaultMaxAttempts {
+		t.Errorf("collector calls = %d, want %d", len(calls), defaultMaxAttempts)
+	}
+}
+
+func TestStopRequeuesInterruptedTask(t *testing.T) {
+	d := newTestDatabase(t)
+	started := make(chan struct{})
+	blocking := &fakeCollector{name: "bilibili", collect: func(ctx context.Context, _ string) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	s := newTestScheduler(d, blocking)
+	s.Start()
+	if err := s.AddTask(Task{Keyword: "PANDORA PARADOXXX"}); err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("任务未开始执行")
+	}
+	s.Stop()
+
+	// 停止导致的中断不消耗重试次数，也不退避
+	var task model.Task
+	if err := d.DB.First(&task).Error; err != nil {
+		t.Fatalf("读取任务失败: %v", err)
+	}
+	if task.Status != model.TaskStatusPending || task.Attempts != 0 || task.FinishedAt != nil || task.NextRunAt.After(time.Now()) {
+		t.Fatalf("task after Stop() = %+v, want pending with 0 attempts and due now", task)
+	}
+
+	// 重启后继续执行
+	resumed := &fakeCollector{name: "bilibili"}
+	restarted := newTestScheduler(d, resumed)
+	restarted.Start()
+	defer restarted.Stop()
+	got := waitForStatus(t, d, task.ID, model.TaskStatusDone)
+	if got.Attempts != 1 {
+		t.Errorf("attempts = %d, want 1", got.Attempts)
+	}
+}
+
+func TestStartResetsRunningTasks(t *testing.T) {
+	d := newTestDatabase(t)
+	// 模拟进程异常退出：任务停留在运行中
+	crashed := newTestScheduler(d, &fakeCollector{name: "bilibili"})
+	if err := crashed.AddTask(Task{Keyword: "PANDORA PARADOXXX"}); err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+	task := claim(t, d)
+
+	s := newTestScheduler(d, &fakeCollector{name: "bilibili"})
+	s.Start()
+	defer s.Stop()
+	got := waitForStatus(t, d, task.ID, model.TaskStatusDone)
+	if got.Attempts != 1 {
+		t.Errorf("attempts = %d, want 1 (异常退出的那次不计入)", got.Attempts)
+	}
+}
+
+func TestRunTaskRetriesOnlyFailedCollectors(t *testing.T) {
+	d := newTestDatabase(t)
+	bilibili := &fakeCollector{name: "bilibili"}
+	flaky := errors.New("connection reset")
+	tieba := &fakeCollector{name: "tieba", collect: func(context.Context, string) error { return flaky }}
+	s := newTestScheduler(d, bilibili, tieba)
+	if err := s.AddTask(Task{Keyword: "PANDORA PARADOXXX"}); err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+
+	task := claim(t, d)
+	s.runTask(0, task)
+	got := loadTask(t, d, task.ID)
+	if got.Status != model.TaskStatusPending || got.DoneSources != "bilibili" {
+		t.Fatalf("task after partial failure = %+v, want pending with bilibili done", got)
+	}
+
+	// 重试时跳过已成功的采集器
+	tieba.collect = nil
+	s.runTask(0, claim(t, d))
+	got = loadTask(t, d, task.ID)
+	if got.Status != model.TaskStatusDone || got.DoneSources != "bilibili,tieba" {
+		t.Errorf("task after retry = %+v, want done with both sources", got)
+	}
+	if calls := bilibili.Calls(); len(calls) != 1 {
+		t.Errorf("bilibili calls = %d, want 1", len(calls))
+	}
+	if calls := tieba.Calls(); len(calls) != 2 {
+		t.Errorf("tieba calls = %d, want 2", len(calls))
+	}
+}
+
+// bannedCollector 始终处于封禁冷却的采集器替身
+type bannedCollector struct {
+	fakeCollector
+	until time.Time
+}
+
+func newBannedCollector(name string, until time.Time) *bannedCollector {
+	b := &bannedCollector{fakeCollector: fakeCollector{name: name}, until: until}
+	b.collect = func(context.Context, string) error { return collector.ErrBanned }
+	return b
+}
+
+func (b *bannedCollector) IsBanned() bool { return true }
+
+func (b *bannedCollector) BanState() collector.BreakerSnapshot {
+	until := b.until
+	return collector.BreakerSnapshot{State: collector.BreakerOpen, BannedUntil: &until}
+}
+
+func (b *bannedCollector) ResetBan() {}
+
+func TestRunTaskAllBanned(t *testing.T) {
+	d := newTestDatabase(t)
+	until := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	s := newTestScheduler(d, newBannedCollector("bilibili", until))
+	if err := s.AddTask(Task{Keyword: "PANDORA PARADOXXX"}); err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+
+	task := claim(t, d)
+	s.runTask(0, task)
+
+	// 整个任务延后到冷却结束，不消耗重试次数
+	got := loadTask(t, d, task.ID)
+	if got.Status != model.TaskStatusPending || got.Attempts != 0 || !got.NextRunAt.Equal(until) {
+		t.Errorf("task = %+v, want pending with 0 attempts until %v", got, until)
+	}
+	var count int64
+	d.DB.Model(&model.Task{}).Count(&count)
+	if count != 1 {
+		t.Errorf("tasks = %d, want 1 (不追加单独的来源任务)", count)
+	}
+}
+
+func TestRunTaskPartiallyBanned(t *testing.T) {
+	d := newTestDatabase(t)
+	until := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	banned := newBannedCollector("bilibili", until)
+	tieba := &fakeCollector{name: "tieba"}
+	s := newTestScheduler(d, banned, tieba)
+
+	job := &model.Job{Type: model.JobTypeCollect, Status: model.JobStatusPending, Total: 1}
+	if err := d.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob() error = %v", err)
+	}
+	if err := s.AddTask(Task{JobID: job.ID, Keyword: "PANDORA PARADOXXX", SongID: 7}); err != nil {
+		t.Fatalf("AddTask() error = %v", err)
+	}
+
+	task := claim(t, d)
+	s.runTask(0, task)
+
+	// 未被封禁的采集器正常完成本任务
+	if got := loadTask(t, d, task.ID); got.Status != model.TaskStatusDone {
+		t.Errorf("task = %+v, want done", got)
+	}
+	// 被封禁的来源单独追加任务，延后到冷却结束
+	var followUps []model.Task
+	if err := d.DB.Where("id <> ?", task.ID).Find(&followUps).Error; err != nil {
+		t.Fatalf("读取追加任务失败: %v", err)
+	}
+	if len(followUps) != 1 {
+		t.Fatalf("follow-up tasks = %+v, want 1", followUps)
+	}
+	f := followUps[0]
+	if f.Source != "bilibili" || f.Status != model.TaskStatusPending || f.Attempts != 0 ||
+		!f.NextRunAt.Equal(until) || f.JobID != job.ID || f.SongID != 7 || f.Keyword != "PANDORA PARADOXXX" {
+		t.Errorf("follow-up task = %+v, want pending bilibili task until %v", f, until)
+	}
+
+	// 作业等待追加的任务完成
+	got, err := d.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if got.Total != 2 || got.Succeeded != 1 || got.Status != model.JobStatusRunning {
+		t.Errorf("job = %+v, want running with 1 of 2 done", got)
+	}
+
+	// 再次遇到封禁时不重复追加
+	s.deferBannedSource(task, bannedSource{name: "bilibili", until: until, err: collector.ErrBanned})
+	var count int64
+	d.DB.Model(&model.Task{}).Where("source = ?", "bilibili").Count(&count)
+	if count != 1 {
+		t.Errorf("bilibili tasks = %d, want 1", count)
+	}
+
+	// 冷却结束后追加的任务只使用被封禁的采集器
+	banned.collect = nil
+	followUp := claim(t, d)
+	s.runTask(0, followUp)
+	if calls := tieba.Calls(); len(calls) != 1 {
+		t.Errorf("tieba calls = %d, want 1", len(calls))
+	}
+	if got, _ := d.GetJob(job.ID); got.Status != model.JobStatusDone || got.Succeeded != 2 {
+		t.Errorf("job = %+v, want done with 2 succeeded", got)
+	}
+}
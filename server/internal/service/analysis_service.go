@@ -2,24 +2,42 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/xumoe-c/maiecho/server/internal/agent"
 	"github.com/xumoe-c/maiecho/server/internal/config"
 	"github.com/xumoe-c/maiecho/server/internal/llm"
 	"github.com/xumoe-c/maiecho/server/internal/logger"
 	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/progress"
 	"github.com/xumoe-c/maiecho/server/internal/storage"
 )
 
+// budgetRecheckInterval 作业因超出预算暂停时重新检查预算的最长间隔
+const budgetRecheckInterval = 10 * time.Minute
+
 type AnalysisService struct {
 	analyzer *agent.Analyzer
 	storage  storage.Storage
+	hub      *progress.Hub
+	summary  *progress.Hub // 顾问摘要的流式输出，与阶段事件分开推送
+	usage    UsageService
+
+	// now 与 sleep 用于预算暂停的计时，测试时替换
+	now   func() time.Time
+	sleep func(time.Duration)
 }
 
-func NewAnalysisService(s storage.Storage, client *llm.Client, prompts *config.PromptConfig) *AnalysisService {
+func NewAnalysisService(s storage.Storage, llmRouter *llm.Router, prompts *config.PromptConfig, hub, summaryHub *progress.Hub, usage UsageService) *AnalysisService {
 	return &AnalysisService{
-		analyzer: agent.NewAnalyzer(s, client, prompts),
+		analyzer: agent.NewAnalyzer(s, llmRouter, prompts),
 		storage:  s,
+		hub:      hub,
+		summary:  summaryHub,
+		usage:    usage,
+		now:      time.Now,
+		sleep:    time.Sleep,
 	}
 }
 
@@ -35,6 +53,152 @@ func (s *AnalysisService) AnalyzeSongByGameID(ctx context.Context, gameID int) e
 	return s.analyzer.AnalyzeSong(ctx, song.ID)
 }
 
+// StartAnalysis 为单首歌曲创建分析作业并在后台执行，进度可通过作业接口或事件流查看
+func (s *AnalysisService) StartAnalysis(gameID int) (*model.Job, error) {
+	if _, err := s.storage.GetSongByGameID(gameID); err != nil {
+		return nil, err
+	}
+	return s.StartBatchAnalysis([]int{gameID})
+}
+
+// StartBatchAnalysis 创建批量分析作业并在后台依次分析每首歌曲
+func (s *AnalysisService) StartBatchAnalysis(gameIDs []int) (*model.Job, error) {
+	job := &model.Job{
+		Type:   model.JobTypeAnalysis,
+		Status: model.JobStatusPending,
+		Total:  len(gameIDs),
+	}
+	if len(gameIDs) == 0 {
+		now := time.Now()
+		job.Status = model.JobStatusDone
+		job.FinishedAt = &now
+	}
+	if err := s.storage.CreateJob(job); err != nil {
+		return nil, fmt.Errorf("创建作业失败: %w", err)
+	}
+
+	// 简单的循环调用，实际生产中应放入消息队列
+	go s.runAnalysisJob(job.ID, gameIDs)
+
+	return job, nil
+}
+
+// AbortInterruptedJobs 将上次进程退出时未完成的分析作业标记为失败
+// 分析作业在进程内的协程中执行，重启后无法继续，需要重新提交
+func (s *AnalysisService) AbortInterruptedJobs() {
+	aborted, err := s.storage.AbortUnfinishedJobs(model.JobTypeAnalysis)
+	if err != nil {
+		logger.Error("收尾中断的分析作业失败", "module", "service.analysis", "error", err)
+		return
+	}
+	if aborted > 0 {
+		logger.Warn("上次退出时未完成的分析作业已标记为失败", "module", "service.analysis", "count", aborted)
+	}
+}
+
+func (s *AnalysisService) runAnalysisJob(jobID uint, gameIDs []int) {
+	if err := s.storage.StartJob(jobID); err != nil {
+		logger.Error("更新作业状态失败", "module", "service.analysis", "jobID", jobID, "error", err)
+	}
+	s.hub.Publish(progress.Event{JobID: jobID, Stage: progress.StageStarted, Data: map[string]interface{}{"total": len(gameIDs)}})
+
+	succeeded := 0
+	for _, id := range gameIDs {
+		s.waitForBudget(jobID)
+
+		start := time.Now()
+		item := &model.JobItem{
+			JobID:     jobID,
+			GameID:    id,
+			Status:    model.JobStatusDone,
+			StartedAt: &start,
+		}
+
+		// 使用新的 context，因为请求 context 会在请求结束时取消
+		song, err := s.storage.GetSongByGameID(id)
+		if err == nil {
+			item.SongID = song.ID
+			ctx := progress.WithReporter(llm.WithJobID(context.Background(), jobID), func(stage, message string, data map[string]interface{}) {
+				s.hub.Publish(progress.Event{JobID: jobID, SongID: song.ID, Stage: stage, Message: message, Data: data})
+			})
+			ctx = progress.WithStreamReporter(ctx, func(stage, message string, data map[string]interface{}) {
+				s.summary.Publish(progress.Event{JobID: jobID, SongID: song.ID, Stage: stage, Message: message, Data: data})
+			})
+			err = s.analyzer.AnalyzeSong(ctx, song.ID)
+		}
+		if err != nil {
+			logger.Error("批量分析中单个歌曲分析失败", "module", "service.analysis", "jobID", jobID, "gameID", id, "error", err)
+			item.Status = model.JobStatusFailed
+			item.Error = err.Error()
+			s.hub.Publish(progress.Event{JobID: jobID, SongID: item.SongID, Stage: progress.StageSongFailed, Message: err.Error(), Data: map[string]interface{}{"game_id": id}})
+		} else {
+			succeeded++
+		}
+
+		end := time.Now()
+		item.FinishedAt = &end
+		item.DurationMs = end.Sub(start).Milliseconds()
+		if err := s.storage.RecordJobItem(item); err != nil {
+			logger.Error("更新作业进度失败", "module", "service.analysis", "jobID", jobID, "error", err)
+		}
+	}
+
+	final := progress.StageCompleted
+	if succeeded == 0 && len(gameIDs) > 0 {
+		final = progress.StageFailed
+	}
+	finalEvent := progress.Event{JobID: jobID, Stage: final, Data: map[string]interface{}{
+		"total":     len(gameIDs),
+		"succeeded": succeeded,
+		"failed":    len(gameIDs) - succeeded,
+	}}
+	s.hub.Publish(finalEvent)
+	s.summary.Publish(finalEvent)
+	logger.Info("批量分析任务完成", "module", "service.analysis", "jobID", jobID, "count", len(gameIDs))
+}
+
+// waitForBudget 今日 LLM 用量超出每日预算时暂停作业，直到次日预算重置
+// 正在分析的歌曲会完成，暂停发生在两首歌曲之间；暂停期间作业状态为 paused
+func (s *AnalysisService) waitForBudget(jobID uint) {
+	paused := false
+	for {
+		status, err := s.usage.GetBudgetStatus()
+		if err != nil {
+			// 无法确认用量时不阻塞作业
+			logger.Error("检查LLM预算失败", "module", "service.analysis", "jobID", jobID, "error", err)
+		}
+		if err != nil || !status.Exceeded {
+			if paused {
+				if err := s.storage.ResumeJob(jobID); err != nil {
+					logger.Error("更新作业状态失败", "module", "service.analysis", "jobID", jobID, "error", err)
+				}
+				logger.Info("LLM预算已重置，分析作业继续", "module", "service.analysis", "jobID", jobID)
+				s.hub.Publish(progress.Event{JobID: jobID, Stage: progress.StageBudgetResumed})
+			}
+			return
+		}
+
+		now := s.now()
+		resumeAt := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		if !paused {
+			paused = true
+			if err := s.storage.PauseJob(jobID, resumeAt); err != nil {
+				logger.Error("更新作业状态失败", "module", "service.analysis", "jobID", jobID, "error", err)
+			}
+			logger.Warn("今日LLM用量超出预算，分析作业暂停到次日", "module", "service.analysis", "jobID", jobID,
+				"cost", status.Cost, "dailyBudget", status.DailyBudget, "tokens", status.TotalTokens, "dailyTokenBudget", status.DailyTokenBudget)
+			s.hub.Publish(progress.Event{JobID: jobID, Stage: progress.StageBudgetPaused, Message: "今日LLM用量超出预算，作业暂停到次日", Data: map[string]interface{}{
+				"cost":               status.Cost,
+				"daily_budget":       status.DailyBudget,
+				"total_tokens":       status.TotalTokens,
+				"daily_token_budget": status.DailyTokenBudget,
+				"resume_at":          resumeAt,
+			}})
+		}
+		s.sleep(min(resumeAt.Sub(now), budgetRecheckInterval))
+	}
+}
+
 // AggregatedAnalysisResult 聚合了歌曲和谱面的分析结果
 type AggregatedAnalysisResult struct {
 	SongResult   *model.AnalysisResult   `json:"song_result"`
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+	"github.com/xumoe-c/maiecho/server/internal/llm"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/progress"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+)
+
+// newBudgetTestService 创建只用于预算暂停测试的分析服务，sleep 推进时钟而不真正等待
+func newBudgetTestService(t *testing.T, d *storage.Database, clock *fakeClock, onSleep func(time.Duration)) *AnalysisService {
+	t.Helper()
+	usage := newTestUsageService(d, config.LLMConfig{DailyBudget: 1}, clock)
+	return &AnalysisService{
+		storage: d,
+		hub:     progress.NewHub(),
+		summary: progress.NewHub(),
+		usage:   usage,
+		now:     clock.Now,
+		sleep: func(dur time.Duration) {
+			onSleep(dur)
+			clock.Advance(dur)
+		},
+	}
+}
+
+func newRunningJob(t *testing.T, d *storage.Database) *model.Job {
+	t.Helper()
+	job := &model.Job{Type: model.JobTypeAnalysis, Status: model.JobStatusPending, Total: 2}
+	if err := d.CreateJob(job); err != nil {
+		t.Fatalf("CreateJob() error = %v", err)
+	}
+	if err := d.StartJob(job.ID); err != nil {
+		t.Fatalf("StartJob() error = %v", err)
+	}
+	return job
+}
+
+func TestWaitForBudgetPausesUntilMidnight(t *testing.T) {
+	d := newTestDatabase(t)
+	clock := &fakeClock{t: time.Date(2024, 3, 1, 23, 45, 0, 0, time.UTC)}
+	resumeAt := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	job := newRunningJob(t, d)
+
+	var sleeps []time.Duration
+	s := newBudgetTestService(t, d, clock, func(dur time.Duration) {
+		sleeps = append(sleeps, dur)
+		// 暂停期间作业状态可通过作业接口查询
+		got, err := d.GetJob(job.ID)
+		if err != nil {
+			t.Fatalf("GetJob() error = %v", err)
+		}
+		if got.Status != model.JobStatusPaused || got.ResumeAt == nil || !got.ResumeAt.Equal(resumeAt) {
+			t.Errorf("job while sleeping = %s (resume_at %v), want paused until %v", got.Status, got.ResumeAt, resumeAt)
+		}
+	})
+	s.usage.RecordUsage(llm.Usage{Role: "analyst", Cost: 1.5})
+
+	s.waitForBudget(job.ID)
+
+	// 每次最多等待 budgetRecheckInterval，在次日零点恢复
+	if len(sleeps) != 2 || sleeps[0] != budgetRecheckInterval || sleeps[1] != 5*time.Minute {
+		t.Errorf("sleeps = %v, want [%v 5m0s]", sleeps, budgetRecheckInterval)
+	}
+	if !clock.Now().Equal(resumeAt) {
+		t.Errorf("resumed at %v, want %v", clock.Now(), resumeAt)
+	}
+
+	got, err := d.GetJob(job.ID)
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if got.Status != model.JobStatusRunning || got.ResumeAt != nil {
+		t.Errorf("job after resume = %s (resume_at %v), want running", got.Status, got.ResumeAt)
+	}
+
+	history, _, cancel := s.hub.Subscribe(job.ID)
+	defer cancel()
+	if len(history) != 2 || history[0].Stage != progress.StageBudgetPaused || history[1].Stage != progress.StageBudgetResumed {
+		t.Fatalf("events = %+v, want budget_paused then budget_resumed", history)
+	}
+	if at, ok := history[0].Data["resume_at"].(time.Time); !ok || !at.Equal(resumeAt) {
+		t.Errorf("budget_paused resume_at = %v, want %v", history[0].Data["resume_at"], resumeAt)
+	}
+}
+
+func TestWaitForBudgetWithinBudget(t *testing.T) {
+	d := newTestDatabase(t)
+	clock := &fakeClock{t: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	job := newRunningJob(t, d)
+
+	s := newBudgetTestService(t, d, clock, func(dur time.Duration) {
+		t.Errorf("slept %v within budget", dur)
+	})
+	s.usage.RecordUsage(llm.Usage{Role: "analyst", Cost: 0.5})
+
+	s.waitForBudget(job.ID)
+
+	if got, _ := d.GetJob(job.ID); got.Status != model.JobStatusRunning {
+		t.Errorf("job Status = %s, want running", got.Status)
+	}
+	if history, _, cancel := s.hub.Subscribe(job.ID); len(history) != 0 {
+		cancel()
+		t.Errorf("events = %+v, want none", history)
+	} else {
+		cancel()
+	}
+}
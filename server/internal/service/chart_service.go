@@ -0,0 +1,65 @@
+package service
+
+import (
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+)
+
+type ChartService interface {
+	// GetChartChanges 按检测时间倒序列出定数变更事件
+	GetChartChanges(filter model.ChartChangeFilter) (*model.ChartChangeListResponse, error)
+	// GetChartTimeline 获取单个谱面的定数变更事件与数据快照
+	GetChartTimeline(chartID uint) (*model.ChartTimeline, error)
+	// SearchCharts 按谱面数据与最新分析结果 (配置、难度描述符、情感倾向、拟合定数差) 搜索谱面
+	SearchCharts(filter model.ChartSearchFilter) (*model.ChartSearchResponse, error)
+}
+
+type chartServiceImpl struct {
+	storage storage.Storage
+}
+
+func NewChartService(s storage.Storage) ChartService {
+	return &chartServiceImpl{storage: s}
+}
+
+func (s *chartServiceImpl) GetChartChanges(filter model.ChartChangeFilter) (*model.ChartChangeListResponse, error) {
+	items, total, err := s.storage.GetChartChanges(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ChartChangeListResponse{
+		Total: total,
+		Items: items,
+	}, nil
+}
+
+func (s *chartServiceImpl) GetChartTimeline(chartID uint) (*model.ChartTimeline, error) {
+	chart, err := s.storage.GetChart(chartID)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := s.storage.GetChartChangesByChartID(chartID)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := s.storage.GetChartSnapshots(chartID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ChartTimeline{
+		Chart:     *chart,
+		Changes:   changes,
+		Snapshots: snapshots,
+	}, nil
+}
+
+func (s *chartServiceImpl) SearchCharts(filter model.ChartSearchFilter) (*model.ChartSearchResponse, error) {
+	items, total, err := s.storage.SearchCharts(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ChartSearchResponse{
+		Total: total,
+		Items: items,
+	}, nil
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/xumoe-c/maiecho/server/internal/agent"
@@ -12,14 +14,16 @@ import (
 	"github.com/xumoe-c/maiecho/server/internal/logger"
 	"github.com/xumoe-c/maiecho/server/internal/model"
 	"github.com/xumoe-c/maiecho/server/internal/scheduler"
+	"github.com/xumoe-c/maiecho/server/internal/status"
 	"github.com/xumoe-c/maiecho/server/internal/storage"
 )
 
 type CollectorService interface {
-	// TriggerCollection 根据关键词触发一次采集任务
-	TriggerCollection(keyword string, songID *uint) error
-	// BackfillCollection 为数据库中的所有歌曲排队采集任务
-	BackfillCollection() error
+	// TriggerCollection 根据关键词触发一次采集作业，返回作业记录
+	// source 为空时使用所有启用的采集器，否则只使用指定的采集器
+	TriggerCollection(keywords []string, songID *uint, source string) (*model.Job, error)
+	// BackfillCollection 为数据库中的所有歌曲排队采集任务，返回作业记录
+	BackfillCollection() (*model.Job, error)
 	// StartDiscovery 启动定期发现任务
 	StartDiscovery()
 	// StartScheduler 启动后台工作线程
@@ -30,41 +34,123 @@ type CollectorService interface {
 	GetSongByGameID(gameID int) (*model.Song, error)
 	// CheckAliasSuitability 检查别名是否适合作为搜索关键词
 	CheckAliasSuitability(ctx context.Context, song *model.Song, alias *model.SongAlias) (bool, error)
+	// GetCollectorStatuses 获取所有采集器的状态 (含封禁熔断状态)
+	GetCollectorStatuses() []status.CollectorStatus
+	// ResetCollectorBan 手动解除指定采集器的封禁状态
+	ResetCollectorBan(name string) (*status.CollectorStatus, error)
 }
 
+// ErrCollectorNotFound 指定名称的采集器不存在
+var ErrCollectorNotFound = errors.New("collector not found")
+
+// ErrUnknownSource 采集作业指定的采集器未注册或未启用
+var ErrUnknownSource = scheduler.ErrUnknownSource
+
+// ErrCollectorNotBanAware 指定的采集器不支持封禁状态管理
+var ErrCollectorNotBanAware = errors.New("collector does not track ban state")
+
 type collectorServiceImpl struct {
 	scheduler          *scheduler.Scheduler
+	collectors         []collector.Collector
 	songService        SongService
 	storage            storage.Storage
 	discoveryCollector collector.Collector
 	discoveryTicker    *time.Ticker
 	discoveryDone      chan bool
+	discoveryRunning   int32        // 发现任务是否正在运行
+	discoveryLastRun   atomic.Value // time.Time，上次发现任务完成时间
 	relevanceAnalyzer  *agent.RelevanceAnalyzer
 }
 
-func NewCollectorService(s storage.Storage, songService SongService, cfg *config.Config, llmClient *llm.Client, prompts *config.PromptConfig) CollectorService {
-	// 初始化采集器
-	discovery := collector.NewBilibiliDiscoveryCollector(s)
-	// 初始化主采集器 (用于深度采集评论)
-	mainCollector := collector.NewBilibiliCollector(s, cfg.Bilibili.Cookie, cfg.Bilibili.Proxy)
+func NewCollectorService(s storage.Storage, songService SongService, registry *collector.Registry, llmRouter *llm.Router, prompts *config.PromptConfig) CollectorService {
+	collectors := registry.All()
 
-	// 调度器同时管理发现采集器和主采集器
-	collectors := []collector.Collector{discovery, mainCollector}
+	// 定期发现任务使用第一个启用的发现采集器
+	var discovery collector.Collector
+	for _, c := range collectors {
+		if d, ok := c.(collector.Discoverer); ok && d.IsDiscovery() {
+			discovery = c
+			break
+		}
+	}
 
-	// 初始化调度器
-	sched := scheduler.NewScheduler(collectors, s, 1, 1000)
+	// 初始化调度器，关键词任务在所有启用的采集器上执行
+	sched := scheduler.NewScheduler(collectors, s, registry.Workers())
 
-	return &collectorServiceImpl{
+	svc := &collectorServiceImpl{
 		scheduler:          sched,
+		collectors:         collectors,
 		songService:        songService,
 		storage:            s,
 		discoveryCollector: discovery,
 		discoveryDone:      make(chan bool),
-		relevanceAnalyzer:  agent.NewRelevanceAnalyzer(llmClient, prompts),
+		relevanceAnalyzer:  agent.NewRelevanceAnalyzer(llmRouter.For(llm.RoleRelevance), prompts),
+	}
+
+	// 向状态模块注册运行指标
+	status.RegisterScheduler(sched.Stats)
+	for _, c := range collectors {
+		status.RegisterCollector(func() status.CollectorStatus {
+			return collectorStatus(c)
+		})
+	}
+	status.RegisterDiscovery(svc.discoveryStatus)
+
+	return svc
+}
+
+func collectorStatus(c collector.Collector) status.CollectorStatus {
+	cs := status.CollectorStatus{Name: c.Name()}
+	if b, ok := c.(collector.BanAware); ok {
+		snap := b.BanState()
+		cs.Banned = snap.State != collector.BreakerClosed
+		cs.BanState = snap.State
+		cs.BanTrips = snap.Trips
+		cs.BanReason = snap.LastReason
+		cs.BannedUntil = snap.BannedUntil
+	}
+	return cs
+}
+
+func (s *collectorServiceImpl) GetCollectorStatuses() []status.CollectorStatus {
+	statuses := make([]status.CollectorStatus, 0, len(s.collectors))
+	for _, c := range s.collectors {
+		statuses = append(statuses, collectorStatus(c))
+	}
+	return statuses
+}
+
+func (s *collectorServiceImpl) ResetCollectorBan(name string) (*status.CollectorStatus, error) {
+	for _, c := range s.collectors {
+		if c.Name() != name {
+			continue
+		}
+		b, ok := c.(collector.BanAware)
+		if !ok {
+			return nil, ErrCollectorNotBanAware
+		}
+		b.ResetBan()
+		logger.Info("采集器封禁状态已重置", "module", "service.collector", "collector", name)
+		cs := collectorStatus(c)
+		return &cs, nil
+	}
+	return nil, ErrCollectorNotFound
+}
+
+func (s *collectorServiceImpl) discoveryStatus() status.DiscoveryStatus {
+	ds := status.DiscoveryStatus{Running: atomic.LoadInt32(&s.discoveryRunning) == 1}
+	if lastRun, ok := s.discoveryLastRun.Load().(time.Time); ok {
+		ds.LastRunAt = &lastRun
 	}
+	return ds
 }
 
 func (s *collectorServiceImpl) StartDiscovery() {
+	if s.discoveryCollector == nil {
+		logger.Info("未启用发现采集器，跳过发现服务", "module", "service.collector")
+		return
+	}
+
 	// 每小时运行一次发现任务
 	s.discoveryTicker = time.NewTicker(1 * time.Hour)
 
@@ -85,6 +171,12 @@ func (s *collectorServiceImpl) StartDiscovery() {
 }
 
 func (s *collectorServiceImpl) runDiscovery() {
+	atomic.StoreInt32(&s.discoveryRunning, 1)
+	defer func() {
+		atomic.StoreInt32(&s.discoveryRunning, 0)
+		s.discoveryLastRun.Store(time.Now())
+	}()
+
 	logger.Info("运行发现任务", "module", "service.collector")
 	spinner := logger.StartSpinner("运行发现任务...")
 	ctx := context.Background()
@@ -112,19 +204,27 @@ func (s *collectorServiceImpl) StopScheduler() {
 	}
 }
 
-func (s *collectorServiceImpl) TriggerCollection(keyword string, songID *uint) error {
-	task := scheduler.Task{Keyword: keyword}
-	if songID != nil {
-		task.SongID = *songID
+func (s *collectorServiceImpl) TriggerCollection(keywords []string, songID *uint, source string) (*model.Job, error) {
+	// 在创建作业前校验来源，避免产生全部失败的作业
+	if source != "" && !s.scheduler.HasCollector(source) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSource, source)
+	}
+
+	var tasks []scheduler.Task
+	for _, kw := range keywords {
+		task := scheduler.Task{Keyword: kw, Source: source}
+		if songID != nil {
+			task.SongID = *songID
+		}
+		tasks = append(tasks, task)
 	}
-	s.scheduler.AddTask(task)
-	return nil
+	return s.enqueueJob(model.JobTypeCollect, tasks)
 }
 
-func (s *collectorServiceImpl) BackfillCollection() error {
+func (s *collectorServiceImpl) BackfillCollection() (*model.Job, error) {
 	songs, err := s.songService.GetAllSongs()
 	if err != nil {
-		return fmt.Errorf("获取歌曲失败: %w", err)
+		return nil, fmt.Errorf("获取歌曲失败: %w", err)
 	}
 
 	logger.Info("开始回填", "module", "service.collector", "songCount", len(songs))
@@ -134,14 +234,13 @@ func (s *collectorServiceImpl) BackfillCollection() error {
 	defer tracker.Stop()
 
 	skippedCount := 0
-	queuedCount := 0
+	var tasks []scheduler.Task
 
 	for _, song := range songs {
 		// 检查上次采集时间
-		if song.LastScraped != nil {
-			lastScraped, err := time.Parse(time.RFC3339, *song.LastScraped)
-			// 如果解析成功且距离现在不到 7 天，则跳过
-			if err == nil && time.Since(lastScraped) < 7*24*time.Hour {
+		if song.LastScrapedAt != nil {
+			// 如果距离上次采集不到 7 天，则跳过
+			if time.Since(*song.LastScrapedAt) < 7*24*time.Hour {
 				skippedCount++
 				tracker.Increment()
 				continue
@@ -150,13 +249,75 @@ func (s *collectorServiceImpl) BackfillCollection() error {
 
 		// 构造关键词: "歌曲标题 舞萌 maimai 手元 谱面确认"
 		keyword := fmt.Sprintf("%s 舞萌 maimai 手元 谱面确认", song.Title)
-		s.scheduler.AddTask(scheduler.Task{Keyword: keyword, SongID: song.ID})
-		queuedCount++
+
+		// 如果上次回填的任务仍在队列中（例如服务重启前未完成），不再重复排队
+		active, err := s.storage.HasActiveTask(keyword, song.ID)
+		if err != nil {
+			logger.Error("检查任务队列失败", "module", "service.collector", "songID", song.ID, "error", err)
+		} else if active {
+			skippedCount++
+			tracker.Increment()
+			continue
+		}
+
+		tasks = append(tasks, scheduler.Task{Keyword: keyword, SongID: song.ID})
 		tracker.Increment()
 	}
 
-	logger.Info("回填任务排队完成", "module", "service.collector", "queued", queuedCount, "skipped", skippedCount)
-	return nil
+	job, err := s.enqueueJob(model.JobTypeBackfill, tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("回填任务排队完成", "module", "service.collector", "jobID", job.ID, "queued", len(tasks)-job.Failed, "skipped", skippedCount, "failed", job.Failed)
+	return job, nil
+}
+
+// enqueueJob 创建作业记录并将其下的任务加入调度队列
+func (s *collectorServiceImpl) enqueueJob(jobType string, tasks []scheduler.Task) (*model.Job, error) {
+	job := &model.Job{
+		Type:   jobType,
+		Status: model.JobStatusPending,
+		Total:  len(tasks),
+	}
+	if len(tasks) == 0 {
+		now := time.Now()
+		job.Status = model.JobStatusDone
+		job.FinishedAt = &now
+	}
+	if err := s.storage.CreateJob(job); err != nil {
+		return nil, fmt.Errorf("创建作业失败: %w", err)
+	}
+
+	failed := 0
+	for _, task := range tasks {
+		task.JobID = job.ID
+		if err := s.scheduler.AddTask(task); err != nil {
+			// 入队失败的任务直接记为失败，保证作业进度可以结束
+			now := time.Now()
+			item := &model.JobItem{
+				JobID:      job.ID,
+				SongID:     task.SongID,
+				Keyword:    task.Keyword,
+				Status:     model.JobStatusFailed,
+				Error:      err.Error(),
+				StartedAt:  &now,
+				FinishedAt: &now,
+			}
+			if err := s.storage.RecordJobItem(item); err != nil {
+				logger.Error("记录作业子项失败", "module", "service.collector", "jobID", job.ID, "error", err)
+			}
+			failed++
+		}
+	}
+
+	// 存在入队失败时重新读取作业，返回最新的进度
+	if failed > 0 {
+		if updated, err := s.storage.GetJob(job.ID); err == nil {
+			job = updated
+		}
+	}
+	return job, nil
 }
 
 func (s *collectorServiceImpl) GetSongByGameID(gameID int) (*model.Song, error) {
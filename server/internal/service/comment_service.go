@@ -0,0 +1,30 @@
+package service
+
+import (
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+)
+
+type CommentService interface {
+	// SearchComments 按关键词搜索评论，结果按相关度排序
+	SearchComments(filter model.CommentSearchFilter) (*model.CommentSearchResponse, error)
+}
+
+type commentServiceImpl struct {
+	storage storage.Storage
+}
+
+func NewCommentService(s storage.Storage) CommentService {
+	return &commentServiceImpl{storage: s}
+}
+
+func (s *commentServiceImpl) SearchComments(filter model.CommentSearchFilter) (*model.CommentSearchResponse, error) {
+	hits, total, err := s.storage.SearchComments(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CommentSearchResponse{
+		Total: total,
+		Items: hits,
+	}, nil
+}
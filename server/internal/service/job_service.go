@@ -0,0 +1,49 @@
+package service
+
+import (
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/progress"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+)
+
+type JobService interface {
+	GetJob(id uint) (*model.Job, error)
+	GetJobs(filter model.JobFilter) (*model.JobListResponse, error)
+	// SubscribeEvents 订阅作业的进度事件
+	SubscribeEvents(jobID uint) (history []progress.Event, events <-chan progress.Event, cancel func())
+	// SubscribeSummary 订阅分析作业中顾问摘要的流式输出
+	SubscribeSummary(jobID uint) (history []progress.Event, events <-chan progress.Event, cancel func())
+}
+
+type jobServiceImpl struct {
+	storage storage.Storage
+	hub     *progress.Hub
+	summary *progress.Hub
+}
+
+func NewJobService(s storage.Storage, hub, summaryHub *progress.Hub) JobService {
+	return &jobServiceImpl{storage: s, hub: hub, summary: summaryHub}
+}
+
+func (s *jobServiceImpl) GetJob(id uint) (*model.Job, error) {
+	return s.storage.GetJob(id)
+}
+
+func (s *jobServiceImpl) GetJobs(filter model.JobFilter) (*model.JobListResponse, error) {
+	jobs, total, err := s.storage.GetJobs(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &model.JobListResponse{
+		Total: total,
+		Items: jobs,
+	}, nil
+}
+
+func (s *jobServiceImpl) SubscribeEvents(jobID uint) ([]progress.Event, <-chan progress.Event, func()) {
+	return s.hub.Subscribe(jobID)
+}
+
+func (s *jobServiceImpl) SubscribeSummary(jobID uint) ([]progress.Event, <-chan progress.Event, func()) {
+	return s.summary.Subscribe(jobID)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+	"gorm.io/gorm"
+)
+
+// LLMCache 基于数据库的 LLM 响应缓存 (实现 llm.Cache)
+// 缓存条目记录写入时的提示词版本，prompts.yaml 修改后旧条目不再命中
+type LLMCache struct {
+	storage       storage.Storage
+	ttl           time.Duration
+	promptVersion string
+}
+
+func NewLLMCache(s storage.Storage, ttl time.Duration, promptVersion string) *LLMCache {
+	return &LLMCache{storage: s, ttl: ttl, promptVersion: promptVersion}
+}
+
+func (c *LLMCache) Get(key string) (string, bool) {
+	entry, err := c.storage.GetLLMCacheEntry(key, c.promptVersion)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error("读取LLM缓存失败", "module", "service.llm_cache", "error", err)
+		}
+		return "", false
+	}
+	return entry.Response, true
+}
+
+func (c *LLMCache) Set(key, modelName, response string) {
+	now := time.Now()
+	entry := &model.LLMCacheEntry{
+		Key:           key,
+		Model:         modelName,
+		PromptVersion: c.promptVersion,
+		Response:      response,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(c.ttl),
+	}
+	if err := c.storage.SaveLLMCacheEntry(entry); err != nil {
+		logger.Error("写入LLM缓存失败", "module", "service.llm_cache", "error", err)
+	}
+}
+
+func (c *LLMCache) Delete(key string) {
+	if err := c.storage.DeleteLLMCacheEntry(key); err != nil {
+		logger.Error("删除LLM缓存失败", "module", "service.llm_cache", "error", err)
+	}
+}
+
+// Purge 删除过期及提示词版本已变化的缓存 (启动时调用)
+func (c *LLMCache) Purge() (int64, error) {
+	return c.storage.PurgeLLMCache(c.promptVersion)
+}
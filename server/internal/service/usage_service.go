@@ -0,0 +1,106 @@
+package service
+
+import (
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+	"github.com/xumoe-c/maiecho/server/internal/llm"
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+)
+
+// usageDayLayout 用量按本地日期聚合
+const usageDayLayout = "2006-01-02"
+
+type UsageService interface {
+	// RecordUsage 将一次 LLM 调用的用量累加到当天的统计中 (实现 llm.UsageRecorder)
+	RecordUsage(u llm.Usage)
+	// GetUsage 返回时间范围内按天和按角色的用量合计，以及今日的预算状态
+	GetUsage(filter model.LLMUsageFilter) (*model.LLMUsageResponse, error)
+	// GetBudgetStatus 返回今日用量与每日预算
+	GetBudgetStatus() (*model.LLMBudgetStatus, error)
+}
+
+type usageServiceImpl struct {
+	storage          storage.Storage
+	dailyBudget      float64
+	dailyTokenBudget int64
+	now              func() time.Time // 用于确定用量所属的日期，测试时替换
+}
+
+func NewUsageService(s storage.Storage, cfg config.LLMConfig) UsageService {
+	return &usageServiceImpl{
+		storage:          s,
+		dailyBudget:      cfg.DailyBudget,
+		dailyTokenBudget: cfg.DailyTokenBudget,
+		now:              time.Now,
+	}
+}
+
+func (s *usageServiceImpl) RecordUsage(u llm.Usage) {
+	usage := &model.LLMUsage{
+		Day:              s.now().Format(usageDayLayout),
+		Role:             u.Role,
+		Provider:         u.Provider,
+		Model:            u.Model,
+		SongID:           u.SongID,
+		JobID:            u.JobID,
+		Calls:            1,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		Cost:             u.Cost,
+	}
+	// 统计失败不影响调用结果
+	if err := s.storage.RecordLLMUsage(usage); err != nil {
+		logger.Error("记录LLM用量失败", "module", "service.usage", "role", u.Role, "provider", u.Provider, "error", err)
+	}
+}
+
+func (s *usageServiceImpl) GetUsage(filter model.LLMUsageFilter) (*model.LLMUsageResponse, error) {
+	byDay, err := s.storage.GetLLMUsageByDay(filter)
+	if err != nil {
+		return nil, err
+	}
+	byRole, err := s.storage.GetLLMUsageByRole(filter)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := s.GetBudgetStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &model.LLMUsageResponse{
+		From:   filter.From,
+		To:     filter.To,
+		ByDay:  byDay,
+		ByRole: byRole,
+		Budget: *budget,
+	}
+	for _, day := range byDay {
+		resp.Total.Add(day.LLMUsageTotals)
+	}
+	return resp, nil
+}
+
+func (s *usageServiceImpl) GetBudgetStatus() (*model.LLMBudgetStatus, error) {
+	today := s.now().Format(usageDayLayout)
+	days, err := s.storage.GetLLMUsageByDay(model.LLMUsageFilter{From: today, To: today})
+	if err != nil {
+		return nil, err
+	}
+
+	status := &model.LLMBudgetStatus{
+		Day:              today,
+		DailyBudget:      s.dailyBudget,
+		DailyTokenBudget: s.dailyTokenBudget,
+	}
+	if len(days) > 0 {
+		status.Cost = days[0].Cost
+		status.TotalTokens = days[0].TotalTokens
+	}
+	status.Exceeded = (s.dailyBudget > 0 && status.Cost >= s.dailyBudget) ||
+		(s.dailyTokenBudget > 0 && status.TotalTokens >= s.dailyTokenBudget)
+	return status, nil
+}
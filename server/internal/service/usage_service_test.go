@@ -0,0 +1,116 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/config"
+	"github.com/xumoe-c/maiecho/server/internal/llm"
+	"github.com/xumoe-c/maiecho/server/internal/storage"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestDatabase(t *testing.T) *storage.Database {
+	t.Helper()
+	d, err := storage.NewDatabase(filepath.Join(t.TempDir(), "maiecho.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+	return d
+}
+
+// newTestUsageService 创建使用指定时钟的用量服务
+func newTestUsageService(d *storage.Database, cfg config.LLMConfig, clock *fakeClock) *usageServiceImpl {
+	s := NewUsageService(d, cfg).(*usageServiceImpl)
+	s.now = clock.Now
+	return s
+}
+
+func TestGetBudgetStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		budget       float64
+		tokenBudget  int64
+		usages       []llm.Usage
+		yesterday    []llm.Usage // 前一天的用量，不计入今日预算
+		wantCost     float64
+		wantTokens   int64
+		wantExceeded bool
+	}{
+		{
+			name:     "no budget never exceeded",
+			usages:   []llm.Usage{{Role: "analyst", Cost: 100, PromptTokens: 1_000_000}},
+			wantCost: 100, wantTokens: 1_000_000,
+		},
+		{
+			name:     "under cost budget",
+			budget:   1,
+			usages:   []llm.Usage{{Role: "analyst", Cost: 0.4}, {Role: "advisor", Cost: 0.5}},
+			wantCost: 0.9,
+		},
+		{
+			name:     "cost budget reached",
+			budget:   1,
+			usages:   []llm.Usage{{Role: "analyst", Cost: 0.5}, {Role: "advisor", Cost: 0.5}},
+			wantCost: 1, wantExceeded: true,
+		},
+		{
+			name:        "token budget exceeded",
+			tokenBudget: 1000,
+			usages:      []llm.Usage{{Role: "analyst", PromptTokens: 800, CompletionTokens: 300}},
+			wantTokens:  1100, wantExceeded: true,
+		},
+		{
+			name:        "either budget exceeds",
+			budget:      10,
+			tokenBudget: 1000,
+			usages:      []llm.Usage{{Role: "analyst", Cost: 0.1, PromptTokens: 1000}},
+			wantCost:    0.1, wantTokens: 1000, wantExceeded: true,
+		},
+		{
+			name:      "yesterday not counted",
+			budget:    1,
+			yesterday: []llm.Usage{{Role: "analyst", Cost: 5}},
+			usages:    []llm.Usage{{Role: "analyst", Cost: 0.2}},
+			wantCost:  0.2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{t: time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)}
+			s := newTestUsageService(newTestDatabase(t), config.LLMConfig{DailyBudget: tt.budget, DailyTokenBudget: tt.tokenBudget}, clock)
+
+			for _, u := range tt.yesterday {
+				s.RecordUsage(u)
+			}
+			clock.Advance(2 * time.Hour)
+			for _, u := range tt.usages {
+				s.RecordUsage(u)
+			}
+
+			status, err := s.GetBudgetStatus()
+			if err != nil {
+				t.Fatalf("GetBudgetStatus() error = %v", err)
+			}
+			if status.Day != "2024-03-02" || status.DailyBudget != tt.budget || status.DailyTokenBudget != tt.tokenBudget {
+				t.Errorf("status = %+v, want day 2024-03-02 with the configured budgets", status)
+			}
+			if diff := status.Cost - tt.wantCost; diff > 1e-9 || diff < -1e-9 || status.TotalTokens != tt.wantTokens {
+				t.Errorf("usage = %v / %d tokens, want %v / %d", status.Cost, status.TotalTokens, tt.wantCost, tt.wantTokens)
+			}
+			if status.Exceeded != tt.wantExceeded {
+				t.Errorf("Exceeded = %v, want %v", status.Exceeded, tt.wantExceeded)
+			}
+		})
+	}
+}
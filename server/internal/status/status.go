@@ -2,38 +2,126 @@ package status
 
 import (
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/xumoe-c/maiecho/server/internal/logger"
 )
 
 type SystemStatus struct {
-	Uptime       time.Duration `json:"uptime"`
-	Goroutines   int           `json:"goroutines"`
-	MemoryUsage  uint64        `json:"memory_usage_mb"`
-	ActiveTasks  int           `json:"active_tasks"`
-	LastLogEntry string        `json:"last_log_entry"`
+	Uptime       time.Duration       `json:"uptime"`
+	Goroutines   int                 `json:"goroutines"`
+	MemoryUsage  uint64              `json:"memory_usage_mb"`
+	ActiveTasks  int                 `json:"active_tasks"`
+	LastLogEntry string              `json:"last_log_entry"`
+	Scheduler    *SchedulerStatus    `json:"scheduler,omitempty"`
+	Collectors   []CollectorStatus   `json:"collectors"`
+	Discovery    *DiscoveryStatus    `json:"discovery,omitempty"`
+	LLMInFlight  int64               `json:"llm_in_flight"`
+	RecentErrors []logger.ErrorEntry `json:"recent_errors"`
+}
+
+// SchedulerStatus 描述任务队列与 Worker 的运行情况
+type SchedulerStatus struct {
+	Workers      int   `json:"workers"`
+	BusyWorkers  int   `json:"busy_workers"`
+	QueueLength  int64 `json:"queue_length"` // 待执行任务数
+	RunningTasks int64 `json:"running_tasks"`
+	FailedTasks  int64 `json:"failed_tasks"`
+}
+
+// CollectorStatus 描述单个采集器的状态
+type CollectorStatus struct {
+	Name        string     `json:"name"`
+	Banned      bool       `json:"banned"`
+	BanState    string     `json:"ban_state,omitempty"`    // closed, open, half_open
+	BanTrips    int        `json:"ban_trips,omitempty"`    // 连续触发封禁的次数
+	BanReason   string     `json:"ban_reason,omitempty"`   // 最近一次触发封禁的原因
+	BannedUntil *time.Time `json:"banned_until,omitempty"` // 冷却结束时间
+}
+
+// DiscoveryStatus 描述定期发现任务的运行情况
+type DiscoveryStatus struct {
+	Running   bool       `json:"running"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
 }
 
 var (
 	startTime time.Time
+
+	// 各模块注册的状态提供函数
+	providerMu         sync.RWMutex
+	schedulerProvider  func() SchedulerStatus
+	collectorProviders []func() CollectorStatus
+	discoveryProvider  func() DiscoveryStatus
+	llmProvider        func() int64
 )
 
 func Init() {
 	startTime = time.Now()
 }
 
+// RegisterScheduler 注册调度器状态提供函数
+func RegisterScheduler(fn func() SchedulerStatus) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	schedulerProvider = fn
+}
+
+// RegisterCollector 注册采集器状态提供函数，每个采集器注册一次
+func RegisterCollector(fn func() CollectorStatus) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	collectorProviders = append(collectorProviders, fn)
+}
+
+// RegisterDiscovery 注册发现任务状态提供函数
+func RegisterDiscovery(fn func() DiscoveryStatus) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	discoveryProvider = fn
+}
+
+// RegisterLLM 注册 LLM 进行中请求数的提供函数
+func RegisterLLM(fn func() int64) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	llmProvider = fn
+}
+
 func GetSystemStatus() SystemStatus {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 
-	return SystemStatus{
+	status := SystemStatus{
 		Uptime:       time.Since(startTime),
 		Goroutines:   runtime.NumGoroutine(),
 		MemoryUsage:  m.Alloc / 1024 / 1024,
-		ActiveTasks:  0, // TODO: Connect to task manager
 		LastLogEntry: logger.GetLastLog(),
+		Collectors:   []CollectorStatus{},
+		RecentErrors: logger.GetRecentErrors(),
 	}
+
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+
+	if schedulerProvider != nil {
+		s := schedulerProvider()
+		status.Scheduler = &s
+		status.ActiveTasks = s.BusyWorkers
+	}
+	for _, fn := range collectorProviders {
+		status.Collectors = append(status.Collectors, fn())
+	}
+	if discoveryProvider != nil {
+		d := discoveryProvider()
+		status.Discovery = &d
+	}
+	if llmProvider != nil {
+		status.LLMInFlight = llmProvider()
+	}
+
+	return status
 }
 
 func LogStatus() {
@@ -43,5 +131,7 @@ func LogStatus() {
 		"uptime", status.Uptime,
 		"goroutines", status.Goroutines,
 		"memory_mb", status.MemoryUsage,
+		"active_tasks", status.ActiveTasks,
+		"llm_in_flight", status.LLMInFlight,
 	)
 }
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"gorm.io/gorm/clause"
+)
+
+// GetLLMCacheEntry 返回未过期且提示词版本一致的缓存响应，不存在时返回 gorm.ErrRecordNotFound
+func (d *Database) GetLLMCacheEntry(key, promptVersion string) (*model.LLMCacheEntry, error) {
+	var entry model.LLMCacheEntry
+	err := d.DB.Where("key = ? AND prompt_version = ? AND expires_at > ?", key, promptVersion, time.Now()).
+		Take(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// SaveLLMCacheEntry 写入缓存响应，键已存在时覆盖
+func (d *Database) SaveLLMCacheEntry(entry *model.LLMCacheEntry) error {
+	return d.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"model", "prompt_version", "response", "created_at", "expires_at"}),
+	}).Create(entry).Error
+}
+
+// DeleteLLMCacheEntry 删除单个缓存响应
+func (d *Database) DeleteLLMCacheEntry(key string) error {
+	return d.DB.Where("key = ?", key).Delete(&model.LLMCacheEntry{}).Error
+}
+
+// PurgeLLMCache 删除已过期或提示词版本不是 promptVersion 的缓存，返回删除的条数
+func (d *Database) PurgeLLMCache(promptVersion string) (int64, error) {
+	result := d.DB.Where("expires_at <= ? OR prompt_version <> ?", time.Now(), promptVersion).
+		Delete(&model.LLMCacheEntry{})
+	return result.RowsAffected, result.Error
+}
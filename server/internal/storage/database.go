@@ -1,37 +1,116 @@
 package storage
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/xumoe-c/maiecho/server/internal/model"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Database struct {
 	DB *gorm.DB
+
+	ftsEnabled bool // 评论全文索引 (SQLite FTS5) 是否可用
 }
 
-func NewDatabase(dsn string) (*Database, error) {
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+// commentUniqueIndex 评论 (source, external_id) 唯一索引的名称
+const commentUniqueIndex = "idx_comments_source_external_id"
+
+// ErrDuplicateComments 数据库中存在重复评论，无法创建唯一索引
+var ErrDuplicateComments = errors.New("发现重复评论，请先运行 `maiecho dedupe-comments` 合并重复数据")
+
+// ErrUnsupportedDatabase database_url 的协议不受支持
+var ErrUnsupportedDatabase = errors.New("unsupported database_url scheme")
+
+// NewDatabase 打开 databaseURL 指定的数据库并执行结构迁移
+func NewDatabase(databaseURL string) (*Database, error) {
+	d, err := OpenDatabase(databaseURL)
 	if err != nil {
 		return nil, err
 	}
+	// 数据库比程序新时拒绝启动，否则自动应用未执行的迁移
+	if _, err := d.MigrateUp(); err != nil {
+		return nil, err
+	}
+	// 迁移可能创建或重建了 comments 表，重新检查全文索引
+	if err := d.setupCommentSearch(); err != nil {
+		return nil, fmt.Errorf("初始化评论全文索引失败: %w", err)
+	}
+	return d, nil
+}
 
-	// 自动迁移
-	err = db.AutoMigrate(
-		&model.Song{},
-		&model.Chart{},
-		&model.SongAlias{},
-		&model.Comment{},
-		&model.AnalysisResult{},
-		&model.Video{},
-	)
+// OpenDatabase 仅打开数据库连接，不执行迁移 (供维护命令使用)
+// databaseURL 以 postgres:// 或 postgresql:// 开头时使用 PostgreSQL，
+// 否则视为 SQLite 文件路径 (可带 sqlite:// 前缀)
+func OpenDatabase(databaseURL string) (*Database, error) {
+	dialector, err := openDialector(databaseURL)
 	if err != nil {
 		return nil, err
 	}
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	d := &Database{DB: db}
+	// 尽早移除当前程序无法维护的全文索引触发器，保证维护命令也能正常写入评论
+	if err := d.setupCommentSearch(); err != nil {
+		return nil, fmt.Errorf("初始化评论全文索引失败: %w", err)
+	}
+	return d, nil
+}
+
+// openDialector 根据 databaseURL 的协议选择数据库驱动
+func openDialector(databaseURL string) (gorm.Dialector, error) {
+	if databaseURL == "" {
+		return nil, fmt.Errorf("%w: database_url 为空", ErrUnsupportedDatabase)
+	}
+
+	scheme, rest, hasScheme := strings.Cut(databaseURL, "://")
+	if !hasScheme {
+		return openSQLite(databaseURL)
+	}
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return postgres.Open(databaseURL), nil
+	case "sqlite", "sqlite3":
+		return openSQLite(rest)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDatabase, scheme)
+	}
+}
+
+// openSQLite 打开 SQLite 数据库文件，文件所在目录不存在时自动创建
+func openSQLite(path string) (gorm.Dialector, error) {
+	if path != ":memory:" && !strings.HasPrefix(path, "file:") {
+		if dir := filepath.Dir(path); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+			}
+		}
+	}
+	return sqlite.Open(path), nil
+}
 
-	return &Database{DB: db}, nil
+// Driver 返回当前使用的数据库驱动名称 (sqlite / postgres)
+func (d *Database) Driver() string {
+	return d.DB.Dialector.Name()
+}
+
+// likeOperator 返回不区分大小写的模糊匹配运算符
+// SQLite 的 LIKE 对 ASCII 字符默认不区分大小写，PostgreSQL 需要使用 ILIKE 保持一致
+func (d *Database) likeOperator() string {
+	if d.Driver() == "postgres" {
+		return "ILIKE"
+	}
+	return "LIKE"
 }
 
 // 实现基本的 CRUD 操作，可以放在这里或单独的文件中
@@ -40,28 +119,250 @@ func (d *Database) CreateSong(song *model.Song) error {
 }
 
 func (d *Database) UpsertSong(song *model.Song) error {
-	var existing model.Song
-	// 使用 Find 替代 First 避免 "record not found" 日志
-	result := d.DB.Where("game_id = ?", song.GameID).Limit(1).Find(&existing)
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		var existing model.Song
+		// 使用 Find 替代 First 避免 "record not found" 日志
+		result := tx.Where("game_id = ?", song.GameID).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// 谱面单独按 (song_id, difficulty) 更新，保持谱面 ID 稳定
+		if result.RowsAffected == 0 {
+			if err := tx.Omit(clause.Associations).Create(song).Error; err != nil {
+				return err
+			}
+		} else {
+			song.ID = existing.ID
+			song.CreatedAt = existing.CreatedAt
+			// 上次采集时间由调度器维护，不随同步覆盖
+			if err := tx.Omit("LastScrapedAt", clause.Associations).Save(song).Error; err != nil {
+				return err
+			}
+		}
+
+		// 本次同步中不存在的旧谱面保留不动，其分析结果和评论关联仍然有效
+		for i := range song.Charts {
+			song.Charts[i].SongID = song.ID
+			if err := upsertChart(tx, &song.Charts[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// upsertChart 按 (song_id, difficulty) 插入或更新谱面
+// 新谱面或定数、等级、统计数据有变化时记录一条快照，已有谱面的定数或等级变化时另外记录变更事件
+func upsertChart(tx *gorm.DB, chart *model.Chart) error {
+	var existing model.Chart
+	result := tx.Where("song_id = ? AND difficulty = ?", chart.SongID, chart.Difficulty).Limit(1).Find(&existing)
 	if result.Error != nil {
 		return result.Error
 	}
 
 	if result.RowsAffected == 0 {
-		return d.DB.Create(song).Error
+		if err := tx.Omit(clause.Associations).Create(chart).Error; err != nil {
+			return err
+		}
+	} else {
+		chart.ID = existing.ID
+		chart.CreatedAt = existing.CreatedAt
+		if err := tx.Omit(clause.Associations).Save(chart).Error; err != nil {
+			return err
+		}
+		if existing.DS != chart.DS || existing.Level != chart.Level {
+			change := model.ChartChange{
+				ChartID:    chart.ID,
+				SongID:     chart.SongID,
+				Difficulty: chart.Difficulty,
+				OldLevel:   existing.Level,
+				NewLevel:   chart.Level,
+				OldDS:      existing.DS,
+				NewDS:      chart.DS,
+			}
+			if err := tx.Create(&change).Error; err != nil {
+				return err
+			}
+		}
+		if !chartValuesChanged(&existing, chart) {
+			return nil
+		}
 	}
 
-	// 更新已有记录
-	song.ID = existing.ID
+	snapshot := newChartSnapshot(chart)
+	return tx.Create(&snapshot).Error
+}
 
-	return d.DB.Transaction(func(tx *gorm.DB) error {
-		// 删除旧的谱面，确保没有重复或过时的数据
-		if err := tx.Where("song_id = ?", existing.ID).Delete(&model.Chart{}).Error; err != nil {
-			return err
+// newChartSnapshot 根据谱面当前数据生成快照
+func newChartSnapshot(chart *model.Chart) model.ChartSnapshot {
+	return model.ChartSnapshot{
+		ChartID:        chart.ID,
+		Level:          chart.Level,
+		DS:             chart.DS,
+		FitDiff:        chart.FitDiff,
+		AvgAchievement: chart.AvgAchievement,
+		AvgDX:          chart.AvgDX,
+		StdDev:         chart.StdDev,
+		SampleCount:    chart.SampleCount,
+	}
+}
+
+// chartValuesChanged 判断快照记录的字段是否有变化
+func chartValuesChanged(old, updated *model.Chart) bool {
+	return old.Level != updated.Level ||
+		old.DS != updated.DS ||
+		old.FitDiff != updated.FitDiff ||
+		old.AvgAchievement != updated.AvgAchievement ||
+		old.AvgDX != updated.AvgDX ||
+		old.StdDev != updated.StdDev ||
+		old.SampleCount != updated.SampleCount
+}
+
+func (d *Database) GetChart(id uint) (*model.Chart, error) {
+	var chart model.Chart
+	err := d.DB.First(&chart, id).Error
+	return &chart, err
+}
+
+// GetChartChanges 按检测时间倒序返回定数变更事件，filter.PageSize 为 0 时返回全部
+func (d *Database) GetChartChanges(filter model.ChartChangeFilter) ([]model.ChartChangeItem, int64, error) {
+	query := d.DB.Table("chart_changes").
+		Joins("JOIN songs ON songs.id = chart_changes.song_id").
+		Where("chart_changes.deleted_at IS NULL")
+	if !filter.Since.IsZero() {
+		query = query.Where("chart_changes.created_at >= ?", filter.Since)
+	}
+	if filter.SongID != 0 {
+		query = query.Where("chart_changes.song_id = ?", filter.SongID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Select("chart_changes.*, songs.game_id, songs.title, songs.type").
+		Order("chart_changes.created_at DESC, chart_changes.id DESC")
+	if filter.PageSize > 0 {
+		query = query.Offset((filter.Page - 1) * filter.PageSize).Limit(filter.PageSize)
+	}
+
+	items := []model.ChartChangeItem{}
+	if err := query.Scan(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// SearchCharts 按谱面数据与最新分析结果搜索谱面
+// 每个谱面取自身最新的谱面分析结果参与匹配，没有时退回所属歌曲最新的总览结果 (大多数谱面没有单独的评论桶)；
+// 两者都没有的谱面不返回。结果附带所用分析结果的标签
+func (d *Database) SearchCharts(filter model.ChartSearchFilter) ([]model.ChartSearchItem, int64, error) {
+	latestChart := d.DB.Table("analysis_results AS latest_chart").Select("MAX(latest_chart.id)").
+		Where("latest_chart.target_type = ? AND latest_chart.target_id = charts.id AND latest_chart.deleted_at IS NULL", "chart")
+	latestSong := d.DB.Table("analysis_results AS latest_song").Select("MAX(latest_song.id)").
+		Where("latest_song.target_type = ? AND latest_song.target_id = charts.song_id AND latest_song.deleted_at IS NULL", "song")
+	query := d.DB.Table("charts").
+		Joins("JOIN songs ON songs.id = charts.song_id").
+		Joins("JOIN analysis_results ON analysis_results.id = COALESCE((?), (?))", latestChart, latestSong).
+		Where("charts.deleted_at IS NULL AND songs.deleted_at IS NULL")
+
+	if filter.Difficulty != "" {
+		query = query.Where("charts.difficulty = ?", filter.Difficulty)
+	}
+	if filter.Level != "" {
+		query = query.Where("charts.level = ?", filter.Level)
+	}
+	if filter.MinDS > 0 {
+		query = query.Where("charts.ds >= ?", filter.MinDS)
+	}
+	if filter.MaxDS > 0 {
+		query = query.Where("charts.ds <= ?", filter.MaxDS)
+	}
+	if filter.Type != "" {
+		query = query.Where("songs.type = ?", filter.Type)
+	}
+	if filter.Sentiment != "" {
+		query = query.Where("analysis_results.sentiment = ?", filter.Sentiment)
+	}
+	// 每个标签各用一个 EXISTS 子查询，实现 "同时包含" 的语义
+	// 标签是 LLM 输出的自由文本，按子串匹配，"转圈" 可以匹配 "大量转圈"
+	like := d.likeOperator()
+	hasTag := func(kind, value string) *gorm.DB {
+		return d.DB.Table("analysis_tags").Select("1").
+			Where("analysis_tags.result_id = analysis_results.id AND analysis_tags.kind = ? AND analysis_tags.value "+like+" ?", kind, "%"+value+"%")
+	}
+	for _, pattern := range filter.Patterns {
+		query = query.Where("EXISTS (?)", hasTag(model.AnalysisTagPattern, pattern))
+	}
+	for _, tag := range filter.Tags {
+		query = query.Where("EXISTS (?)", hasTag(model.AnalysisTagDifficulty, tag))
+	}
+	// 没有拟合定数 (fit_diff 为 0) 的谱面无法计算差值
+	if filter.MinDelta != nil || filter.MaxDelta != nil {
+		query = query.Where("charts.fit_diff > 0")
+	}
+	if filter.MinDelta != nil {
+		query = query.Where("charts.fit_diff - charts.ds >= ?", *filter.MinDelta)
+	}
+	if filter.MaxDelta != nil {
+		query = query.Where("charts.fit_diff - charts.ds <= ?", *filter.MaxDelta)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Select("charts.*, songs.game_id, songs.title, songs.type, charts.fit_diff - charts.ds AS fit_diff_delta, " +
+		"analysis_results.id AS result_id, analysis_results.target_type AS result_type, analysis_results.summary, analysis_results.sentiment").
+		Order("charts.ds DESC, charts.id")
+	if filter.PageSize > 0 {
+		query = query.Offset((filter.Page - 1) * filter.PageSize).Limit(filter.PageSize)
+	}
+
+	items := []model.ChartSearchItem{}
+	if err := query.Scan(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(items) == 0 {
+		return items, total, nil
+	}
+
+	resultIDs := make([]uint, len(items))
+	for i := range items {
+		resultIDs[i] = items[i].ResultID
+	}
+	var tags []model.AnalysisTag
+	if err := d.DB.Where("result_id IN ?", resultIDs).Order("id").Find(&tags).Error; err != nil {
+		return nil, 0, err
+	}
+	byResult := make(map[uint][]model.AnalysisTag, len(items))
+	for _, tag := range tags {
+		byResult[tag.ResultID] = append(byResult[tag.ResultID], tag)
+	}
+	for i := range items {
+		items[i].Tags = byResult[items[i].ResultID]
+		if items[i].Tags == nil {
+			items[i].Tags = []model.AnalysisTag{}
 		}
-		// 保存歌曲（更新字段并插入新的谱面）
-		return tx.Save(song).Error
-	})
+	}
+	return items, total, nil
+}
+
+// GetChartChangesByChartID 按时间顺序返回谱面的定数变更事件
+func (d *Database) GetChartChangesByChartID(chartID uint) ([]model.ChartChange, error) {
+	var changes []model.ChartChange
+	err := d.DB.Where("chart_id = ?", chartID).Order("created_at, id").Find(&changes).Error
+	return changes, err
+}
+
+// GetChartSnapshots 按时间顺序返回谱面的历史快照
+func (d *Database) GetChartSnapshots(chartID uint) ([]model.ChartSnapshot, error) {
+	var snapshots []model.ChartSnapshot
+	err := d.DB.Where("chart_id = ?", chartID).Order("created_at, id").Find(&snapshots).Error
+	return snapshots, err
 }
 
 func (d *Database) GetSong(id uint) (*model.Song, error) {
@@ -102,12 +403,13 @@ func (d *Database) GetSongs(filter model.SongFilter) ([]model.Song, int64, error
 	}
 	if filter.Keyword != "" {
 		keyword := "%" + filter.Keyword + "%"
+		like := d.likeOperator()
 		// Search in Title, Artist OR Aliases
 		// 使用 Group 条件来确保 OR 逻辑正确，并且正确处理子查询
 		query = query.Where(
-			d.DB.Where("title LIKE ?", keyword).
-				Or("artist LIKE ?", keyword).
-				Or("id IN (?)", d.DB.Model(&model.SongAlias{}).Select("song_id").Where("alias LIKE ?", keyword)),
+			d.DB.Where("title "+like+" ?", keyword).
+				Or("artist "+like+" ?", keyword).
+				Or("id IN (?)", d.DB.Model(&model.SongAlias{}).Select("song_id").Where("alias "+like+" ?", keyword)),
 		)
 	}
 
@@ -160,16 +462,115 @@ func (d *Database) CreateComment(comment *model.Comment) error {
 	return d.DB.Create(comment).Error
 }
 
-func (d *Database) UpdateComment(comment *model.Comment) error {
-	return d.DB.Save(comment).Error
+// UpsertComment 按 (source, external_id) 插入或更新评论
+// 已存在时更新内容、点赞数等会变化的字段，并保留已有的歌曲关联
+func (d *Database) UpsertComment(comment *model.Comment) error {
+	updates := clause.AssignmentColumns([]string{"source_title", "content", "author", "post_date", "likes", "updated_at"})
+	updates = append(updates,
+		clause.Assignment{Column: clause.Column{Name: "song_id"}, Value: gorm.Expr("COALESCE(comments.song_id, excluded.song_id)")},
+		clause.Assignment{Column: clause.Column{Name: "deleted_at"}, Value: nil},
+	)
+	return d.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source"}, {Name: "external_id"}},
+		DoUpdates: updates,
+	}).Create(comment).Error
 }
 
-func (d *Database) GetCommentsByKeyword(keyword string) ([]model.Comment, error) {
-	var comments []model.Comment
-	// 使用 LIKE 查询内容和来源标题
-	// TODO: 考虑全文索引以提高性能
-	err := d.DB.Where("content LIKE ? OR source_title LIKE ?", "%"+keyword+"%", "%"+keyword+"%").Find(&comments).Error
-	return comments, err
+func countDuplicateCommentGroups(db *gorm.DB) (int64, error) {
+	var count int64
+	err := db.Raw(`SELECT COUNT(*) FROM (SELECT 1 FROM comments GROUP BY source, external_id HAVING COUNT(*) > 1) dup`).Scan(&count).Error
+	return count, err
+}
+
+// DedupeComments 合并 (source, external_id) 相同的重复评论
+// 保留最早的一条记录，内容取最新的一条，歌曲/谱面关联取第一个非空值，其余记录被物理删除。
+// 只读写旧表结构中已有的列，因此可以在迁移 (新增列与唯一索引) 之前执行。返回删除的记录数
+func (d *Database) DedupeComments() (int64, error) {
+	type dupKey struct {
+		Source     string
+		ExternalID string
+	}
+	var keys []dupKey
+	if err := d.DB.Raw(`SELECT source, external_id FROM comments GROUP BY source, external_id HAVING COUNT(*) > 1`).Scan(&keys).Error; err != nil {
+		return 0, err
+	}
+
+	type commentRow struct {
+		ID          uint
+		SourceTitle string
+		Content     string
+		Author      string
+		PostDate    time.Time
+		SongID      *uint
+		ChartID     *uint
+		SearchTag   string
+		DeletedAt   gorm.DeletedAt
+	}
+
+	var removed int64
+	for _, key := range keys {
+		err := d.DB.Transaction(func(tx *gorm.DB) error {
+			var rows []commentRow
+			if err := tx.Table("comments").
+				Select("id, source_title, content, author, post_date, song_id, chart_id, search_tag, deleted_at").
+				Where("source = ? AND external_id = ?", key.Source, key.ExternalID).
+				Order("id").Scan(&rows).Error; err != nil {
+				return err
+			}
+			if len(rows) < 2 {
+				return nil
+			}
+
+			keep, latest := rows[0], rows[len(rows)-1]
+			deleted := keep.DeletedAt.Valid
+			ids := make([]uint, 0, len(rows)-1)
+			for _, r := range rows[1:] {
+				if keep.SongID == nil {
+					keep.SongID = r.SongID
+				}
+				if keep.ChartID == nil {
+					keep.ChartID = r.ChartID
+				}
+				if keep.SearchTag == "" {
+					keep.SearchTag = r.SearchTag
+				}
+				if !r.DeletedAt.Valid {
+					deleted = false
+				}
+				ids = append(ids, r.ID)
+			}
+
+			updates := map[string]interface{}{
+				"source_title": latest.SourceTitle,
+				"content":      latest.Content,
+				"author":       latest.Author,
+				"post_date":    latest.PostDate,
+				"song_id":      keep.SongID,
+				"chart_id":     keep.ChartID,
+				"search_tag":   keep.SearchTag,
+			}
+			if !deleted {
+				updates["deleted_at"] = nil
+			}
+			if err := tx.Table("comments").Where("id = ?", keep.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+			result := tx.Exec("DELETE FROM comments WHERE id IN ?", ids)
+			if result.Error != nil {
+				return result.Error
+			}
+			removed += result.RowsAffected
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+func (d *Database) UpdateComment(comment *model.Comment) error {
+	return d.DB.Save(comment).Error
 }
 
 func (d *Database) GetCommentsBySongID(songID uint) ([]model.Comment, error) {
@@ -188,7 +589,9 @@ func (d *Database) GetAnalysisResultBySongID(songID uint) (*model.AnalysisResult
 
 func (d *Database) GetAnalysisResultsByTarget(targetType string, targetID uint) (*model.AnalysisResult, error) {
 	var result model.AnalysisResult
-	err := d.DB.Where("target_type = ? AND target_id = ?", targetType, targetID).Order("created_at desc").First(&result).Error
+	err := d.DB.Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("target_type = ? AND target_id = ?", targetType, targetID).Order("created_at desc").First(&result).Error
 	return &result, err
 }
 
@@ -198,18 +601,254 @@ func (d *Database) CreateVideo(video *model.Video) error {
 	var existing model.Video
 	result := d.DB.Where("external_id = ?", video.ExternalID).First(&existing)
 	if result.RowsAffected > 0 {
-		// 如果需要，更新字段，或者直接返回 nil
+		// 更新字段 (播放量、点赞等统计会随时间变化)
 		video.ID = existing.ID
+		video.CreatedAt = existing.CreatedAt
 		return d.DB.Save(video).Error
 	}
 	return d.DB.Create(video).Error
 }
 
 func (d *Database) UpdateSongLastScrapedTime(songID uint) error {
-	now := time.Now().Format(time.RFC3339)
-	return d.DB.Model(&model.Song{}).Where("id = ?", songID).Update("last_scraped", now).Error
+	return d.DB.Model(&model.Song{}).Where("id = ?", songID).Update("last_scraped_at", time.Now()).Error
 }
 
 func (d *Database) UpdateSongAliasSuitability(aliasID uint, isSuitable bool) error {
 	return d.DB.Model(&model.SongAlias{}).Where("id = ?", aliasID).Update("is_suitable", isSuitable).Error
 }
+
+func (d *Database) CreateTask(task *model.Task) error {
+	return d.DB.Create(task).Error
+}
+
+func (d *Database) CreateFollowUpTask(task *model.Task) (bool, error) {
+	created := false
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.Task{}).
+			Where("job_id = ? AND keyword = ? AND song_id = ? AND source = ? AND status IN ?",
+				task.JobID, task.Keyword, task.SongID, task.Source, []string{model.TaskStatusPending, model.TaskStatusRunning}).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		if err := tx.Create(task).Error; err != nil {
+			return err
+		}
+		if task.JobID != 0 {
+			if err := tx.Model(&model.Job{}).Where("id = ?", task.JobID).
+				Update("total", gorm.Expr("total + 1")).Error; err != nil {
+				return err
+			}
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// ClaimNextTask 领取一条到期的待执行任务，并将其标记为运行中
+// 如果当前没有可执行的任务，返回 nil
+func (d *Database) ClaimNextTask() (*model.Task, error) {
+	for {
+		var task model.Task
+		result := d.DB.Where("status = ? AND next_run_at <= ?", model.TaskStatusPending, time.Now()).
+			Order("next_run_at asc, id asc").
+			Limit(1).
+			Find(&task)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, nil
+		}
+
+		// 使用条件更新保证同一任务只会被一个 Worker 领取
+		now := time.Now()
+		claim := d.DB.Model(&model.Task{}).
+			Where("id = ? AND status = ?", task.ID, model.TaskStatusPending).
+			Updates(map[string]interface{}{
+				"status":     model.TaskStatusRunning,
+				"attempts":   gorm.Expr("attempts + 1"),
+				"started_at": now,
+			})
+		if claim.Error != nil {
+			return nil, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			// 已被其他 Worker 领取，重新查找
+			continue
+		}
+
+		task.Status = model.TaskStatusRunning
+		task.Attempts++
+		task.StartedAt = &now
+		return &task, nil
+	}
+}
+
+func (d *Database) UpdateTask(task *model.Task) error {
+	return d.DB.Save(task).Error
+}
+
+// ResetRunningTasks 将上次进程退出时仍处于运行中的任务重新置为待执行，并归还被中断的那次尝试
+func (d *Database) ResetRunningTasks() (int64, error) {
+	result := d.DB.Model(&model.Task{}).
+		Where("status = ?", model.TaskStatusRunning).
+		Updates(map[string]interface{}{
+			"status":      model.TaskStatusPending,
+			"attempts":    gorm.Expr("CASE WHEN attempts > 0 THEN attempts - 1 ELSE 0 END"),
+			"next_run_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// HasActiveTask 检查是否存在相同关键词和歌曲的待执行或运行中任务
+func (d *Database) HasActiveTask(keyword string, songID uint) (bool, error) {
+	var count int64
+	err := d.DB.Model(&model.Task{}).
+		Where("keyword = ? AND song_id = ? AND status IN ?", keyword, songID, []string{model.TaskStatusPending, model.TaskStatusRunning}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CountTasksByStatus 统计各状态的任务数量
+func (d *Database) CountTasksByStatus() (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := d.DB.Model(&model.Task{}).Select("status, count(*) as count").Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+func (d *Database) CreateJob(job *model.Job) error {
+	return d.DB.Create(job).Error
+}
+
+func (d *Database) GetJob(id uint) (*model.Job, error) {
+	var job model.Job
+	err := d.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id asc")
+	}).First(&job, id).Error
+	return &job, err
+}
+
+func (d *Database) GetJobs(filter model.JobFilter) ([]model.Job, int64, error) {
+	var jobs []model.Job
+	var total int64
+
+	query := d.DB.Model(&model.Job{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	err := query.Order("id desc").Offset(offset).Limit(filter.PageSize).Find(&jobs).Error
+	return jobs, total, err
+}
+
+func (d *Database) UpdateJob(job *model.Job) error {
+	return d.DB.Omit("Items").Save(job).Error
+}
+
+// StartJob 将待执行的作业标记为运行中并记录开始时间，作业已开始或已结束时不做修改
+func (d *Database) StartJob(id uint) error {
+	return d.DB.Model(&model.Job{}).
+		Where("id = ? AND status = ?", id, model.JobStatusPending).
+		Updates(map[string]interface{}{
+			"status":     model.JobStatusRunning,
+			"started_at": time.Now(),
+		}).Error
+}
+
+// PauseJob 将运行中的作业标记为暂停，并记录预计恢复的时间
+func (d *Database) PauseJob(id uint, resumeAt time.Time) error {
+	return d.DB.Model(&model.Job{}).
+		Where("id = ? AND status = ?", id, model.JobStatusRunning).
+		Updates(map[string]interface{}{
+			"status":    model.JobStatusPaused,
+			"resume_at": resumeAt,
+		}).Error
+}
+
+// ResumeJob 将暂停中的作业恢复为运行中并清除预计恢复时间
+func (d *Database) ResumeJob(id uint) error {
+	return d.DB.Model(&model.Job{}).
+		Where("id = ? AND status = ?", id, model.JobStatusPaused).
+		Updates(map[string]interface{}{
+			"status":    model.JobStatusRunning,
+			"resume_at": nil,
+		}).Error
+}
+
+// AbortUnfinishedJobs 将指定类型中仍处于待执行、运行中或暂停中的作业标记为失败，未完成的子项计为失败
+// 用于进程重启后收尾无法恢复执行的作业
+func (d *Database) AbortUnfinishedJobs(jobType string) (int64, error) {
+	result := d.DB.Model(&model.Job{}).
+		Where("type = ? AND status IN ?", jobType, []string{model.JobStatusPending, model.JobStatusRunning, model.JobStatusPaused}).
+		Updates(map[string]interface{}{
+			"status":      model.JobStatusFailed,
+			"failed":      gorm.Expr("total - succeeded"),
+			"finished_at": time.Now(),
+			"resume_at":   nil,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// RecordJobItem 保存作业子项的执行结果，并同步更新作业的进度与状态
+func (d *Database) RecordJobItem(item *model.JobItem) error {
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+
+		var job model.Job
+		if err := tx.First(&job, item.JobID).Error; err != nil {
+			return err
+		}
+
+		if item.Status == model.JobStatusDone {
+			job.Succeeded++
+		} else {
+			job.Failed++
+		}
+
+		if job.StartedAt == nil {
+			job.StartedAt = item.StartedAt
+		}
+		if job.Status == model.JobStatusPending {
+			job.Status = model.JobStatusRunning
+		}
+
+		// 所有子项均已结束
+		if job.Succeeded+job.Failed >= job.Total {
+			now := time.Now()
+			job.FinishedAt = &now
+			if job.Succeeded == 0 && job.Failed > 0 {
+				job.Status = model.JobStatusFailed
+			} else {
+				job.Status = model.JobStatusDone
+			}
+		}
+
+		return tx.Omit("Items").Save(&job).Error
+	})
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"gorm.io/gorm"
+)
+
+// ErrSchemaAhead 数据库已应用了当前程序不认识的迁移 (数据库比程序新)
+var ErrSchemaAhead = errors.New("数据库结构版本高于当前程序，请升级程序后再启动")
+
+// Migration 一个带编号的结构迁移
+// Up/Down 在同一个事务中执行，Down 为 nil 的迁移不可回滚
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration 记录已应用的迁移
+type SchemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"not null"`
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationState 迁移的应用状态 (用于 `maiecho migrate status`)
+type MigrationState struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Unknown   bool // 数据库中存在但当前程序中没有定义 (数据库比程序新)
+}
+
+// LatestSchemaVersion 当前程序已知的最新迁移版本
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// ensureMigrationTable 创建 schema_migrations 表
+func (d *Database) ensureMigrationTable() error {
+	return d.DB.AutoMigrate(&SchemaMigration{})
+}
+
+func (d *Database) appliedMigrations() (map[int]SchemaMigration, error) {
+	if err := d.ensureMigrationTable(); err != nil {
+		return nil, fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+	var rows []SchemaMigration
+	if err := d.DB.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// CheckSchemaVersion 数据库中存在当前程序不认识的迁移时返回 ErrSchemaAhead
+func (d *Database) CheckSchemaVersion() error {
+	applied, err := d.appliedMigrations()
+	if err != nil {
+		return err
+	}
+	latest := LatestSchemaVersion()
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w (数据库版本 %d，程序支持到 %d)", ErrSchemaAhead, version, latest)
+		}
+	}
+	return nil
+}
+
+// MigrateUp 按版本顺序应用所有未执行的迁移，返回本次应用的迁移
+func (d *Database) MigrateUp() ([]Migration, error) {
+	if err := d.CheckSchemaVersion(); err != nil {
+		return nil, err
+	}
+	applied, err := d.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := d.DB.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("迁移 %d_%s 失败: %w", m.Version, m.Name, err)
+		}
+		logger.Info("已应用迁移", "module", "storage.migrate", "version", m.Version, "name", m.Name)
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown 按版本倒序回滚最近应用的 steps 个迁移，返回本次回滚的迁移
+func (d *Database) MigrateDown(steps int) ([]Migration, error) {
+	if err := d.CheckSchemaVersion(); err != nil {
+		return nil, err
+	}
+	applied, err := d.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			return done, fmt.Errorf("迁移 %d_%s 不支持回滚", m.Version, m.Name)
+		}
+		err := d.DB.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("回滚迁移 %d_%s 失败: %w", m.Version, m.Name, err)
+		}
+		logger.Info("已回滚迁移", "module", "storage.migrate", "version", m.Version, "name", m.Name)
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrationStatus 返回所有迁移的应用状态，包含数据库中存在但程序不认识的迁移
+func (d *Database) MigrationStatus() ([]MigrationState, error) {
+	applied, err := d.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			state.Applied = true
+			state.AppliedAt = &appliedAt
+			delete(applied, m.Version)
+		}
+		states = append(states, state)
+	}
+
+	// 剩余的记录是程序不认识的迁移
+	unknown := make([]int, 0, len(applied))
+	for version := range applied {
+		unknown = append(unknown, version)
+	}
+	sort.Ints(unknown)
+	for _, version := range unknown {
+		row := applied[version]
+		appliedAt := row.AppliedAt
+		states = append(states, MigrationState{Version: row.Version, Name: row.Name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+	}
+	return states, nil
+}
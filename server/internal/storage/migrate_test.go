@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMigrateDownAndUp(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, d *Database) {
+		states, err := d.MigrationStatus()
+		if err != nil {
+			t.Fatalf("MigrationStatus() error = %v", err)
+		}
+		for _, st := range states {
+			if !st.Applied || st.Unknown {
+				t.Errorf("migration %d_%s state = %+v, want applied", st.Version, st.Name, st)
+			}
+		}
+
+		scrapedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		if err := d.DB.Exec("INSERT INTO songs (game_id, title, last_scraped_at) VALUES (?, ?, ?)", 834, "PANDORA PARADOXXX", scrapedAt).Error; err != nil {
+			t.Fatalf("插入歌曲失败: %v", err)
+		}
+
+		// 回滚到 1_baseline 后 last_scraped_at 转回 RFC3339 字符串
+		steps := LatestSchemaVersion() - 1
+		reverted, err := d.MigrateDown(steps)
+		if err != nil || len(reverted) != steps || reverted[steps-1].Name != "song_last_scraped_at" {
+			t.Fatalf("MigrateDown(%d) = %v, %v", steps, reverted, err)
+		}
+		if d.DB.Migrator().HasColumn(&songV2{}, "LastScrapedAt") {
+			t.Error("last_scraped_at still exists after down")
+		}
+		var legacy string
+		if err := d.DB.Raw("SELECT last_scraped FROM songs WHERE game_id = ?", 834).Scan(&legacy).Error; err != nil {
+			t.Fatalf("读取 last_scraped 失败: %v", err)
+		}
+		if parsed, err := time.Parse(time.RFC3339, legacy); err != nil || !parsed.Equal(scrapedAt) {
+			t.Errorf("last_scraped = %q, want %s", legacy, scrapedAt.Format(time.RFC3339))
+		}
+
+		// 重新应用后恢复为时间列
+		applied, err := d.MigrateUp()
+		if err != nil || len(applied) != steps {
+			t.Fatalf("MigrateUp() = %v, %v", applied, err)
+		}
+		var restored struct{ LastScrapedAt time.Time }
+		if err := d.DB.Table("songs").Select("last_scraped_at").Where("game_id = ?", 834).Scan(&restored).Error; err != nil {
+			t.Fatalf("读取 last_scraped_at 失败: %v", err)
+		}
+		if !restored.LastScrapedAt.Equal(scrapedAt) {
+			t.Errorf("last_scraped_at = %v, want %v", restored.LastScrapedAt, scrapedAt)
+		}
+		if d.DB.Migrator().HasColumn(&songV1{}, "LastScraped") {
+			t.Error("last_scraped still exists after up")
+		}
+	})
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	// 模拟引入版本化迁移之前由 AutoMigrate 创建的数据库
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := OpenDatabase(path)
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	if err := legacy.DB.AutoMigrate(&songV1{}, &chartV1{}, &songAliasV1{}, &commentV1{}); err != nil {
+		t.Fatalf("创建旧表结构失败: %v", err)
+	}
+	if err := legacy.DB.Exec("INSERT INTO songs (game_id, title, last_scraped) VALUES (1, 'valid', '2024-03-01T20:15:00+08:00'), (2, 'broken', 'yesterday')").Error; err != nil {
+		t.Fatalf("插入旧数据失败: %v", err)
+	}
+
+	d, err := NewDatabase(path)
+	if err != nil {
+		t.Fatalf("NewDatabase(legacy) error = %v", err)
+	}
+
+	var rows []struct {
+		GameID        int
+		LastScrapedAt *time.Time
+	}
+	if err := d.DB.Table("songs").Select("game_id, last_scraped_at").Order("game_id").Scan(&rows).Error; err != nil {
+		t.Fatalf("读取歌曲失败: %v", err)
+	}
+	want := time.Date(2024, 3, 1, 12, 15, 0, 0, time.UTC)
+	if len(rows) != 2 || rows[0].LastScrapedAt == nil || !rows[0].LastScrapedAt.Equal(want) {
+		t.Fatalf("rows = %+v, want first last_scraped_at %v", rows, want)
+	}
+	// 无法解析的旧值被丢弃
+	if rows[1].LastScrapedAt != nil {
+		t.Errorf("broken last_scraped converted to %v, want nil", rows[1].LastScrapedAt)
+	}
+	if !d.DB.Migrator().HasTable("tasks") {
+		t.Error("baseline did not create missing tables")
+	}
+}
+
+func TestRefuseSchemaAhead(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, d *Database) {
+		future := SchemaMigration{Version: LatestSchemaVersion() + 1, Name: "from_the_future", AppliedAt: time.Now()}
+		if err := d.DB.Create(&future).Error; err != nil {
+			t.Fatalf("写入迁移记录失败: %v", err)
+		}
+
+		if _, err := d.MigrateUp(); !errors.Is(err, ErrSchemaAhead) {
+			t.Errorf("MigrateUp() error = %v, want ErrSchemaAhead", err)
+		}
+		if _, err := d.MigrateDown(1); !errors.Is(err, ErrSchemaAhead) {
+			t.Errorf("MigrateDown() error = %v, want ErrSchemaAhead", err)
+		}
+
+		states, err := d.MigrationStatus()
+		if err != nil {
+			t.Fatalf("MigrationStatus() error = %v", err)
+		}
+		last := states[len(states)-1]
+		if !last.Unknown || last.Version != future.Version {
+			t.Errorf("last state = %+v, want unknown future migration", last)
+		}
+	})
+}
+
+func TestMigrateChartHistory(t *testing.T) {
+	// 模拟旧版本多次同步后留下的软删除谱面
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := OpenDatabase(path)
+	if err != nil {
+		t.Fatalf("OpenDatabase() error = %v", err)
+	}
+	if _, err := legacy.MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp() error = %v", err)
+	}
+	// 回滚到 2_song_last_scraped_at
+	if _, err := legacy.MigrateDown(LatestSchemaVersion() - 2); err != nil {
+		t.Fatalf("MigrateDown() error = %v", err)
+	}
+	seed := []string{
+		"INSERT INTO songs (id, game_id, title) VALUES (1, 834, 'PANDORA PARADOXXX')",
+		"INSERT INTO charts (id, song_id, difficulty, ds, deleted_at) VALUES (1, 1, 'Master', 14.7, '2024-01-01 00:00:00')",
+		"INSERT INTO charts (id, song_id, difficulty, ds, deleted_at) VALUES (2, 1, 'Master', 14.8, '2024-02-01 00:00:00')",
+		"INSERT INTO charts (id, song_id, difficulty, ds, updated_at) VALUES (3, 1, 'Master', 14.8, '2024-02-01 00:00:00')",
+		"INSERT INTO charts (id, song_id, difficulty, ds, deleted_at) VALUES (4, 1, 'Re:Master', 15.0, '2024-01-01 00:00:00')",
+		"INSERT INTO charts (id, song_id, difficulty, ds, deleted_at) VALUES (5, 1, 'Re:Master', 15.0, '2024-02-01 00:00:00')",
+		"INSERT INTO analysis_results (target_type, target_id, summary) VALUES ('chart', 1, 'old master'), ('chart', 5, 'old remaster'), ('song', 1, 'song')",
+		"INSERT INTO comments (source, external_id, content, chart_id) VALUES ('Bilibili', '1', 'master', 2)",
+	}
+	for _, stmt := range seed {
+		if err := legacy.DB.Exec(stmt).Error; err != nil {
+			t.Fatalf("插入旧数据失败: %v", err)
+		}
+	}
+
+	d, err := NewDatabase(path)
+	if err != nil {
+		t.Fatalf("NewDatabase(legacy) error = %v", err)
+	}
+
+	var ids []uint
+	if err := d.DB.Raw("SELECT id FROM charts ORDER BY id").Scan(&ids).Error; err != nil {
+		t.Fatalf("读取谱面失败: %v", err)
+	}
+	// 优先保留未删除的谱面，全部已删除时保留最新的一条
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 5 {
+		t.Fatalf("chart ids = %v, want [3 5]", ids)
+	}
+	var targets []uint
+	if err := d.DB.Raw("SELECT target_id FROM analysis_results ORDER BY id").Scan(&targets).Error; err != nil {
+		t.Fatalf("读取分析结果失败: %v", err)
+	}
+	if len(targets) != 3 || targets[0] != 3 || targets[1] != 5 || targets[2] != 1 {
+		t.Errorf("analysis target ids = %v, want [3 5 1]", targets)
+	}
+	var chartID uint
+	if err := d.DB.Raw("SELECT chart_id FROM comments").Scan(&chartID).Error; err != nil || chartID != 3 {
+		t.Errorf("comment chart_id = %d (%v), want 3", chartID, err)
+	}
+
+	// 未删除的谱面生成初始快照
+	snapshots, err := d.GetChartSnapshots(3)
+	if err != nil || len(snapshots) != 1 || snapshots[0].DS != 14.8 {
+		t.Errorf("GetChartSnapshots(3) = %+v, %v, want one snapshot with ds 14.8", snapshots, err)
+	}
+	if err := d.DB.Exec("INSERT INTO charts (song_id, difficulty) VALUES (1, 'Master')").Error; err == nil {
+		t.Error("duplicate (song_id, difficulty) inserted, want unique constraint error")
+	}
+}
+
+func TestMigrateChartChangesBackfill(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, d *Database) {
+		// 回滚到 3_chart_history
+		steps := LatestSchemaVersion() - 3
+		if _, err := d.MigrateDown(steps); err != nil {
+			t.Fatalf("MigrateDown(%d) error = %v", steps, err)
+		}
+		seed := []string{
+			"INSERT INTO songs (id, game_id, title) VALUES (1, 834, 'PANDORA PARADOXXX')",
+			"INSERT INTO charts (id, song_id, difficulty, level, ds) VALUES (1, 1, 'Master', '14', 14.0)",
+			"INSERT INTO chart_snapshots (chart_id, level, ds, fit_diff, created_at) VALUES (1, '13+', 13.7, 13.9, '2024-01-01 00:00:00')",
+			"INSERT INTO chart_snapshots (chart_id, level, ds, fit_diff, created_at) VALUES (1, '13+', 13.7, 14.0, '2024-02-01 00:00:00')",
+			"INSERT INTO chart_snapshots (chart_id, level, ds, fit_diff, created_at) VALUES (1, '14', 14.0, 14.0, '2024-03-01 00:00:00')",
+		}
+		for _, stmt := range seed {
+			if err := d.DB.Exec(stmt).Error; err != nil {
+				t.Fatalf("插入快照失败: %v", err)
+			}
+		}
+
+		if _, err := d.MigrateUp(); err != nil {
+			t.Fatalf("MigrateUp() error = %v", err)
+		}
+		// 只有定数或等级变化的快照之间产生变更事件
+		changes, err := d.GetChartChangesByChartID(1)
+		if err != nil {
+			t.Fatalf("GetChartChangesByChartID() error = %v", err)
+		}
+		if len(changes) != 1 {
+			t.Fatalf("changes = %+v, want 1", changes)
+		}
+		got := changes[0]
+		if got.OldDS != 13.7 || got.NewDS != 14.0 || got.OldLevel != "13+" || got.NewLevel != "14" || got.SongID != 1 {
+			t.Errorf("change = %+v, want 13.7 (13+) -> 14.0 (14) for song 1", got)
+		}
+		if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !got.CreatedAt.Equal(want) {
+			t.Errorf("change created_at = %v, want %v", got.CreatedAt, want)
+		}
+	})
+}
@@ -0,0 +1,666 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"gorm.io/gorm"
+)
+
+// migrations 按版本号递增排列的全部迁移
+// 新增迁移时在末尾追加，已发布的迁移不要修改。迁移中使用各自的表结构快照，
+// 而不是 model 包中的当前模型，保证之后修改模型不会影响旧迁移的执行结果
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Up:      migrateBaselineUp,
+		Down:    migrateBaselineDown,
+	},
+	{
+		Version: 2,
+		Name:    "song_last_scraped_at",
+		Up:      migrateSongLastScrapedAtUp,
+		Down:    migrateSongLastScrapedAtDown,
+	},
+	{
+		Version: 3,
+		Name:    "chart_history",
+		Up:      migrateChartHistoryUp,
+		Down:    migrateChartHistoryDown,
+	},
+	{
+		Version: 4,
+		Name:    "chart_changes",
+		Up:      migrateChartChangesUp,
+		Down:    migrateChartChangesDown,
+	},
+	{
+		Version: 5,
+		Name:    "llm_usages",
+		Up:      migrateLLMUsagesUp,
+		Down:    migrateLLMUsagesDown,
+	},
+	{
+		Version: 6,
+		Name:    "llm_cache",
+		Up:      migrateLLMCacheUp,
+		Down:    migrateLLMCacheDown,
+	},
+	{
+		Version: 7,
+		Name:    "analysis_chart_report",
+		Up:      migrateAnalysisChartReportUp,
+		Down:    migrateAnalysisChartReportDown,
+	},
+	{
+		Version: 8,
+		Name:    "analysis_structured_fields",
+		Up:      migrateAnalysisStructuredFieldsUp,
+		Down:    migrateAnalysisStructuredFieldsDown,
+	},
+	{
+		Version: 9,
+		Name:    "job_resume_at",
+		Up:      migrateJobResumeAtUp,
+		Down:    migrateJobResumeAtDown,
+	},
+	{
+		Version: 10,
+		Name:    "task_done_sources",
+		Up:      migrateTaskDoneSourcesUp,
+		Down:    migrateTaskDoneSourcesDown,
+	},
+}
+
+// ---- 1_baseline ----
+// 引入版本化迁移前由 AutoMigrate 创建的表结构。
+// 对已有数据库 (由旧版本 AutoMigrate 创建) 执行时只补齐缺失的表、列和索引
+
+type songV1 struct {
+	gorm.Model
+	GameID      int    `gorm:"uniqueIndex"`
+	Title       string `gorm:"index"`
+	Type        string
+	Artist      string
+	Genre       string
+	BPM         float64
+	ReleaseDate string
+	Version     string
+	IsNew       bool
+	CoverURL    string
+	LastScraped *string
+	Charts      []chartV1     `gorm:"foreignKey:SongID"`
+	Aliases     []songAliasV1 `gorm:"foreignKey:SongID"`
+}
+
+func (songV1) TableName() string { return "songs" }
+
+type songAliasV1 struct {
+	gorm.Model
+	SongID     uint   `gorm:"index"`
+	Alias      string `gorm:"index"`
+	IsSuitable *bool
+}
+
+func (songAliasV1) TableName() string { return "song_aliases" }
+
+type chartV1 struct {
+	gorm.Model
+	SongID         uint `gorm:"index"`
+	Difficulty     string
+	Level          string
+	DS             float64
+	Notes          string
+	Charter        string
+	FitDiff        float64
+	AvgAchievement float64
+	AvgDX          float64
+	StdDev         float64
+	SampleCount    int
+	Song           songV1 `gorm:"foreignKey:SongID"`
+}
+
+func (chartV1) TableName() string { return "charts" }
+
+type commentV1 struct {
+	gorm.Model
+	Source           string `gorm:"index;uniqueIndex:idx_comments_source_external_id"`
+	SourceTitle      string
+	ExternalID       string `gorm:"uniqueIndex:idx_comments_source_external_id"`
+	ParentExternalID string `gorm:"index"`
+	Content          string
+	Author           string
+	PostDate         time.Time
+	SongID           *uint  `gorm:"index"`
+	ChartID          *uint  `gorm:"index"`
+	SearchTag        string `gorm:"index"`
+	Likes            int64
+	Sentiment        float64
+}
+
+func (commentV1) TableName() string { return "comments" }
+
+type analysisResultV1 struct {
+	gorm.Model
+	TargetType         string `gorm:"index"`
+	TargetID           uint   `gorm:"index"`
+	Summary            string
+	RatingAdvice       string
+	DifficultyAnalysis string
+	ReasoningLog       string `gorm:"type:text"`
+}
+
+func (analysisResultV1) TableName() string { return "analysis_results" }
+
+type videoV1 struct {
+	gorm.Model
+	Source      string
+	ExternalID  string `gorm:"uniqueIndex"`
+	Title       string
+	Description string
+	Author      string
+	URL         string
+	PublishTime time.Time `gorm:"index"`
+	Views       int64
+	Likes       int64
+	Tags        string
+	Duration    int
+}
+
+func (videoV1) TableName() string { return "videos" }
+
+type taskV1 struct {
+	gorm.Model
+	JobID       uint `gorm:"index"`
+	Keyword     string
+	Source      string
+	SongID      uint   `gorm:"index"`
+	Status      string `gorm:"index"`
+	Attempts    int
+	MaxAttempts int
+	LastError   string
+	NextRunAt   time.Time `gorm:"index"`
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+}
+
+func (taskV1) TableName() string { return "tasks" }
+
+type jobV1 struct {
+	gorm.Model
+	Type       string `gorm:"index"`
+	Status     string `gorm:"index"`
+	Total      int
+	Succeeded  int
+	Failed     int
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	Items      []jobItemV1 `gorm:"foreignKey:JobID"`
+}
+
+func (jobV1) TableName() string { return "jobs" }
+
+type jobItemV1 struct {
+	gorm.Model
+	JobID      uint `gorm:"index"`
+	SongID     uint
+	GameID     int
+	Keyword    string
+	Status     string
+	Error      string
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	DurationMs int64
+}
+
+func (jobItemV1) TableName() string { return "job_items" }
+
+func migrateBaselineUp(tx *gorm.DB) error {
+	// 唯一索引创建前检查旧数据中的重复评论，给出明确的处理提示
+	if tx.Migrator().HasTable("comments") && !tx.Migrator().HasIndex(&commentV1{}, commentUniqueIndex) {
+		groups, err := countDuplicateCommentGroups(tx)
+		if err != nil {
+			return err
+		}
+		if groups > 0 {
+			return ErrDuplicateComments
+		}
+	}
+
+	return tx.AutoMigrate(
+		&songV1{},
+		&chartV1{},
+		&songAliasV1{},
+		&commentV1{},
+		&analysisResultV1{},
+		&videoV1{},
+		&taskV1{},
+		&jobV1{},
+		&jobItemV1{},
+	)
+}
+
+func migrateBaselineDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(
+		&jobItemV1{},
+		&jobV1{},
+		&taskV1{},
+		&videoV1{},
+		&analysisResultV1{},
+		&commentV1{},
+		&songAliasV1{},
+		&chartV1{},
+		&songV1{},
+	)
+}
+
+// ---- 2_song_last_scraped_at ----
+// 将 songs.last_scraped (RFC3339 字符串) 转换为时间列 last_scraped_at
+
+type songV2 struct {
+	LastScrapedAt *time.Time
+}
+
+func (songV2) TableName() string { return "songs" }
+
+func migrateSongLastScrapedAtUp(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if !m.HasColumn(&songV2{}, "LastScrapedAt") {
+		if err := m.AddColumn(&songV2{}, "LastScrapedAt"); err != nil {
+			return err
+		}
+	}
+
+	var rows []struct {
+		ID          uint
+		LastScraped string
+	}
+	if err := tx.Table("songs").Select("id, last_scraped").
+		Where("last_scraped IS NOT NULL AND last_scraped <> ''").Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		// 无法解析的旧值直接丢弃，对应歌曲会在下次回填时重新采集
+		scrapedAt, err := time.Parse(time.RFC3339, row.LastScraped)
+		if err != nil {
+			continue
+		}
+		if err := tx.Table("songs").Where("id = ?", row.ID).Update("last_scraped_at", scrapedAt).Error; err != nil {
+			return err
+		}
+	}
+
+	return m.DropColumn(&songV1{}, "LastScraped")
+}
+
+func migrateSongLastScrapedAtDown(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if !m.HasColumn(&songV1{}, "LastScraped") {
+		if err := m.AddColumn(&songV1{}, "LastScraped"); err != nil {
+			return err
+		}
+	}
+
+	var rows []struct {
+		ID            uint
+		LastScrapedAt time.Time
+	}
+	if err := tx.Table("songs").Select("id, last_scraped_at").
+		Where("last_scraped_at IS NOT NULL").Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := tx.Table("songs").Where("id = ?", row.ID).
+			Update("last_scraped", row.LastScrapedAt.Format(time.RFC3339)).Error; err != nil {
+			return err
+		}
+	}
+
+	return m.DropColumn(&songV2{}, "LastScrapedAt")
+}
+
+// ---- 3_chart_history ----
+// 谱面改为按 (song_id, difficulty) 原地更新，并新增 chart_snapshots 历史表。
+// 旧版本每次同步都会软删除并重新插入谱面，迁移时将分析结果和评论指向的旧谱面 ID
+// 合并到同一难度的当前谱面，清除多余的旧记录后创建唯一索引
+
+const chartSongDifficultyIndex = "idx_charts_song_difficulty"
+
+type chartV3 struct {
+	SongID     uint   `gorm:"uniqueIndex:idx_charts_song_difficulty"`
+	Difficulty string `gorm:"uniqueIndex:idx_charts_song_difficulty"`
+}
+
+func (chartV3) TableName() string { return "charts" }
+
+type chartSnapshotV3 struct {
+	gorm.Model
+	ChartID        uint `gorm:"index"`
+	Level          string
+	DS             float64
+	FitDiff        float64
+	AvgAchievement float64
+	AvgDX          float64
+	StdDev         float64
+	SampleCount    int
+}
+
+func (chartSnapshotV3) TableName() string { return "chart_snapshots" }
+
+func migrateChartHistoryUp(tx *gorm.DB) error {
+	if err := mergeDuplicateCharts(tx); err != nil {
+		return err
+	}
+	if err := tx.Migrator().CreateIndex(&chartV3{}, chartSongDifficultyIndex); err != nil {
+		return err
+	}
+	if err := tx.Migrator().CreateTable(&chartSnapshotV3{}); err != nil {
+		return err
+	}
+	// 以现有谱面数据作为历史的起点
+	return tx.Exec(`INSERT INTO chart_snapshots (created_at, updated_at, chart_id, level, ds, fit_diff, avg_achievement, avg_dx, std_dev, sample_count)
+	SELECT updated_at, updated_at, id, level, ds, fit_diff, avg_achievement, avg_dx, std_dev, sample_count
+	FROM charts WHERE deleted_at IS NULL`).Error
+}
+
+// mergeDuplicateCharts 每个 (song_id, difficulty) 只保留一条谱面
+// 优先保留 ID 最大的未删除谱面，其余谱面上的分析结果和评论改为指向保留的谱面后彻底删除
+func mergeDuplicateCharts(tx *gorm.DB) error {
+	var rows []struct {
+		ID         uint
+		SongID     uint
+		Difficulty string
+		Deleted    bool
+	}
+	if err := tx.Table("charts").
+		Select("id, song_id, difficulty, deleted_at IS NOT NULL AS deleted").
+		Order("id").Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	type chartKey struct {
+		songID     uint
+		difficulty string
+	}
+	keep := make(map[chartKey]uint)
+	keepDeleted := make(map[chartKey]bool)
+	for _, row := range rows {
+		key := chartKey{row.SongID, row.Difficulty}
+		if _, ok := keep[key]; !ok || !row.Deleted || keepDeleted[key] {
+			keep[key] = row.ID
+			keepDeleted[key] = row.Deleted
+		}
+	}
+
+	duplicates := make(map[uint][]uint) // 保留的谱面 ID -> 需要合并的谱面 ID
+	merged := 0
+	for _, row := range rows {
+		target := keep[chartKey{row.SongID, row.Difficulty}]
+		if row.ID != target {
+			duplicates[target] = append(duplicates[target], row.ID)
+			merged++
+		}
+	}
+	for target, ids := range duplicates {
+		if err := tx.Table("analysis_results").
+			Where("target_type = ? AND target_id IN ?", "chart", ids).
+			Update("target_id", target).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("comments").Where("chart_id IN ?", ids).Update("chart_id", target).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM charts WHERE id IN ?", ids).Error; err != nil {
+			return err
+		}
+	}
+	if merged > 0 {
+		logger.Info("已合并重复谱面", "module", "storage.migrate", "removed", merged)
+	}
+	return nil
+}
+
+func migrateChartHistoryDown(tx *gorm.DB) error {
+	// 合并掉的旧谱面记录无法恢复，回滚只移除历史表和唯一索引
+	if err := tx.Migrator().DropTable(&chartSnapshotV3{}); err != nil {
+		return err
+	}
+	return tx.Migrator().DropIndex(&chartV3{}, chartSongDifficultyIndex)
+}
+
+// ---- 4_chart_changes ----
+// 新增定数变更事件表 chart_changes，并根据已有的谱面快照补录历史变更
+
+type chartChangeV4 struct {
+	gorm.Model
+	ChartID    uint `gorm:"index"`
+	SongID     uint `gorm:"index"`
+	Difficulty string
+	OldLevel   string
+	NewLevel   string
+	OldDS      float64
+	NewDS      float64
+}
+
+func (chartChangeV4) TableName() string { return "chart_changes" }
+
+func migrateChartChangesUp(tx *gorm.DB) error {
+	if err := tx.Migrator().CreateTable(&chartChangeV4{}); err != nil {
+		return err
+	}
+
+	var snapshots []struct {
+		ChartID    uint
+		SongID     uint
+		Difficulty string
+		Level      string
+		DS         float64
+		CreatedAt  time.Time
+	}
+	if err := tx.Table("chart_snapshots").
+		Select("chart_snapshots.chart_id, charts.song_id, charts.difficulty, chart_snapshots.level, chart_snapshots.ds, chart_snapshots.created_at").
+		Joins("JOIN charts ON charts.id = chart_snapshots.chart_id").
+		Where("chart_snapshots.deleted_at IS NULL").
+		Order("chart_snapshots.chart_id, chart_snapshots.created_at, chart_snapshots.id").
+		Scan(&snapshots).Error; err != nil {
+		return err
+	}
+
+	var changes []chartChangeV4
+	for i := 1; i < len(snapshots); i++ {
+		prev, cur := snapshots[i-1], snapshots[i]
+		if prev.ChartID != cur.ChartID || (prev.DS == cur.DS && prev.Level == cur.Level) {
+			continue
+		}
+		change := chartChangeV4{
+			ChartID:    cur.ChartID,
+			SongID:     cur.SongID,
+			Difficulty: cur.Difficulty,
+			OldLevel:   prev.Level,
+			NewLevel:   cur.Level,
+			OldDS:      prev.DS,
+			NewDS:      cur.DS,
+		}
+		change.CreatedAt = cur.CreatedAt
+		change.UpdatedAt = cur.CreatedAt
+		changes = append(changes, change)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(&changes, 100).Error
+}
+
+func migrateChartChangesDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&chartChangeV4{})
+}
+
+// ---- 5_llm_usages ----
+// 新增按天聚合的 LLM 用量表 llm_usages
+
+type llmUsageV5 struct {
+	ID               uint   `gorm:"primarykey"`
+	Day              string `gorm:"size:10;uniqueIndex:idx_llm_usages_key,priority:1"`
+	Role             string `gorm:"uniqueIndex:idx_llm_usages_key,priority:2"`
+	Provider         string `gorm:"uniqueIndex:idx_llm_usages_key,priority:3"`
+	Model            string `gorm:"uniqueIndex:idx_llm_usages_key,priority:4"`
+	SongID           uint   `gorm:"uniqueIndex:idx_llm_usages_key,priority:5"`
+	JobID            uint   `gorm:"uniqueIndex:idx_llm_usages_key,priority:6"`
+	Calls            int64
+	PromptTokens     int64
+	CompletionTokens int64
+	Cost             float64
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func (llmUsageV5) TableName() string { return "llm_usages" }
+
+func migrateLLMUsagesUp(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&llmUsageV5{})
+}
+
+func migrateLLMUsagesDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&llmUsageV5{})
+}
+
+// ---- 6_llm_cache ----
+// 新增确定性 LLM 调用的响应缓存表 llm_cache_entries
+
+type llmCacheEntryV6 struct {
+	Key           string `gorm:"primaryKey;size:64"`
+	Model         string
+	PromptVersion string `gorm:"index"`
+	Response      string `gorm:"type:text"`
+	CreatedAt     time.Time
+	ExpiresAt     time.Time `gorm:"index"`
+}
+
+func (llmCacheEntryV6) TableName() string { return "llm_cache_entries" }
+
+func migrateLLMCacheUp(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&llmCacheEntryV6{})
+}
+
+func migrateLLMCacheDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&llmCacheEntryV6{})
+}
+
+// ---- 7_analysis_chart_report ----
+// 分析结果新增谱面顾问的结构化报告 chart_report (JSON)
+
+type analysisResultV7 struct {
+	ChartReport string `gorm:"type:text"`
+}
+
+func (analysisResultV7) TableName() string { return "analysis_results" }
+
+func migrateAnalysisChartReportUp(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if m.HasColumn(&analysisResultV7{}, "ChartReport") {
+		return nil
+	}
+	return m.AddColumn(&analysisResultV7{}, "ChartReport")
+}
+
+func migrateAnalysisChartReportDown(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&analysisResultV7{}, "ChartReport")
+}
+
+// ---- 8_analysis_structured_fields ----
+// 分析结果新增分析师输出的情感倾向 sentiment (带索引)、优缺点 pros/cons (JSON)，
+// 并新增标签表 analysis_tags 保存难度描述符与谱面配置
+
+type analysisResultV8 struct {
+	Sentiment string `gorm:"index"`
+	Pros      string `gorm:"type:text"`
+	Cons      string `gorm:"type:text"`
+}
+
+func (analysisResultV8) TableName() string { return "analysis_results" }
+
+type analysisTagV8 struct {
+	ID       uint   `gorm:"primarykey"`
+	ResultID uint   `gorm:"uniqueIndex:idx_analysis_tags_result_kind_value,priority:1"`
+	Kind     string `gorm:"uniqueIndex:idx_analysis_tags_result_kind_value,priority:2;index:idx_analysis_tags_kind_value,priority:1"`
+	Value    string `gorm:"uniqueIndex:idx_analysis_tags_result_kind_value,priority:3;index:idx_analysis_tags_kind_value,priority:2"`
+}
+
+func (analysisTagV8) TableName() string { return "analysis_tags" }
+
+func migrateAnalysisStructuredFieldsUp(tx *gorm.DB) error {
+	m := tx.Migrator()
+	for _, field := range []string{"Sentiment", "Pros", "Cons"} {
+		if m.HasColumn(&analysisResultV8{}, field) {
+			continue
+		}
+		if err := m.AddColumn(&analysisResultV8{}, field); err != nil {
+			return err
+		}
+	}
+	if !m.HasIndex(&analysisResultV8{}, "Sentiment") {
+		if err := m.CreateIndex(&analysisResultV8{}, "Sentiment"); err != nil {
+			return err
+		}
+	}
+	return m.CreateTable(&analysisTagV8{})
+}
+
+func migrateAnalysisStructuredFieldsDown(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if err := m.DropTable(&analysisTagV8{}); err != nil {
+		return err
+	}
+	if err := m.DropIndex(&analysisResultV8{}, "Sentiment"); err != nil {
+		return err
+	}
+	for _, field := range []string{"Sentiment", "Pros", "Cons"} {
+		if err := m.DropColumn(&analysisResultV8{}, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ---- 9_job_resume_at ----
+// 作业新增 resume_at 列，记录因超出 LLM 预算暂停的作业预计恢复的时间
+
+type jobV9 struct {
+	ResumeAt *time.Time
+}
+
+func (jobV9) TableName() string { return "jobs" }
+
+func migrateJobResumeAtUp(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if m.HasColumn(&jobV9{}, "ResumeAt") {
+		return nil
+	}
+	return m.AddColumn(&jobV9{}, "ResumeAt")
+}
+
+func migrateJobResumeAtDown(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&jobV9{}, "ResumeAt")
+}
+
+// ---- 10_task_done_sources ----
+// 任务新增 done_sources 列，记录已完成的采集器，重试时只执行失败的采集器
+
+type taskV10 struct {
+	DoneSources string
+}
+
+func (taskV10) TableName() string { return "tasks" }
+
+func migrateTaskDoneSourcesUp(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if m.HasColumn(&taskV10{}, "DoneSources") {
+		return nil
+	}
+	return m.AddColumn(&taskV10{}, "DoneSources")
+}
+
+func migrateTaskDoneSourcesDown(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&taskV10{}, "DoneSources")
+}
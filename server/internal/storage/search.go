@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xumoe-c/maiecho/server/internal/logger"
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"gorm.io/gorm"
+)
+
+// ftsMinTermLength trigram 分词器只能匹配不少于 3 个字符的词
+const ftsMinTermLength = 3
+
+// commentsFTSTriggers 保持评论全文索引与 comments 表同步的触发器
+// comments_fts 是 comments 的外部内容索引 (content='comments')，只保存分词结果。
+// 使用 trigram 分词器，对中日文等不以空格分词的文本同样可以做子串匹配
+var commentsFTSTriggers = map[string]string{
+	"comments_fts_ai": `CREATE TRIGGER IF NOT EXISTS comments_fts_ai AFTER INSERT ON comments BEGIN
+	INSERT INTO comments_fts(rowid, content, source_title) VALUES (new.id, new.content, new.source_title);
+END`,
+	"comments_fts_ad": `CREATE TRIGGER IF NOT EXISTS comments_fts_ad AFTER DELETE ON comments BEGIN
+	INSERT INTO comments_fts(comments_fts, rowid, content, source_title) VALUES ('delete', old.id, old.content, old.source_title);
+END`,
+	"comments_fts_au": `CREATE TRIGGER IF NOT EXISTS comments_fts_au AFTER UPDATE OF content, source_title ON comments BEGIN
+	INSERT INTO comments_fts(comments_fts, rowid, content, source_title) VALUES ('delete', old.id, old.content, old.source_title);
+	INSERT INTO comments_fts(rowid, content, source_title) VALUES (new.id, new.content, new.source_title);
+END`,
+}
+
+// setupCommentSearch 准备评论全文索引
+// 全文索引是 comments 的派生数据，且依赖编译时是否启用 FTS5 (构建标签 sqlite_fts5)，因此不放在版本化迁移中：
+//   - 支持 FTS5 时创建索引表和同步触发器；触发器缺失 (首次启用或曾被不支持 FTS5 的程序移除) 时重建索引
+//   - 不支持 FTS5 时移除同步触发器，避免写入评论时因缺少 fts5 模块而失败，搜索回退为 LIKE
+//
+// PostgreSQL 不使用该索引，搜索使用 ILIKE
+func (d *Database) setupCommentSearch() error {
+	d.ftsEnabled = false
+	if d.Driver() != "sqlite" || !d.DB.Migrator().HasTable("comments") {
+		return nil
+	}
+
+	if !d.fts5Available() {
+		for name := range commentsFTSTriggers {
+			if err := d.DB.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return err
+			}
+		}
+		logger.Warn("SQLite 未启用 FTS5，评论搜索回退为 LIKE 查询 (使用 -tags sqlite_fts5 构建以启用全文索引)", "module", "storage.search")
+		return nil
+	}
+
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts5(
+	content, source_title,
+	content='comments', content_rowid='id',
+	tokenize='trigram'
+)`).Error; err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND tbl_name = 'comments' AND name LIKE 'comments_fts_%'").
+			Scan(&existing).Error; err != nil {
+			return err
+		}
+		if existing == int64(len(commentsFTSTriggers)) {
+			d.ftsEnabled = true
+			return nil
+		}
+
+		for _, stmt := range commentsFTSTriggers {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		// 根据 comments 表的当前内容重建索引
+		if err := tx.Exec("INSERT INTO comments_fts(comments_fts) VALUES ('rebuild')").Error; err != nil {
+			return err
+		}
+		logger.Info("评论全文索引已重建", "module", "storage.search")
+		d.ftsEnabled = true
+		return nil
+	})
+}
+
+// fts5Available 判断当前链接的 SQLite 是否启用了 FTS5
+func (d *Database) fts5Available() bool {
+	var used int
+	if err := d.DB.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&used).Error; err != nil {
+		return false
+	}
+	return used == 1
+}
+
+// SearchComments 按关键词搜索评论，返回按相关度排序的一页结果
+// 查询按空白拆分为多个词，结果需同时包含所有词 (在内容或来源标题中)
+func (d *Database) SearchComments(filter model.CommentSearchFilter) ([]model.CommentSearchHit, int64, error) {
+	terms := strings.Fields(filter.Query)
+	if len(terms) == 0 {
+		return []model.CommentSearchHit{}, 0, nil
+	}
+	return d.searchComments(terms, filter)
+}
+
+func (d *Database) GetCommentsByKeyword(keyword string) ([]model.Comment, error) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return []model.Comment{}, nil
+	}
+	// 整个关键词作为一个短语匹配，与 LIKE %keyword% 的语义一致
+	hits, _, err := d.searchComments([]string{keyword}, model.CommentSearchFilter{})
+	if err != nil {
+		return nil, err
+	}
+	comments := make([]model.Comment, 0, len(hits))
+	for _, hit := range hits {
+		comments = append(comments, hit.Comment)
+	}
+	return comments, nil
+}
+
+// searchComments 执行搜索，filter.PageSize 为 0 时返回全部结果
+func (d *Database) searchComments(terms []string, filter model.CommentSearchFilter) ([]model.CommentSearchHit, int64, error) {
+	var query *gorm.DB
+	if d.ftsEnabled && canUseFTS(terms) {
+		query = d.DB.Table("comments_fts").
+			Joins("JOIN comments ON comments.id = comments_fts.rowid").
+			Where("comments_fts MATCH ?", ftsMatchQuery(terms))
+	} else {
+		like := d.likeOperator()
+		query = d.DB.Table("comments")
+		for _, term := range terms {
+			pattern := "%" + term + "%"
+			query = query.Where("(comments.content "+like+" ? OR comments.source_title "+like+" ?)", pattern, pattern)
+		}
+	}
+
+	query = query.Where("comments.deleted_at IS NULL")
+	if filter.SongID != 0 {
+		query = query.Where("comments.song_id = ?", filter.SongID)
+	}
+	if filter.Source != "" {
+		query = query.Where("comments.source = ?", filter.Source)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if d.ftsEnabled && canUseFTS(terms) {
+		// bm25 越小越相关，取负数使 score 越大越相关
+		query = query.Select("comments.*, -bm25(comments_fts) AS score").Order("score DESC")
+	} else {
+		// 无全文索引时按点赞数和时间排序
+		query = query.Select("comments.*, 0 AS score").Order("comments.likes DESC, comments.post_date DESC")
+	}
+	query = query.Order("comments.id DESC")
+	if filter.PageSize > 0 {
+		query = query.Offset((filter.Page - 1) * filter.PageSize).Limit(filter.PageSize)
+	}
+
+	hits := []model.CommentSearchHit{}
+	if err := query.Scan(&hits).Error; err != nil {
+		return nil, 0, err
+	}
+	return hits, total, nil
+}
+
+// canUseFTS trigram 索引无法匹配过短的词，任意一个词过短时回退为 LIKE
+func canUseFTS(terms []string) bool {
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < ftsMinTermLength {
+			return false
+		}
+	}
+	return true
+}
+
+// ftsMatchQuery 将每个词作为短语 (双引号转义) 组成 FTS5 查询，避免用户输入被解析为查询语法
+func ftsMatchQuery(terms []string) string {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(quoted, " AND ")
+}
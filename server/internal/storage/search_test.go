@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/model"
+)
+
+func seedSearchComments(t *testing.T, d *Database) []*model.Comment {
+	t.Helper()
+	songID := uint(3)
+	comments := []*model.Comment{
+		{Source: "Bilibili", ExternalID: "1", SourceTitle: "PANDORA PARADOXXX 白谱手元", Content: "后半的纵连交互太难了", Likes: 10, SongID: &songID},
+		{Source: "Bilibili", ExternalID: "2", SourceTitle: "PANDORA PARADOXXX 白谱手元", Content: "纵连交互纵连交互，练了一周还是断", Likes: 1, SongID: &songID},
+		{Source: "Tieba", ExternalID: "3", SourceTitle: "【求助】绝赞怎么打", Content: "12 个绝赞全在后半", Likes: 3},
+		{Source: "Tieba", ExternalID: "4", SourceTitle: "水帖", Content: "今天出勤打了 Oshama Scramble", Likes: 0},
+	}
+	for _, c := range comments {
+		c.PostDate = time.Now()
+		if err := d.UpsertComment(c); err != nil {
+			t.Fatalf("UpsertComment() error = %v", err)
+		}
+	}
+	return comments
+}
+
+func TestSearchComments(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, d *Database) {
+		t.Logf("driver = %s, fts = %v", d.Driver(), d.ftsEnabled)
+		comments := seedSearchComments(t, d)
+
+		search := func(filter model.CommentSearchFilter) ([]model.CommentSearchHit, int64) {
+			t.Helper()
+			if filter.Page == 0 {
+				filter.Page, filter.PageSize = 1, 20
+			}
+			hits, total, err := d.SearchComments(filter)
+			if err != nil {
+				t.Fatalf("SearchComments(%+v) error = %v", filter, err)
+			}
+			return hits, total
+		}
+
+		// 中文子串匹配，出现次数更多的评论排在前面 (启用全文索引时)
+		hits, total := search(model.CommentSearchFilter{Query: "纵连交互"})
+		if total != 2 || len(hits) != 2 {
+			t.Fatalf("纵连交互: total = %d, want 2", total)
+		}
+		if d.ftsEnabled && hits[0].ExternalID != "2" {
+			t.Errorf("纵连交互: first hit = %s, want the comment with more matches", hits[0].ExternalID)
+		}
+
+		// 标题同样参与匹配，且不区分大小写
+		if _, total := search(model.CommentSearchFilter{Query: "pandora"}); total != 2 {
+			t.Errorf("pandora: total = %d, want 2", total)
+		}
+		// 多个词需同时匹配
+		if hits, total := search(model.CommentSearchFilter{Query: "绝赞 后半"}); total != 1 || hits[0].ExternalID != "3" {
+			t.Errorf("绝赞 后半: total = %d, want only comment 3", total)
+		}
+		// 过滤条件与分页
+		if _, total := search(model.CommentSearchFilter{Query: "后半", Source: "Tieba"}); total != 1 {
+			t.Errorf("后半 (Tieba): total = %d, want 1", total)
+		}
+		if _, total := search(model.CommentSearchFilter{Query: "后半", SongID: 3}); total != 1 {
+			t.Errorf("后半 (song 3): total = %d, want 1", total)
+		}
+		if hits, total := search(model.CommentSearchFilter{Query: "PANDORA", Page: 2, PageSize: 1}); total != 2 || len(hits) != 1 {
+			t.Errorf("PANDORA page 2: %d hits (total %d), want 1 of 2", len(hits), total)
+		}
+		// 查询语法字符按普通文本处理
+		if _, total := search(model.CommentSearchFilter{Query: `"OR* NEAR(`}); total != 0 {
+			t.Errorf("syntax chars: total = %d, want 0", total)
+		}
+
+		// 更新与删除后索引保持同步
+		updated := &model.Comment{Source: "Tieba", ExternalID: "4", SourceTitle: "水帖", Content: "今天出勤打了 PANDORA", PostDate: time.Now()}
+		if err := d.UpsertComment(updated); err != nil {
+			t.Fatalf("UpsertComment(update) error = %v", err)
+		}
+		if _, total := search(model.CommentSearchFilter{Query: "Oshama"}); total != 0 {
+			t.Errorf("Oshama after update: total = %d, want 0", total)
+		}
+		if err := d.DB.Delete(&model.Comment{}, comments[0].ID).Error; err != nil {
+			t.Fatalf("删除评论失败: %v", err)
+		}
+		if _, total := search(model.CommentSearchFilter{Query: "PANDORA"}); total != 2 {
+			t.Errorf("PANDORA after update/delete: total = %d, want 2", total)
+		}
+
+		byKeyword, err := d.GetCommentsByKeyword("纵连交互")
+		if err != nil || len(byKeyword) != 1 {
+			t.Errorf("GetCommentsByKeyword() = %d comments, %v, want 1", len(byKeyword), err)
+		}
+	})
+}
+
+func TestCommentSearchIndexRebuild(t *testing.T) {
+	d, err := NewDatabase(t.TempDir() + "/maiecho.db")
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+	if !d.ftsEnabled {
+		t.Skip("未启用 FTS5 (使用 -tags sqlite_fts5 运行)")
+	}
+
+	// 模拟不支持 FTS5 的程序移除了触发器后写入的数据
+	for name := range commentsFTSTriggers {
+		if err := d.DB.Exec("DROP TRIGGER " + name).Error; err != nil {
+			t.Fatalf("删除触发器失败: %v", err)
+		}
+	}
+	seedSearchComments(t, d)
+
+	if err := d.setupCommentSearch(); err != nil {
+		t.Fatalf("setupCommentSearch() error = %v", err)
+	}
+	hits, total, err := d.SearchComments(model.CommentSearchFilter{Query: "纵连交互", Page: 1, PageSize: 20})
+	if err != nil || total != 2 || len(hits) != 2 {
+		t.Errorf("after rebuild: %d hits (total %d), %v, want 2", len(hits), total, err)
+	}
+}
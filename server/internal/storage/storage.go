@@ -1,19 +1,38 @@
 package storage
 
-import "github.com/xumoe-c/maiecho/server/internal/model"
+import (
+	"time"
+
+	"github.com/xumoe-c/maiecho/server/internal/model"
+)
 
 // Storage 定义了存储接口
 type Storage interface {
 	CreateSong(song *model.Song) error
+	// UpsertSong 按 GameID 插入或更新歌曲，谱面按 (song_id, difficulty) 原地更新并记录数据变化快照
 	UpsertSong(song *model.Song) error
 	GetSong(id uint) (*model.Song, error)
 	GetSongByGameID(gameID int) (*model.Song, error)
 	GetAllSongs() ([]model.Song, error)
 	GetSongs(filter model.SongFilter) ([]model.Song, int64, error)
+	GetChart(id uint) (*model.Chart, error)
+	// GetChartSnapshots 按时间顺序返回谱面的历史快照
+	GetChartSnapshots(chartID uint) ([]model.ChartSnapshot, error)
+	// GetChartChanges 按检测时间倒序返回定数变更事件 (附带歌曲信息)
+	GetChartChanges(filter model.ChartChangeFilter) ([]model.ChartChangeItem, int64, error)
+	// SearchCharts 按谱面数据与最新分析结果 (标签、情感倾向) 搜索谱面，按定数倒序返回
+	SearchCharts(filter model.ChartSearchFilter) ([]model.ChartSearchItem, int64, error)
+	// GetChartChangesByChartID 按时间顺序返回谱面的定数变更事件
+	GetChartChangesByChartID(chartID uint) ([]model.ChartChange, error)
 	SaveSongAliases(songID uint, aliases []string) error
 	CreateComment(comment *model.Comment) error
+	// UpsertComment 按 (source, external_id) 插入或更新评论，避免重复采集产生重复数据
+	UpsertComment(comment *model.Comment) error
 	UpdateComment(comment *model.Comment) error
+	// GetCommentsByKeyword 返回内容或来源标题包含关键词的全部评论 (按相关度排序)
 	GetCommentsByKeyword(keyword string) ([]model.Comment, error)
+	// SearchComments 分页搜索评论，SQLite 启用 FTS5 时使用全文索引并按相关度排序
+	SearchComments(filter model.CommentSearchFilter) ([]model.CommentSearchHit, int64, error)
 	GetCommentsBySongID(songID uint) ([]model.Comment, error)
 	CreateAnalysisResult(result *model.AnalysisResult) error
 	GetAnalysisResultBySongID(songID uint) (*model.AnalysisResult, error)
@@ -21,4 +40,38 @@ type Storage interface {
 	CreateVideo(video *model.Video) error
 	UpdateSongLastScrapedTime(songID uint) error
 	UpdateSongAliasSuitability(aliasID uint, isSuitable bool) error
+	CreateTask(task *model.Task) error
+	// CreateFollowUpTask 为同一关键词追加一条指定来源的任务，已存在相同的待执行或运行中任务时不重复创建；
+	// 任务属于某个作业时，作业子项总数同步加一
+	CreateFollowUpTask(task *model.Task) (bool, error)
+	ClaimNextTask() (*model.Task, error)
+	UpdateTask(task *model.Task) error
+	ResetRunningTasks() (int64, error)
+	HasActiveTask(keyword string, songID uint) (bool, error)
+	CountTasksByStatus() (map[string]int64, error)
+	CreateJob(job *model.Job) error
+	GetJob(id uint) (*model.Job, error)
+	GetJobs(filter model.JobFilter) ([]model.Job, int64, error)
+	UpdateJob(job *model.Job) error
+	// StartJob 将待执行的作业标记为运行中并记录开始时间
+	StartJob(id uint) error
+	// PauseJob 将运行中的作业标记为暂停，并记录预计恢复的时间
+	PauseJob(id uint, resumeAt time.Time) error
+	// ResumeJob 将暂停中的作业恢复为运行中
+	ResumeJob(id uint) error
+	// AbortUnfinishedJobs 将指定类型中未结束的作业标记为失败，用于进程重启后的收尾
+	AbortUnfinishedJobs(jobType string) (int64, error)
+	RecordJobItem(item *model.JobItem) error
+	// RecordLLMUsage 将调用用量累加到按天聚合的记录中
+	RecordLLMUsage(usage *model.LLMUsage) error
+	// GetLLMUsageByDay 按日期返回 LLM 用量合计
+	GetLLMUsageByDay(filter model.LLMUsageFilter) ([]model.LLMUsageDay, error)
+	// GetLLMUsageByRole 按角色返回 LLM 用量合计
+	GetLLMUsageByRole(filter model.LLMUsageFilter) ([]model.LLMUsageRole, error)
+	// GetLLMCacheEntry 返回未过期且提示词版本一致的 LLM 缓存响应
+	GetLLMCacheEntry(key, promptVersion string) (*model.LLMCacheEntry, error)
+	SaveLLMCacheEntry(entry *model.LLMCacheEntry) error
+	DeleteLLMCacheEntry(key string) error
+	// PurgeLLMCache 删除过期或提示词版本已变化的 LLM 缓存
+	PurgeLLMCache(promptVersion string) (int64, error)
 }
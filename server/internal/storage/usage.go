@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"github.com/xumoe-c/maiecho/server/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// llmUsageSums 用量合计的查询列，与 model.LLMUsageTotals 的字段对应
+const llmUsageSums = `SUM(calls) AS calls, SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens,
+SUM(prompt_tokens + completion_tokens) AS total_tokens, SUM(cost) AS cost`
+
+// RecordLLMUsage 将一次或多次调用的用量累加到对应的按天聚合行
+func (d *Database) RecordLLMUsage(usage *model.LLMUsage) error {
+	return d.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "day"}, {Name: "role"}, {Name: "provider"}, {Name: "model"}, {Name: "song_id"}, {Name: "job_id"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "calls"}, Value: gorm.Expr("llm_usages.calls + excluded.calls")},
+			{Column: clause.Column{Name: "prompt_tokens"}, Value: gorm.Expr("llm_usages.prompt_tokens + excluded.prompt_tokens")},
+			{Column: clause.Column{Name: "completion_tokens"}, Value: gorm.Expr("llm_usages.completion_tokens + excluded.completion_tokens")},
+			{Column: clause.Column{Name: "cost"}, Value: gorm.Expr("llm_usages.cost + excluded.cost")},
+			{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("excluded.updated_at")},
+		},
+	}).Create(usage).Error
+}
+
+// GetLLMUsageByDay 按日期正序返回每天的用量合计，没有调用的日期不返回
+func (d *Database) GetLLMUsageByDay(filter model.LLMUsageFilter) ([]model.LLMUsageDay, error) {
+	days := []model.LLMUsageDay{}
+	err := llmUsageQuery(d.DB, filter).
+		Select("day, " + llmUsageSums).
+		Group("day").
+		Order("day").
+		Scan(&days).Error
+	return days, err
+}
+
+// GetLLMUsageByRole 按角色名返回用量合计
+func (d *Database) GetLLMUsageByRole(filter model.LLMUsageFilter) ([]model.LLMUsageRole, error) {
+	roles := []model.LLMUsageRole{}
+	err := llmUsageQuery(d.DB, filter).
+		Select("role, " + llmUsageSums).
+		Group("role").
+		Order("role").
+		Scan(&roles).Error
+	return roles, err
+}
+
+func llmUsageQuery(db *gorm.DB, filter model.LLMUsageFilter) *gorm.DB {
+	query := db.Model(&model.LLMUsage{})
+	if filter.From != "" {
+		query = query.Where("day >= ?", filter.From)
+	}
+	if filter.To != "" {
+		query = query.Where("day <= ?", filter.To)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.SongID != 0 {
+		query = query.Where("song_id = ?", filter.SongID)
+	}
+	if filter.JobID != 0 {
+		query = query.Where("job_id = ?", filter.JobID)
+	}
+	return query
+}
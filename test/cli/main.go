@@ -195,7 +195,7 @@ func triggerAnalysis() {
 		spinner.Fail("Error: " + resp.Status())
 		return
 	}
-	spinner.Success("Analysis task started!")
+	spinner.Success("Analysis task started! Job ID: " + gjson.GetBytes(resp.Body(), "job_id").String())
 }
 
 func getAnalysisResult() {